@@ -12,6 +12,10 @@ import (
 	"social-network/internal/config"
 	"social-network/internal/database"
 	"social-network/internal/handler"
+	"social-network/internal/keys"
+	"social-network/internal/mailer"
+	"social-network/internal/oidc"
+	"social-network/internal/realtime"
 	"social-network/internal/repository"
 	"social-network/internal/service"
 )
@@ -35,6 +39,33 @@ func main() {
 	// Создаём директорию для аватарок
 	os.MkdirAll("web/uploads", 0755)
 
+	// Отправка писем: SMTP, если настроен, иначе — файлы для локальной разработки
+	var mail mailer.Mailer
+	if cfg.SMTPHost != "" {
+		mail = mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	} else {
+		fileMailer, err := mailer.NewFileMailer(cfg.MailDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("SMTP не настроен, письма сохраняются в %s", cfg.MailDir)
+		mail = fileMailer
+	}
+
+	// Фоновые задачи останавливаются вместе с сервером
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	// Ключи подписи JWT: асимметричные с ротацией, если задана директория ключей
+	var signingKeys *keys.Manager
+	if cfg.JWTKeysDir != "" {
+		signingKeys, err = keys.NewManager(cfg.JWTKeysDir, cfg.JWTSigningAlg, cfg.JWTKeyRetention, keys.JWKSMaxAge)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go signingKeys.Run(ctx, cfg.JWTKeyRotation)
+	}
+
 	// === Инициализация слоёв (DI через конструкторы) ===
 
 	// Репозитории
@@ -43,18 +74,79 @@ func main() {
 	commentRepo := repository.NewCommentRepo(db)
 	followRepo := repository.NewFollowRepo(db)
 	likeRepo := repository.NewLikeRepo(db)
+	repostRepo := repository.NewRepostRepo(db)
+	hashtagRepo := repository.NewHashtagRepo(db)
+	mentionRepo := repository.NewMentionRepo(db)
+	searchRepo := repository.NewSearchRepo(db)
+	notificationRepo := repository.NewNotificationRepo(db)
+	conversationRepo := repository.NewConversationRepo(db)
+	blockRepo := repository.NewBlockRepo(db)
+	muteRepo := repository.NewMuteRepo(db)
 	tokenRepo := repository.NewTokenRepo(db)
+	userTokenRepo := repository.NewUserTokenRepo(db)
+	mfaRepo := repository.NewMFARepo(db)
+	accessTokenRepo := repository.NewAccessTokenRepo(db)
+	identityRepo := repository.NewIdentityRepo(db)
+	oauthStateRepo := repository.NewOAuthStateRepo(db)
+
+	// Счётчики попыток входа: в памяти — только для одного экземпляра приложения
+	loginAttemptRepo := repository.NewLoginAttemptRepo(db)
+	if cfg.LoginAttemptsStore == "memory" {
+		loginAttemptRepo = repository.NewMemoryLoginAttemptRepo()
+	}
+
+	// События в реальном времени: в памяти — только для одного экземпляра приложения
+	var hub realtime.Hub
+	if cfg.EventsBackend == "memory" {
+		hub = realtime.NewMemoryHub()
+	} else {
+		hub, err = realtime.NewPostgresHub(db, cfg.DSN())
+		if err != nil {
+			log.Fatal("Ошибка подписки на события: ", err)
+		}
+	}
+
+	loginThrottle := service.DefaultLoginThrottle()
+	loginThrottle.LockoutThreshold = cfg.LoginLockoutThreshold
+	loginThrottle.LockoutDuration = cfg.LoginLockoutDuration
 
 	// Сервисы
-	authService := service.NewAuthService(userRepo, tokenRepo, cfg.JWTSecret)
-	userService := service.NewUserService(userRepo)
-	postService := service.NewPostService(postRepo)
-	commentService := service.NewCommentService(commentRepo)
-	followService := service.NewFollowService(followRepo)
-	likeService := service.NewLikeService(likeRepo)
+	authService := service.NewAuthService(userRepo, tokenRepo, userTokenRepo, mfaRepo, accessTokenRepo, loginAttemptRepo, mail, service.AuthConfig{
+		JWTSecret:     cfg.JWTSecret,
+		AppURL:        cfg.AppURL,
+		Keys:          signingKeys,
+		LoginThrottle: loginThrottle,
+
+		RequireVerifiedEmail: cfg.RequireEmailVerification,
+	})
+	streamService := service.NewStreamService(hub, postRepo, followRepo, muteRepo)
+	notificationService := service.NewNotificationService(notificationRepo, streamService)
+	userService := service.NewUserService(userRepo, mentionRepo)
+	postService := service.NewPostService(postRepo, repostRepo, hashtagRepo, userRepo, blockRepo, followRepo, notificationService, streamService)
+	commentService := service.NewCommentService(commentRepo, postRepo, userRepo, blockRepo, followRepo, notificationService, streamService)
+	followService := service.NewFollowService(followRepo, blockRepo, userRepo, notificationService, streamService)
+	likeService := service.NewLikeService(likeRepo, postRepo, notificationService, streamService)
+	adminService := service.NewAdminService(userRepo, tokenRepo, streamService, nil)
+	searchService := service.NewSearchService(searchRepo)
+	messageService := service.NewMessageService(conversationRepo, userRepo, followRepo, blockRepo, streamService)
+	blockService := service.NewBlockService(blockRepo, muteRepo, userRepo, streamService)
+
+	// Вход через внешних провайдеров OpenID Connect
+	var providers []*oidc.Provider
+	for _, p := range cfg.OIDCProviders {
+		providers = append(providers, oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  cfg.AppURL + "/v1/auth/oauth/" + p.Name + "/callback",
+			Scopes:       p.Scopes,
+		}, &http.Client{Timeout: 10 * time.Second}))
+	}
+	oauthService := service.NewOAuthService(authService, userRepo, identityRepo, oauthStateRepo, providers)
 
 	// Хендлер + роутер
-	h := handler.NewHandler(authService, userService, postService, commentService, followService, likeService)
+	h := handler.NewHandler(authService, userService, postService, commentService, followService, likeService, oauthService, adminService, searchService, notificationService, streamService, messageService, blockService, cfg.SecureCookies)
 	router := h.Routes()
 
 	// HTTP-сервер
@@ -62,6 +154,8 @@ func main() {
 		Addr:    ":" + cfg.ServerPort,
 		Handler: router,
 	}
+	// Закрытие шины событий завершает открытые потоки, иначе Shutdown ждал бы их до таймаута
+	srv.RegisterOnShutdown(func() { hub.Close() })
 
 	// Запуск в горутине
 	go func() {
@@ -77,10 +171,12 @@ func main() {
 	<-quit
 
 	log.Println("Завершение работы сервера...")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
+	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Fatal("Ошибка при остановке сервера: ", err)
 	}
 
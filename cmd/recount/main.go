@@ -0,0 +1,39 @@
+// Команда recount пересчитывает денормализованные счётчики (лайки, комментарии,
+// подписчики, подписки, посты) по исходным таблицам. Запускать после ручных правок БД
+// или если счётчики разошлись с данными: go run ./cmd/recount
+package main
+
+import (
+	"log"
+
+	"social-network/internal/config"
+	"social-network/internal/database"
+	"social-network/internal/repository"
+)
+
+func main() {
+	cfg := config.Load()
+
+	db, err := database.Connect(cfg.DSN())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := database.RunMigrations(db, "migrations"); err != nil {
+		log.Fatal("Ошибка миграций: ", err)
+	}
+
+	counters := repository.NewCounterRepo(db)
+
+	posts, err := counters.RecountPosts()
+	if err != nil {
+		log.Fatal("Ошибка пересчёта постов: ", err)
+	}
+	users, err := counters.RecountUsers()
+	if err != nil {
+		log.Fatal("Ошибка пересчёта пользователей: ", err)
+	}
+
+	log.Printf("Счётчики исправлены: постов — %d, пользователей — %d", posts, users)
+}
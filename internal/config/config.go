@@ -1,6 +1,11 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Config — конфигурация приложения из ENV-переменных
 type Config struct {
@@ -12,11 +17,49 @@ type Config struct {
 	DBSSLMode  string
 	JWTSecret  string
 	ServerPort string
+
+	// Асимметричная подпись JWT: если JWTKeysDir пуст, используется HS256 с JWTSecret
+	JWTKeysDir      string
+	JWTSigningAlg   string        // RS256 или EdDSA
+	JWTKeyRotation  time.Duration // Как часто выпускать новый ключ
+	JWTKeyRetention time.Duration // Сколько старый ключ проверяет подписи после ротации
+
+	AppURL        string // Публичный адрес приложения для ссылок в письмах
+	SecureCookies bool   // Cookie только по HTTPS; по умолчанию — если AppURL на https
+	SMTPHost      string // Если пусто — письма сохраняются в MailDir
+	SMTPPort      string
+	SMTPUsername  string
+	SMTPPassword  string
+	MailFrom      string
+	MailDir       string
+
+	// RequireEmailVerification — неподтверждённые аккаунты не могут публиковать
+	RequireEmailVerification bool
+
+	// Защита входа от перебора паролей
+	LoginAttemptsStore    string        // postgres или memory
+	LoginLockoutThreshold int           // Неудач до блокировки аккаунта
+	LoginLockoutDuration  time.Duration // На сколько блокируется аккаунт
+
+	// Шина событий потока /v1/stream: postgres (LISTEN/NOTIFY) или memory
+	EventsBackend string
+
+	// Провайдеры входа OpenID Connect из OIDC_PROVIDERS=google,gitlab и OIDC_<ИМЯ>_*
+	OIDCProviders []OIDCProvider
+}
+
+// OIDCProvider — настройки одного провайдера OpenID Connect
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
 }
 
 // Load читает конфигурацию из переменных окружения
 func Load() *Config {
-	return &Config{
+	cfg := &Config{
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		DBUser:     getEnv("DB_USER", "postgres"),
@@ -25,7 +68,53 @@ func Load() *Config {
 		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
 		JWTSecret:  getEnv("JWT_SECRET", "super-secret-key-change-me"),
 		ServerPort: getEnv("PORT", getEnv("SERVER_PORT", "8080")),
+
+		JWTKeysDir:      getEnv("JWT_KEYS_DIR", ""),
+		JWTSigningAlg:   getEnv("JWT_SIGNING_ALG", "RS256"),
+		JWTKeyRotation:  getEnvDuration("JWT_KEY_ROTATION", 7*24*time.Hour),
+		JWTKeyRetention: getEnvDuration("JWT_KEY_RETENTION", time.Hour),
+
+		AppURL:       getEnv("APP_URL", "http://localhost:8080"),
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		MailFrom:     getEnv("MAIL_FROM", "GoNetwork <noreply@localhost>"),
+		MailDir:      getEnv("MAIL_DIR", "mail"),
+
+		RequireEmailVerification: getEnvBool("REQUIRE_EMAIL_VERIFICATION", false),
+
+		LoginAttemptsStore:    getEnv("LOGIN_ATTEMPTS_STORE", "postgres"),
+		LoginLockoutThreshold: getEnvInt("LOGIN_LOCKOUT_THRESHOLD", 10),
+		LoginLockoutDuration:  getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+
+		EventsBackend: getEnv("EVENTS_BACKEND", "postgres"),
+
+		OIDCProviders: loadOIDCProviders(),
+	}
+	cfg.SecureCookies = getEnvBool("SECURE_COOKIES", strings.HasPrefix(cfg.AppURL, "https://"))
+	return cfg
+}
+
+// loadOIDCProviders читает провайдеров: для каждого имени из OIDC_PROVIDERS
+// нужны OIDC_<ИМЯ>_ISSUER, OIDC_<ИМЯ>_CLIENT_ID и OIDC_<ИМЯ>_CLIENT_SECRET
+func loadOIDCProviders() []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, OIDCProvider{
+			Name:         name,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "")),
+		})
 	}
+	return providers
 }
 
 // DSN возвращает строку подключения к PostgreSQL
@@ -45,3 +134,30 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvBool — получить булево значение ENV или вернуть значение по умолчанию
+func getEnvBool(key string, fallback bool) bool {
+	val, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return val
+}
+
+// getEnvInt — получить целое число из ENV или значение по умолчанию
+func getEnvInt(key string, fallback int) int {
+	val, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return val
+}
+
+// getEnvDuration — получить длительность из ENV (например, "24h") или значение по умолчанию
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	val, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return val
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"social-network/internal/service"
+)
+
+// createAccessTokenRequest — тело запроса на создание персонального токена
+type createAccessTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"` // 0 — бессрочный
+}
+
+// createAccessToken обрабатывает POST /v1/users/me/tokens
+func (h *Handler) createAccessToken(w http.ResponseWriter, r *http.Request) {
+	var req createAccessTokenRequest
+	if err := readJSON(r, &req); err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	if req.Name == "" || len(req.Name) > 100 {
+		jsonError(w, http.StatusBadRequest, "name обязателен (до 100 символов)")
+		return
+	}
+	if req.ExpiresInDays < 0 {
+		jsonError(w, http.StatusBadRequest, "expires_in_days не может быть отрицательным")
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, err := h.authService.CreateAccessToken(getUserID(r), req.Name, req.Scopes, ttl)
+	if err != nil {
+		if err == service.ErrInvalidScope {
+			jsonError(w, http.StatusBadRequest, "укажите хотя бы одну допустимую область доступа")
+			return
+		}
+		log.Printf("Ошибка создания персонального токена: %v", err)
+		jsonError(w, http.StatusInternalServerError, "ошибка создания токена")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, token)
+}
+
+// getAccessTokens обрабатывает GET /v1/users/me/tokens
+func (h *Handler) getAccessTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.authService.ListAccessTokens(getUserID(r))
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка получения токенов")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+// revokeAccessToken обрабатывает DELETE /v1/users/me/tokens/{id}
+func (h *Handler) revokeAccessToken(w http.ResponseWriter, r *http.Request) {
+	tokenID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID токена")
+		return
+	}
+
+	if err := h.authService.RevokeAccessToken(getUserID(r), tokenID); err != nil {
+		if err == service.ErrAccessTokenNotFound {
+			jsonError(w, http.StatusNotFound, "токен не найден")
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "ошибка отзыва токена")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "токен отозван"})
+}
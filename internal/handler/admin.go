@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"social-network/internal/model"
+	"social-network/internal/service"
+)
+
+// setRoleRequest — тело запроса смены роли
+type setRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// adminFindUser обрабатывает GET /v1/admin/users?email=...|username=...
+func (h *Handler) adminFindUser(w http.ResponseWriter, r *http.Request) {
+	email := r.URL.Query().Get("email")
+	username := r.URL.Query().Get("username")
+	if email == "" && username == "" {
+		jsonError(w, http.StatusBadRequest, "укажите email или username")
+		return
+	}
+
+	user, err := h.adminService.FindUser(email, username)
+	if err != nil {
+		adminError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+// adminGetUser обрабатывает GET /v1/admin/users/{id}
+func (h *Handler) adminGetUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminUserID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.adminService.GetUser(id)
+	if err != nil {
+		adminError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+// adminSuspendUser обрабатывает POST /v1/admin/users/{id}/suspend
+func (h *Handler) adminSuspendUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminUserID(w, r)
+	if !ok {
+		return
+	}
+
+	claims := getClaims(r)
+	user, err := h.adminService.Suspend(claims.UserID, claims.Role, id)
+	if err != nil {
+		adminError(w, err)
+		return
+	}
+
+	log.Printf("Модерация: user_id=%d заблокировал user_id=%d", claims.UserID, id)
+	writeJSON(w, http.StatusOK, user)
+}
+
+// adminUnsuspendUser обрабатывает DELETE /v1/admin/users/{id}/suspend
+func (h *Handler) adminUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminUserID(w, r)
+	if !ok {
+		return
+	}
+
+	claims := getClaims(r)
+	user, err := h.adminService.Unsuspend(claims.UserID, claims.Role, id)
+	if err != nil {
+		adminError(w, err)
+		return
+	}
+
+	log.Printf("Модерация: user_id=%d разблокировал user_id=%d", claims.UserID, id)
+	writeJSON(w, http.StatusOK, user)
+}
+
+// adminSetRole обрабатывает PUT /v1/admin/users/{id}/role
+func (h *Handler) adminSetRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := adminUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req setRoleRequest
+	if err := readJSON(r, &req); err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	claims := getClaims(r)
+	user, err := h.adminService.SetRole(claims.UserID, claims.Role, id, req.Role)
+	if err != nil {
+		adminError(w, err)
+		return
+	}
+
+	log.Printf("Администрирование: user_id=%d назначил user_id=%d роль %s", claims.UserID, id, req.Role)
+	writeJSON(w, http.StatusOK, user)
+}
+
+// adminDeletePost обрабатывает DELETE /v1/admin/posts/{id} — удаление без проверки авторства
+func (h *Handler) adminDeletePost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID поста")
+		return
+	}
+
+	if err := h.postService.ForceDelete(postID); err != nil {
+		if err == service.ErrPostNotFound {
+			jsonError(w, http.StatusNotFound, "пост не найден")
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "ошибка удаления поста")
+		return
+	}
+
+	log.Printf("Модерация: user_id=%d удалил пост %d", getUserID(r), postID)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "пост удалён"})
+}
+
+// adminUserID читает ID пользователя из URL
+func adminUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID пользователя")
+		return 0, false
+	}
+	return id, true
+}
+
+// adminError переводит ошибки администрирования в HTTP-ответ
+func adminError(w http.ResponseWriter, err error) {
+	switch err {
+	case service.ErrUserNotFound:
+		jsonError(w, http.StatusNotFound, "пользователь не найден")
+	case service.ErrInsufficientRole:
+		jsonError(w, http.StatusForbidden, "недостаточно прав для действий над этим пользователем")
+	case service.ErrInvalidRole:
+		jsonError(w, http.StatusBadRequest, "роль должна быть одной из: "+
+			model.RoleUser+", "+model.RoleModerator+", "+model.RoleAdmin)
+	default:
+		log.Printf("Ошибка администрирования: %v", err)
+		jsonError(w, http.StatusInternalServerError, "ошибка администрирования")
+	}
+}
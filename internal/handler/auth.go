@@ -1,12 +1,17 @@
 package handler
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 
+	"social-network/internal/keys"
 	"social-network/internal/service"
 )
 
+// minPasswordLength — минимальная длина пароля
+const minPasswordLength = 6
+
 // registerRequest — тело запроса регистрации
 type registerRequest struct {
 	Username string `json:"username"`
@@ -38,12 +43,12 @@ func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(req.Password) < 6 {
+	if len(req.Password) < minPasswordLength {
 		jsonError(w, http.StatusBadRequest, "пароль должен быть не менее 6 символов")
 		return
 	}
 
-	user, tokens, err := h.authService.Register(req.Username, req.Email, req.Password)
+	user, tokens, err := h.authService.Register(req.Username, req.Email, req.Password, clientInfo(r))
 	if err != nil {
 		if err == service.ErrUserExists {
 			jsonError(w, http.StatusConflict, "пользователь уже существует")
@@ -72,21 +77,26 @@ func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, tokens, err := h.authService.Login(req.Email, req.Password)
+	// При включённой 2FA вместо токенов придёт mfa_token для POST /v1/auth/login/mfa
+	result, err := h.authService.Login(req.Email, req.Password, clientInfo(r))
 	if err != nil {
 		if err == service.ErrInvalidCredentials {
 			jsonError(w, http.StatusUnauthorized, "неверный email или пароль")
 			return
 		}
+		if err == service.ErrAccountSuspended {
+			jsonError(w, http.StatusForbidden, "аккаунт заблокирован")
+			return
+		}
+		if throttled(w, err) {
+			return
+		}
 		log.Printf("Ошибка логина: %v", err)
 		jsonError(w, http.StatusInternalServerError, "ошибка входа")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{
-		"user":   user,
-		"tokens": tokens,
-	})
+	writeJSON(w, http.StatusOK, result)
 }
 
 // refresh обрабатывает POST /v1/auth/refresh
@@ -102,8 +112,12 @@ func (h *Handler) refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, err := h.authService.Refresh(req.RefreshToken)
+	tokens, err := h.authService.Refresh(req.RefreshToken, clientInfo(r))
 	if err != nil {
+		if err == service.ErrAccountSuspended {
+			jsonError(w, http.StatusForbidden, "аккаунт заблокирован")
+			return
+		}
 		jsonError(w, http.StatusUnauthorized, "невалидный refresh-токен")
 		return
 	}
@@ -131,3 +145,9 @@ func (h *Handler) logout(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, http.StatusOK, map[string]string{"message": "вы вышли из системы"})
 }
+
+// jwks обрабатывает GET /.well-known/jwks.json
+func (h *Handler) jwks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(keys.JWKSMaxAge.Seconds())))
+	writeJSON(w, http.StatusOK, h.authService.JWKS())
+}
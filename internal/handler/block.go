@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"social-network/internal/model"
+	"social-network/internal/service"
+)
+
+// userRefRequest — тело запроса блокировки или скрытия пользователя
+type userRefRequest struct {
+	UserID int `json:"user_id"`
+}
+
+// getBlocks обрабатывает GET /v1/users/me/blocks
+func (h *Handler) getBlocks(w http.ResponseWriter, r *http.Request) {
+	h.listUsers(w, r, h.blockService.GetBlocked, "ошибка получения блокировок")
+}
+
+// blockUser обрабатывает POST /v1/users/me/blocks
+func (h *Handler) blockUser(w http.ResponseWriter, r *http.Request) {
+	h.changeUserRelation(w, r, h.blockService.Block, "пользователь заблокирован", "ошибка блокировки")
+}
+
+// unblockUser обрабатывает DELETE /v1/users/me/blocks/{id}
+func (h *Handler) unblockUser(w http.ResponseWriter, r *http.Request) {
+	h.removeUserRelation(w, r, h.blockService.Unblock, "пользователь разблокирован", "ошибка разблокировки")
+}
+
+// getMutes обрабатывает GET /v1/users/me/mutes
+func (h *Handler) getMutes(w http.ResponseWriter, r *http.Request) {
+	h.listUsers(w, r, h.blockService.GetMuted, "ошибка получения скрытых")
+}
+
+// muteUser обрабатывает POST /v1/users/me/mutes
+func (h *Handler) muteUser(w http.ResponseWriter, r *http.Request) {
+	h.changeUserRelation(w, r, h.blockService.Mute, "пользователь скрыт из лент", "ошибка скрытия")
+}
+
+// unmuteUser обрабатывает DELETE /v1/users/me/mutes/{id}
+func (h *Handler) unmuteUser(w http.ResponseWriter, r *http.Request) {
+	h.removeUserRelation(w, r, h.blockService.Unmute, "пользователь возвращён в ленты", "ошибка возврата в ленты")
+}
+
+// listUsers отдаёт страницу пользователей из личного списка
+func (h *Handler) listUsers(w http.ResponseWriter, r *http.Request,
+	list func(userID int, page model.PageRequest) (*model.Page[*model.User], error), fallback string) {
+	page, err := readPage(r)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный курсор")
+		return
+	}
+
+	users, err := list(getUserID(r), page)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, fallback)
+		return
+	}
+
+	writePage(w, r, users)
+}
+
+// changeUserRelation добавляет пользователя из тела запроса в личный список
+func (h *Handler) changeUserRelation(w http.ResponseWriter, r *http.Request,
+	change func(userID, otherID int) error, message, fallback string) {
+	var req userRefRequest
+	if err := readJSON(r, &req); err != nil || req.UserID == 0 {
+		jsonError(w, http.StatusBadRequest, "нужен user_id пользователя")
+		return
+	}
+
+	if err := change(getUserID(r), req.UserID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			jsonError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, service.ErrSelfBlock), errors.Is(err, service.ErrSelfMute):
+			jsonError(w, http.StatusBadRequest, err.Error())
+		default:
+			jsonError(w, http.StatusInternalServerError, fallback)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": message})
+}
+
+// removeUserRelation убирает пользователя из личного списка по ID из пути
+func (h *Handler) removeUserRelation(w http.ResponseWriter, r *http.Request,
+	remove func(userID, otherID int) error, message, fallback string) {
+	otherID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID пользователя")
+		return
+	}
+
+	if err := remove(getUserID(r), otherID); err != nil {
+		jsonError(w, http.StatusInternalServerError, fallback)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": message})
+}
@@ -5,10 +5,18 @@ import (
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+
+	"social-network/internal/service"
 )
 
 // createCommentRequest — тело запроса создания комментария
 type createCommentRequest struct {
+	Content  string `json:"content"`
+	ParentID *int   `json:"parent_id"` // Ответ на комментарий, необязательно
+}
+
+// updateCommentRequest — тело запроса правки комментария
+type updateCommentRequest struct {
 	Content string `json:"content"`
 }
 
@@ -32,15 +40,124 @@ func (h *Handler) createComment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userID := getUserID(r)
-	comment, err := h.commentService.Create(postID, userID, req.Content)
+	comment, err := h.commentService.Create(postID, userID, req.ParentID, req.Content)
 	if err != nil {
-		jsonError(w, http.StatusInternalServerError, "ошибка создания комментария")
+		switch err {
+		case service.ErrPostNotFound:
+			jsonError(w, http.StatusNotFound, "пост не найден")
+		case service.ErrCommentNotFound:
+			jsonError(w, http.StatusBadRequest, "комментарий, на который вы отвечаете, не найден в этом посте")
+		case service.ErrCommentTooDeep:
+			jsonError(w, http.StatusBadRequest, "слишком глубокая вложенность ответов")
+		default:
+			jsonError(w, http.StatusInternalServerError, "ошибка создания комментария")
+		}
 		return
 	}
 
 	writeJSON(w, http.StatusCreated, comment)
 }
 
+// commentParams читает ID поста и комментария из пути /v1/posts/{id}/comments/{commentID}
+func commentParams(w http.ResponseWriter, r *http.Request) (postID, commentID int, ok bool) {
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID поста")
+		return 0, 0, false
+	}
+	commentID, err = strconv.Atoi(chi.URLParam(r, "commentID"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID комментария")
+		return 0, 0, false
+	}
+	return postID, commentID, true
+}
+
+// commentModerationError отвечает на ошибки правки, удаления и скрытия комментария
+func commentModerationError(w http.ResponseWriter, err error, fallback string) {
+	switch err {
+	case service.ErrPostNotFound:
+		jsonError(w, http.StatusNotFound, "пост не найден")
+	case service.ErrCommentNotFound:
+		jsonError(w, http.StatusNotFound, "комментарий не найден")
+	case service.ErrNotCommentOwner:
+		jsonError(w, http.StatusForbidden, "вы не являетесь автором комментария или поста")
+	case service.ErrNotPostOwner:
+		jsonError(w, http.StatusForbidden, "скрывать комментарии может только автор поста")
+	default:
+		jsonError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+// updateComment обрабатывает PATCH /v1/posts/{id}/comments/{commentID}
+func (h *Handler) updateComment(w http.ResponseWriter, r *http.Request) {
+	postID, commentID, ok := commentParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req updateCommentRequest
+	if err := readJSON(r, &req); err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+	if req.Content == "" {
+		jsonError(w, http.StatusBadRequest, "текст комментария обязателен")
+		return
+	}
+
+	comment, err := h.commentService.Update(postID, commentID, getUserID(r), req.Content)
+	if err != nil {
+		commentModerationError(w, err, "ошибка редактирования комментария")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, comment)
+}
+
+// deleteComment обрабатывает DELETE /v1/posts/{id}/comments/{commentID}
+func (h *Handler) deleteComment(w http.ResponseWriter, r *http.Request) {
+	postID, commentID, ok := commentParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.commentService.Delete(postID, commentID, getUserID(r)); err != nil {
+		commentModerationError(w, err, "ошибка удаления комментария")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "комментарий удалён"})
+}
+
+// hideComment обрабатывает POST /v1/posts/{id}/comments/{commentID}/hide
+func (h *Handler) hideComment(w http.ResponseWriter, r *http.Request) {
+	h.setCommentHidden(w, r, true)
+}
+
+// unhideComment обрабатывает DELETE /v1/posts/{id}/comments/{commentID}/hide
+func (h *Handler) unhideComment(w http.ResponseWriter, r *http.Request) {
+	h.setCommentHidden(w, r, false)
+}
+
+func (h *Handler) setCommentHidden(w http.ResponseWriter, r *http.Request, hidden bool) {
+	postID, commentID, ok := commentParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.commentService.SetHidden(postID, commentID, getUserID(r), hidden); err != nil {
+		commentModerationError(w, err, "ошибка скрытия комментария")
+		return
+	}
+
+	message := "комментарий скрыт"
+	if !hidden {
+		message = "комментарий снова виден"
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": message})
+}
+
 // getComments обрабатывает GET /v1/posts/{id}/comments
 func (h *Handler) getComments(w http.ResponseWriter, r *http.Request) {
 	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
@@ -49,11 +166,48 @@ func (h *Handler) getComments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	comments, err := h.commentService.GetByPostID(postID)
+	page, err := readPage(r)
 	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный курсор")
+		return
+	}
+
+	comments, err := h.commentService.GetByPostID(postID, getUserID(r), page)
+	if err != nil {
+		if err == service.ErrPostNotFound {
+			jsonError(w, http.StatusNotFound, "пост не найден")
+			return
+		}
 		jsonError(w, http.StatusInternalServerError, "ошибка получения комментариев")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, comments)
+	writePage(w, r, comments)
+}
+
+// getCommentReplies обрабатывает GET /v1/comments/{id}/replies
+func (h *Handler) getCommentReplies(w http.ResponseWriter, r *http.Request) {
+	commentID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID комментария")
+		return
+	}
+
+	page, err := readPage(r)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный курсор")
+		return
+	}
+
+	replies, err := h.commentService.GetReplies(commentID, getUserID(r), page)
+	if err != nil {
+		if err == service.ErrCommentNotFound {
+			jsonError(w, http.StatusNotFound, "комментарий не найден")
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "ошибка получения ответов")
+		return
+	}
+
+	writePage(w, r, replies)
 }
@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"social-network/internal/service"
+)
+
+// createConversationRequest — тело запроса создания диалога: user_id — личный диалог,
+// user_ids — группа
+type createConversationRequest struct {
+	UserID  int    `json:"user_id"`
+	UserIDs []int  `json:"user_ids"`
+	Title   string `json:"title"`
+}
+
+// sendMessageRequest — тело запроса отправки сообщения
+type sendMessageRequest struct {
+	Content string `json:"content"`
+}
+
+// markConversationReadRequest — тело запроса отметки о прочтении; без message_id — весь диалог
+type markConversationReadRequest struct {
+	MessageID int `json:"message_id"`
+}
+
+// getConversations обрабатывает GET /v1/conversations?archived=true
+func (h *Handler) getConversations(w http.ResponseWriter, r *http.Request) {
+	page, err := readPage(r)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный курсор")
+		return
+	}
+	archived := r.URL.Query().Get("archived") == "true"
+
+	conversations, err := h.messageService.List(getUserID(r), archived, page)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка получения диалогов")
+		return
+	}
+
+	writePage(w, r, conversations)
+}
+
+// getUnreadConversationsCount обрабатывает GET /v1/conversations/unread-count
+func (h *Handler) getUnreadConversationsCount(w http.ResponseWriter, r *http.Request) {
+	count, err := h.messageService.UnreadCount(getUserID(r))
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка подсчёта диалогов")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"count": count})
+}
+
+// createConversation обрабатывает POST /v1/conversations. Личный диалог с тем же
+// собеседником не создаётся повторно — возвращается существующий
+func (h *Handler) createConversation(w http.ResponseWriter, r *http.Request) {
+	var req createConversationRequest
+	if err := readJSON(r, &req); err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	userID := getUserID(r)
+	if req.UserIDs == nil {
+		if req.UserID == 0 {
+			jsonError(w, http.StatusBadRequest, "нужен user_id собеседника или user_ids участников группы")
+			return
+		}
+		conversation, err := h.messageService.StartDirect(userID, req.UserID)
+		if err != nil {
+			messageError(w, err, "ошибка создания диалога")
+			return
+		}
+		writeJSON(w, http.StatusOK, conversation)
+		return
+	}
+
+	conversation, err := h.messageService.CreateGroup(userID, req.Title, req.UserIDs)
+	if err != nil {
+		messageError(w, err, "ошибка создания группы")
+		return
+	}
+	writeJSON(w, http.StatusCreated, conversation)
+}
+
+// getConversation обрабатывает GET /v1/conversations/{id}
+func (h *Handler) getConversation(w http.ResponseWriter, r *http.Request) {
+	id, ok := conversationID(w, r)
+	if !ok {
+		return
+	}
+
+	conversation, err := h.messageService.Get(getUserID(r), id)
+	if err != nil {
+		messageError(w, err, "ошибка получения диалога")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, conversation)
+}
+
+// leaveConversation обрабатывает DELETE /v1/conversations/{id} — выход из группы
+func (h *Handler) leaveConversation(w http.ResponseWriter, r *http.Request) {
+	id, ok := conversationID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.messageService.Leave(getUserID(r), id); err != nil {
+		messageError(w, err, "ошибка выхода из группы")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "вы вышли из группы"})
+}
+
+// getMessages обрабатывает GET /v1/conversations/{id}/messages
+func (h *Handler) getMessages(w http.ResponseWriter, r *http.Request) {
+	id, ok := conversationID(w, r)
+	if !ok {
+		return
+	}
+	page, err := readPage(r)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный курсор")
+		return
+	}
+
+	messages, err := h.messageService.Messages(getUserID(r), id, page)
+	if err != nil {
+		messageError(w, err, "ошибка получения сообщений")
+		return
+	}
+
+	writePage(w, r, messages)
+}
+
+// sendMessage обрабатывает POST /v1/conversations/{id}/messages
+func (h *Handler) sendMessage(w http.ResponseWriter, r *http.Request) {
+	id, ok := conversationID(w, r)
+	if !ok {
+		return
+	}
+
+	var req sendMessageRequest
+	if err := readJSON(r, &req); err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	msg, err := h.messageService.Send(getUserID(r), id, req.Content)
+	if err != nil {
+		messageError(w, err, "ошибка отправки сообщения")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, msg)
+}
+
+// markConversationRead обрабатывает POST /v1/conversations/{id}/read
+func (h *Handler) markConversationRead(w http.ResponseWriter, r *http.Request) {
+	id, ok := conversationID(w, r)
+	if !ok {
+		return
+	}
+
+	var req markConversationReadRequest
+	if err := readJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	lastReadID, err := h.messageService.MarkRead(getUserID(r), id, req.MessageID)
+	if err != nil {
+		messageError(w, err, "ошибка отметки о прочтении")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"last_read_id": lastReadID})
+}
+
+// muteConversation обрабатывает POST /v1/conversations/{id}/mute
+func (h *Handler) muteConversation(w http.ResponseWriter, r *http.Request) {
+	h.setConversationFlag(w, r, h.messageService.SetMuted, true, "диалог заглушён")
+}
+
+// unmuteConversation обрабатывает DELETE /v1/conversations/{id}/mute
+func (h *Handler) unmuteConversation(w http.ResponseWriter, r *http.Request) {
+	h.setConversationFlag(w, r, h.messageService.SetMuted, false, "звук диалога включён")
+}
+
+// archiveConversation обрабатывает POST /v1/conversations/{id}/archive
+func (h *Handler) archiveConversation(w http.ResponseWriter, r *http.Request) {
+	h.setConversationFlag(w, r, h.messageService.SetArchived, true, "диалог в архиве")
+}
+
+// unarchiveConversation обрабатывает DELETE /v1/conversations/{id}/archive
+func (h *Handler) unarchiveConversation(w http.ResponseWriter, r *http.Request) {
+	h.setConversationFlag(w, r, h.messageService.SetArchived, false, "диалог возвращён из архива")
+}
+
+// setConversationFlag меняет личную настройку участника диалога
+func (h *Handler) setConversationFlag(w http.ResponseWriter, r *http.Request,
+	set func(userID, conversationID int, value bool) error, value bool, message string) {
+	id, ok := conversationID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := set(getUserID(r), id, value); err != nil {
+		messageError(w, err, "ошибка изменения диалога")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": message})
+}
+
+// conversationID читает ID диалога из пути
+func conversationID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID диалога")
+		return 0, false
+	}
+	return id, true
+}
+
+// messageError переводит ошибку сообщений в HTTP-ответ
+func messageError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrConversationNotFound), errors.Is(err, service.ErrUserNotFound):
+		jsonError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrMessagesRestricted):
+		jsonError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrSelfConversation), errors.Is(err, service.ErrEmptyMessage),
+		errors.Is(err, service.ErrMessageTooLong), errors.Is(err, service.ErrGroupTooSmall),
+		errors.Is(err, service.ErrGroupTooLarge), errors.Is(err, service.ErrGroupTitleTooLong),
+		errors.Is(err, service.ErrLeaveDirect), errors.Is(err, service.ErrGroupMembersBlocked):
+		jsonError(w, http.StatusBadRequest, err.Error())
+	default:
+		jsonError(w, http.StatusInternalServerError, fallback)
+	}
+}
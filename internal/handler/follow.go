@@ -9,7 +9,8 @@ import (
 	"social-network/internal/service"
 )
 
-// followUser обрабатывает POST /v1/users/{id}/follow
+// followUser обрабатывает POST /v1/users/{id}/follow. На закрытый аккаунт отправляется
+// запрос на подписку — ответ 202
 func (h *Handler) followUser(w http.ResponseWriter, r *http.Request) {
 	followingID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
@@ -18,15 +19,24 @@ func (h *Handler) followUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	followerID := getUserID(r)
-	if err := h.followService.Follow(followerID, followingID); err != nil {
+	requested, err := h.followService.Follow(followerID, followingID)
+	if err != nil {
 		if err == service.ErrSelfFollow {
 			jsonError(w, http.StatusBadRequest, "нельзя подписаться на себя")
 			return
 		}
+		if err == service.ErrUserNotFound {
+			jsonError(w, http.StatusNotFound, "пользователь не найден")
+			return
+		}
 		jsonError(w, http.StatusInternalServerError, "ошибка подписки")
 		return
 	}
 
+	if requested {
+		writeJSON(w, http.StatusAccepted, map[string]string{"message": "запрос на подписку отправлен"})
+		return
+	}
 	writeJSON(w, http.StatusOK, map[string]string{"message": "вы подписались"})
 }
 
@@ -55,13 +65,19 @@ func (h *Handler) getFollowers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	users, err := h.followService.GetFollowers(userID)
+	page, err := readPage(r)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный курсор")
+		return
+	}
+
+	users, err := h.followService.GetFollowers(userID, getUserID(r), page)
 	if err != nil {
 		jsonError(w, http.StatusInternalServerError, "ошибка получения подписчиков")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, users)
+	writePage(w, r, users)
 }
 
 // getFollowing обрабатывает GET /v1/users/{id}/following
@@ -72,11 +88,53 @@ func (h *Handler) getFollowing(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	users, err := h.followService.GetFollowing(userID)
+	page, err := readPage(r)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный курсор")
+		return
+	}
+
+	users, err := h.followService.GetFollowing(userID, getUserID(r), page)
 	if err != nil {
 		jsonError(w, http.StatusInternalServerError, "ошибка получения подписок")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, users)
+	writePage(w, r, users)
+}
+
+// getFollowRequests обрабатывает GET /v1/users/me/follow-requests
+func (h *Handler) getFollowRequests(w http.ResponseWriter, r *http.Request) {
+	h.listUsers(w, r, h.followService.GetRequests, "ошибка получения запросов на подписку")
+}
+
+// approveFollowRequest обрабатывает POST /v1/users/me/follow-requests/{id}
+func (h *Handler) approveFollowRequest(w http.ResponseWriter, r *http.Request) {
+	h.resolveFollowRequest(w, r, h.followService.ApproveRequest, "запрос одобрен")
+}
+
+// denyFollowRequest обрабатывает DELETE /v1/users/me/follow-requests/{id}
+func (h *Handler) denyFollowRequest(w http.ResponseWriter, r *http.Request) {
+	h.resolveFollowRequest(w, r, h.followService.DenyRequest, "запрос отклонён")
+}
+
+// resolveFollowRequest одобряет или отклоняет запрос пользователя из пути
+func (h *Handler) resolveFollowRequest(w http.ResponseWriter, r *http.Request,
+	resolve func(userID, followerID int) error, message string) {
+	followerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID пользователя")
+		return
+	}
+
+	if err := resolve(getUserID(r), followerID); err != nil {
+		if err == service.ErrFollowRequestNotFound {
+			jsonError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "ошибка обработки запроса на подписку")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": message})
 }
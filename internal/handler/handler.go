@@ -10,6 +10,16 @@ type Handler struct {
 	commentService *service.CommentService
 	followService  *service.FollowService
 	likeService    *service.LikeService
+	oauthService   *service.OAuthService
+	adminService   *service.AdminService
+	searchService  *service.SearchService
+
+	notificationService *service.NotificationService
+	streamService       *service.StreamService
+	messageService      *service.MessageService
+	blockService        *service.BlockService
+
+	secureCookies bool // Cookie с флагом Secure — только по HTTPS
 }
 
 // NewHandler создаёт новый Handler с внедрёнными зависимостями
@@ -20,6 +30,14 @@ func NewHandler(
 	commentService *service.CommentService,
 	followService *service.FollowService,
 	likeService *service.LikeService,
+	oauthService *service.OAuthService,
+	adminService *service.AdminService,
+	searchService *service.SearchService,
+	notificationService *service.NotificationService,
+	streamService *service.StreamService,
+	messageService *service.MessageService,
+	blockService *service.BlockService,
+	secureCookies bool,
 ) *Handler {
 	return &Handler{
 		authService:    authService,
@@ -28,5 +46,15 @@ func NewHandler(
 		commentService: commentService,
 		followService:  followService,
 		likeService:    likeService,
+		oauthService:   oauthService,
+		adminService:   adminService,
+		searchService:  searchService,
+
+		notificationService: notificationService,
+		streamService:       streamService,
+		messageService:      messageService,
+		blockService:        blockService,
+
+		secureCookies: secureCookies,
 	}
 }
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"social-network/internal/service"
+)
+
+// getHashtagPosts обрабатывает GET /v1/hashtags/{tag}/posts
+func (h *Handler) getHashtagPosts(w http.ResponseWriter, r *http.Request) {
+	page, err := readPage(r)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный курсор")
+		return
+	}
+
+	// chi отдаёт параметр как есть, если путь пришёл не целиком в %-кодировке
+	tag, err := url.PathUnescape(chi.URLParam(r, "tag"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный тег")
+		return
+	}
+
+	posts, err := h.postService.GetByHashtag(tag, getUserID(r), page)
+	if errors.Is(err, service.ErrEmptyHashtag) {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка получения постов по тегу")
+		return
+	}
+
+	writePage(w, r, posts)
+}
+
+// getTrendingHashtags обрабатывает GET /v1/hashtags/trending?limit=
+func (h *Handler) getTrendingHashtags(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	tags, err := h.postService.Trending(limit)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка получения популярных тегов")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tags)
+}
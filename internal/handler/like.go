@@ -5,6 +5,8 @@ import (
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+
+	"social-network/internal/service"
 )
 
 // likePost обрабатывает POST /v1/posts/{id}/like
@@ -17,6 +19,10 @@ func (h *Handler) likePost(w http.ResponseWriter, r *http.Request) {
 
 	userID := getUserID(r)
 	if err := h.likeService.Like(userID, postID); err != nil {
+		if err == service.ErrPostNotFound {
+			jsonError(w, http.StatusNotFound, "пост не найден")
+			return
+		}
 		jsonError(w, http.StatusInternalServerError, "ошибка лайка")
 		return
 	}
@@ -34,6 +40,10 @@ func (h *Handler) unlikePost(w http.ResponseWriter, r *http.Request) {
 
 	userID := getUserID(r)
 	if err := h.likeService.Unlike(userID, postID); err != nil {
+		if err == service.ErrPostNotFound {
+			jsonError(w, http.StatusNotFound, "пост не найден")
+			return
+		}
 		jsonError(w, http.StatusInternalServerError, "ошибка снятия лайка")
 		return
 	}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"social-network/internal/service"
+)
+
+// loginMFARequest — тело запроса второго шага входа
+type loginMFARequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// mfaCodeRequest — тело запросов, подтверждаемых кодом 2FA
+type mfaCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// loginMFA обрабатывает POST /v1/auth/login/mfa
+func (h *Handler) loginMFA(w http.ResponseWriter, r *http.Request) {
+	var req loginMFARequest
+	if err := readJSON(r, &req); err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	if req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		jsonError(w, http.StatusBadRequest, "mfa_token и код обязательны")
+		return
+	}
+
+	result, err := h.authService.LoginMFA(req.MFAToken, req.Code, req.RecoveryCode, clientInfo(r))
+	if err != nil {
+		if throttled(w, err) {
+			return
+		}
+		switch err {
+		case service.ErrInvalidToken:
+			jsonError(w, http.StatusUnauthorized, "MFA-токен недействителен или истёк")
+		case service.ErrInvalidMFACode, service.ErrMFANotEnabled:
+			jsonError(w, http.StatusUnauthorized, "неверный код")
+		case service.ErrAccountSuspended:
+			jsonError(w, http.StatusForbidden, "аккаунт заблокирован")
+		default:
+			log.Printf("Ошибка второго шага входа: %v", err)
+			jsonError(w, http.StatusInternalServerError, "ошибка входа")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// getMFAStatus обрабатывает GET /v1/users/me/mfa
+func (h *Handler) getMFAStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.authService.MFAStatus(getUserID(r))
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка получения настроек 2FA")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// enrollTOTP обрабатывает POST /v1/users/me/mfa/totp
+func (h *Handler) enrollTOTP(w http.ResponseWriter, r *http.Request) {
+	enrollment, err := h.authService.EnrollTOTP(getUserID(r))
+	if err != nil {
+		if err == service.ErrMFAAlreadyEnabled {
+			jsonError(w, http.StatusConflict, "двухфакторная аутентификация уже включена")
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "ошибка подключения 2FA")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, enrollment)
+}
+
+// confirmTOTP обрабатывает POST /v1/users/me/mfa/totp/confirm
+func (h *Handler) confirmTOTP(w http.ResponseWriter, r *http.Request) {
+	var req mfaCodeRequest
+	if err := readJSON(r, &req); err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	if req.Code == "" {
+		jsonError(w, http.StatusBadRequest, "код обязателен")
+		return
+	}
+
+	codes, err := h.authService.ConfirmTOTP(getUserID(r), req.Code)
+	if err != nil {
+		h.mfaError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+// disableTOTP обрабатывает DELETE /v1/users/me/mfa/totp
+func (h *Handler) disableTOTP(w http.ResponseWriter, r *http.Request) {
+	var req mfaCodeRequest
+	if err := readJSON(r, &req); err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	if req.Code == "" && req.RecoveryCode == "" {
+		jsonError(w, http.StatusBadRequest, "код обязателен")
+		return
+	}
+
+	if err := h.authService.DisableTOTP(getUserID(r), req.Code, req.RecoveryCode); err != nil {
+		h.mfaError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "двухфакторная аутентификация отключена"})
+}
+
+// regenerateRecoveryCodes обрабатывает POST /v1/users/me/mfa/recovery-codes
+func (h *Handler) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var req mfaCodeRequest
+	if err := readJSON(r, &req); err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	if req.Code == "" {
+		jsonError(w, http.StatusBadRequest, "код обязателен")
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(getUserID(r), req.Code)
+	if err != nil {
+		h.mfaError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+// mfaError переводит ошибки управления 2FA в HTTP-ответы
+func (h *Handler) mfaError(w http.ResponseWriter, err error) {
+	switch err {
+	case service.ErrInvalidMFACode:
+		jsonError(w, http.StatusBadRequest, "неверный код")
+	case service.ErrMFANotEnrolled:
+		jsonError(w, http.StatusBadRequest, "сначала начните подключение TOTP")
+	case service.ErrMFANotEnabled:
+		jsonError(w, http.StatusBadRequest, "двухфакторная аутентификация не включена")
+	case service.ErrMFAAlreadyEnabled:
+		jsonError(w, http.StatusConflict, "двухфакторная аутентификация уже включена")
+	default:
+		jsonError(w, http.StatusInternalServerError, "ошибка настройки 2FA")
+	}
+}
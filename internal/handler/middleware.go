@@ -3,17 +3,26 @@ package handler
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"strings"
 	"time"
+
+	"social-network/internal/model"
+	"social-network/internal/service"
 )
 
 // contextKey — тип ключа для контекста
 type contextKey string
 
-const userIDKey contextKey = "user_id"
+const (
+	userIDKey    contextKey = "user_id"
+	sessionIDKey contextKey = "session_id"
+	claimsKey    contextKey = "claims"
+)
 
-// AuthMiddleware проверяет JWT и добавляет userID в контекст
+// AuthMiddleware проверяет JWT или персональный токен, не заблокирован ли аккаунт,
+// и добавляет userID в контекст
 func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		header := r.Header.Get("Authorization")
@@ -28,17 +37,76 @@ func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		userID, err := h.authService.ParseToken(parts[1])
+		claims, err := h.authService.ParseToken(parts[1])
 		if err != nil {
 			jsonError(w, http.StatusUnauthorized, "невалидный токен")
 			return
 		}
+		if err := h.authService.EnsureActive(claims.UserID); err != nil {
+			switch err {
+			case service.ErrAccountSuspended:
+				jsonError(w, http.StatusForbidden, "аккаунт заблокирован")
+			case service.ErrInvalidToken:
+				jsonError(w, http.StatusUnauthorized, "невалидный токен")
+			default:
+				jsonError(w, http.StatusInternalServerError, "ошибка проверки аккаунта")
+			}
+			return
+		}
 
-		ctx := context.WithValue(r.Context(), userIDKey, userID)
+		ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, sessionIDKey, claims.SessionID)
+		ctx = context.WithValue(ctx, claimsKey, claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireScope пропускает запрос, только если токену разрешены все перечисленные
+// области доступа. Токены сессии имеют полный доступ. Ставится после AuthMiddleware
+func RequireScope(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := getClaims(r)
+			for _, scope := range scopes {
+				if claims == nil || !claims.HasScope(scope) {
+					jsonError(w, http.StatusForbidden, "токену не хватает прав: "+scope)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole пропускает только пользователей с ролью не ниже role.
+// Роль берётся из access-токена. Ставится после AuthMiddleware
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if claims := getClaims(r); claims == nil || !model.RoleAtLeast(claims.Role, role) {
+				jsonError(w, http.StatusForbidden, "недостаточно прав")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession закрывает управление аккаунтом от персональных токенов:
+// пароль, 2FA, сессии и сами токены меняются только после входа по паролю
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims := getClaims(r); claims == nil || claims.IsAccessToken() {
+			jsonError(w, http.StatusForbidden, "действие недоступно для персональных токенов")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // OptionalAuthMiddleware — если есть токен, добавляет userID, иначе 0
 func (h *Handler) OptionalAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -48,8 +116,9 @@ func (h *Handler) OptionalAuthMiddleware(next http.Handler) http.Handler {
 		if header != "" {
 			parts := strings.SplitN(header, " ", 2)
 			if len(parts) == 2 && parts[0] == "Bearer" {
-				if id, err := h.authService.ParseToken(parts[1]); err == nil {
-					userID = id
+				// Заблокированный или удалённый аккаунт видит сервис как гость
+				if claims, err := h.authService.ParseToken(parts[1]); err == nil && h.authService.EnsureActive(claims.UserID) == nil {
+					userID = claims.UserID
 				}
 			}
 		}
@@ -59,6 +128,23 @@ func (h *Handler) OptionalAuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// RequireVerifiedEmail не пускает пользователей с неподтверждённым email,
+// если это требует политика сервиса авторизации. Ставится после AuthMiddleware
+func (h *Handler) RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h.authService.EnsureVerified(getUserID(r)); err != nil {
+			if err == service.ErrEmailNotVerified {
+				jsonError(w, http.StatusForbidden, "подтвердите email, чтобы публиковать")
+				return
+			}
+			jsonError(w, http.StatusInternalServerError, "ошибка проверки email")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // getUserID извлекает userID из контекста запроса
 func getUserID(r *http.Request) int {
 	if id, ok := r.Context().Value(userIDKey).(int); ok {
@@ -67,11 +153,64 @@ func getUserID(r *http.Request) int {
 	return 0
 }
 
+// getSessionID извлекает ID сессии текущего access-токена из контекста
+func getSessionID(r *http.Request) string {
+	id, _ := r.Context().Value(sessionIDKey).(string)
+	return id
+}
+
+// getClaims извлекает данные токена авторизации из контекста
+func getClaims(r *http.Request) *service.TokenClaims {
+	claims, _ := r.Context().Value(claimsKey).(*service.TokenClaims)
+	return claims
+}
+
+// clientInfo собирает данные клиента для записи в сессию
+func clientInfo(r *http.Request) model.ClientInfo {
+	return model.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IPAddress: clientIP(r),
+	}
+}
+
+// clientIP возвращает IP клиента с учётом прокси перед приложением.
+// X-Forwarded-For учитывается только от прокси в локальной сети: иначе клиент
+// мог бы подставить любой адрес и обойти ограничения попыток входа по IP
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrustedProxy(host) {
+		return host
+	}
+
+	// Идём справа налево: правые адреса дописали наши прокси, первый чужой — клиент
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(hops[i])
+		if ip == "" {
+			continue
+		}
+		if !isTrustedProxy(ip) {
+			return ip
+		}
+		host = ip
+	}
+	return host
+}
+
+// isTrustedProxy сообщает, что адрес принадлежит локальной сети (там стоят наши прокси)
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate())
+}
+
 // CORSMiddleware добавляет CORS-заголовки
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		if r.Method == http.MethodOptions {
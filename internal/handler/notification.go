@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"social-network/internal/service"
+)
+
+// getNotifications обрабатывает GET /v1/notifications
+func (h *Handler) getNotifications(w http.ResponseWriter, r *http.Request) {
+	page, err := readPage(r)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный курсор")
+		return
+	}
+
+	notifications, err := h.notificationService.List(getUserID(r), page)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка получения уведомлений")
+		return
+	}
+
+	writePage(w, r, notifications)
+}
+
+// getUnreadNotificationsCount обрабатывает GET /v1/notifications/unread-count
+func (h *Handler) getUnreadNotificationsCount(w http.ResponseWriter, r *http.Request) {
+	count, err := h.notificationService.UnreadCount(getUserID(r))
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка подсчёта уведомлений")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"count": count})
+}
+
+// markNotificationRead обрабатывает POST /v1/notifications/{id}/read
+func (h *Handler) markNotificationRead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID уведомления")
+		return
+	}
+
+	err = h.notificationService.MarkRead(getUserID(r), id)
+	if errors.Is(err, service.ErrNotificationNotFound) {
+		jsonError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка отметки уведомления")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "уведомление прочитано"})
+}
+
+// markAllNotificationsRead обрабатывает POST /v1/notifications/read
+func (h *Handler) markAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	if err := h.notificationService.MarkAllRead(getUserID(r)); err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка отметки уведомлений")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "все уведомления прочитаны"})
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"social-network/internal/service"
+)
+
+// oauthStateCookie привязывает state к браузеру, начавшему вход: без него чужую ссылку
+// на callback можно было бы подсунуть жертве и привязать её аккаунт у провайдера к своему
+const oauthStateCookie = "oauth_state"
+
+// oauthStart обрабатывает GET /v1/auth/oauth/{provider}/start — перенаправляет на страницу входа провайдера
+func (h *Handler) oauthStart(w http.ResponseWriter, r *http.Request) {
+	authURL, state, err := h.oauthService.Start(r.Context(), chi.URLParam(r, "provider"), 0)
+	if err != nil {
+		oauthError(w, err)
+		return
+	}
+
+	h.setOAuthStateCookie(w, state)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// oauthCallback обрабатывает GET /v1/auth/oauth/{provider}/callback
+func (h *Handler) oauthCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		jsonError(w, http.StatusBadRequest, "вход через провайдера отменён: "+providerErr)
+		return
+	}
+
+	code, state := query.Get("code"), query.Get("state")
+	if code == "" || state == "" {
+		jsonError(w, http.StatusBadRequest, "code и state обязательны")
+		return
+	}
+
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		jsonError(w, http.StatusBadRequest, "вход начат в другом браузере или устарел")
+		return
+	}
+	h.clearOAuthStateCookie(w)
+
+	result, err := h.oauthService.Callback(r.Context(), chi.URLParam(r, "provider"), code, state, clientInfo(r))
+	if err != nil {
+		oauthError(w, err)
+		return
+	}
+
+	if result.Linked != nil {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"message":  "аккаунт привязан",
+			"identity": result.Linked,
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, result.Login)
+}
+
+// getIdentities обрабатывает GET /v1/users/me/identities
+func (h *Handler) getIdentities(w http.ResponseWriter, r *http.Request) {
+	identities, err := h.oauthService.ListIdentities(getUserID(r))
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка получения привязанных аккаунтов")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, identities)
+}
+
+// linkIdentity обрабатывает POST /v1/users/me/identities/{provider}.
+// Возвращает адрес провайдера: SPA переходит по нему, а callback привязывает аккаунт
+func (h *Handler) linkIdentity(w http.ResponseWriter, r *http.Request) {
+	authURL, state, err := h.oauthService.Start(r.Context(), chi.URLParam(r, "provider"), getUserID(r))
+	if err != nil {
+		oauthError(w, err)
+		return
+	}
+
+	h.setOAuthStateCookie(w, state)
+	writeJSON(w, http.StatusOK, map[string]string{"authorization_url": authURL})
+}
+
+// unlinkIdentity обрабатывает DELETE /v1/users/me/identities/{provider}
+func (h *Handler) unlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	if err := h.oauthService.Unlink(getUserID(r), chi.URLParam(r, "provider")); err != nil {
+		oauthError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "провайдер отвязан"})
+}
+
+// oauthError переводит ошибки внешнего входа в HTTP-ответ
+func oauthError(w http.ResponseWriter, err error) {
+	switch err {
+	case service.ErrUnknownProvider:
+		jsonError(w, http.StatusNotFound, "провайдер не настроен")
+	case service.ErrIdentityNotFound:
+		jsonError(w, http.StatusNotFound, "провайдер не привязан")
+	case service.ErrProviderUnavailable:
+		jsonError(w, http.StatusBadGateway, "провайдер недоступен")
+	case service.ErrOAuthState:
+		jsonError(w, http.StatusBadRequest, "вход устарел, начните заново")
+	case service.ErrOAuthEmailMissing:
+		jsonError(w, http.StatusBadRequest, "провайдер не передал email")
+	case service.ErrOAuthExchange:
+		jsonError(w, http.StatusUnauthorized, "провайдер не подтвердил вход")
+	case service.ErrOAuthEmailTaken:
+		jsonError(w, http.StatusConflict, "аккаунт с таким email уже есть: войдите и привяжите провайдера в профиле")
+	case service.ErrIdentityLinked:
+		jsonError(w, http.StatusConflict, "этот аккаунт провайдера привязан к другому пользователю")
+	case service.ErrProviderAlreadyLinked:
+		jsonError(w, http.StatusConflict, "к аккаунту уже привязан другой аккаунт этого провайдера")
+	case service.ErrAccountSuspended:
+		jsonError(w, http.StatusForbidden, "аккаунт заблокирован")
+	case service.ErrLastLoginMethod:
+		jsonError(w, http.StatusConflict, "нельзя отвязать единственный способ входа: сначала задайте пароль")
+	default:
+		log.Printf("Ошибка входа через провайдера: %v", err)
+		jsonError(w, http.StatusInternalServerError, "ошибка входа через провайдера")
+	}
+}
+
+func (h *Handler) setOAuthStateCookie(w http.ResponseWriter, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/v1/auth/oauth",
+		MaxAge:   600, // Столько же живёт state в сервисе
+		HttpOnly: true,
+		Secure:   h.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (h *Handler) clearOAuthStateCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Path:     "/v1/auth/oauth",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
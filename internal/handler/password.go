@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"social-network/internal/service"
+)
+
+// changePasswordRequest — тело запроса смены пароля
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// forgotPasswordRequest — тело запроса письма для сброса пароля
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// resetPasswordRequest — тело запроса сброса пароля по токену
+type resetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// changePassword обрабатывает PUT /v1/users/me/password
+func (h *Handler) changePassword(w http.ResponseWriter, r *http.Request) {
+	var req changePasswordRequest
+	if err := readJSON(r, &req); err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		jsonError(w, http.StatusBadRequest, "текущий и новый пароль обязательны")
+		return
+	}
+
+	if len(req.NewPassword) < minPasswordLength {
+		jsonError(w, http.StatusBadRequest, "пароль должен быть не менее 6 символов")
+		return
+	}
+
+	err := h.authService.ChangePassword(getUserID(r), getSessionID(r), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		if err == service.ErrWrongPassword {
+			jsonError(w, http.StatusForbidden, "неверный текущий пароль")
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "ошибка смены пароля")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "пароль изменён"})
+}
+
+// forgotPassword обрабатывает POST /v1/auth/password/forgot
+func (h *Handler) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotPasswordRequest
+	if err := readJSON(r, &req); err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	if req.Email == "" {
+		jsonError(w, http.StatusBadRequest, "email обязателен")
+		return
+	}
+
+	// Ответ одинаковый для любого email и при сбое отправки — иначе по ошибке
+	// почтового сервиса можно узнать, кто зарегистрирован
+	if err := h.authService.RequestPasswordReset(req.Email); err != nil {
+		log.Printf("Ошибка запроса сброса пароля: %v", err)
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"message": "если аккаунт существует, письмо со ссылкой отправлено",
+	})
+}
+
+// resetPassword обрабатывает POST /v1/auth/password/reset
+func (h *Handler) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if err := readJSON(r, &req); err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	if req.Token == "" || req.NewPassword == "" {
+		jsonError(w, http.StatusBadRequest, "токен и новый пароль обязательны")
+		return
+	}
+
+	if len(req.NewPassword) < minPasswordLength {
+		jsonError(w, http.StatusBadRequest, "пароль должен быть не менее 6 символов")
+		return
+	}
+
+	if err := h.authService.ResetPassword(req.Token, req.NewPassword); err != nil {
+		if err == service.ErrInvalidToken {
+			jsonError(w, http.StatusBadRequest, "ссылка недействительна или устарела")
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "ошибка сброса пароля")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "пароль изменён, войдите заново"})
+}
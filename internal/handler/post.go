@@ -12,11 +12,13 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"social-network/internal/model"
 	"social-network/internal/service"
 )
 
 // createPost обрабатывает POST /v1/posts
-// Принимает multipart/form-data с полями: content (текст), image (файл, необязательно)
+// Принимает multipart/form-data с полями: content (текст), image (файл, необязательно),
+// quote_of (ID цитируемого поста, необязательно)
 func (h *Handler) createPost(w http.ResponseWriter, r *http.Request) {
 	userID := getUserID(r)
 
@@ -24,12 +26,21 @@ func (h *Handler) createPost(w http.ResponseWriter, r *http.Request) {
 
 	var content string
 	var imageURL string
+	var quoteOf *int
 
 	if strings.HasPrefix(contentType, "multipart/form-data") {
 		// Multipart — может содержать изображение
 		r.ParseMultipartForm(10 << 20) // 10 MB
 
 		content = r.FormValue("content")
+		if v := r.FormValue("quote_of"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				jsonError(w, http.StatusBadRequest, "неверный ID цитируемого поста")
+				return
+			}
+			quoteOf = &id
+		}
 
 		file, header, err := r.FormFile("image")
 		if err == nil {
@@ -68,12 +79,14 @@ func (h *Handler) createPost(w http.ResponseWriter, r *http.Request) {
 		// JSON-запрос (обратная совместимость)
 		var req struct {
 			Content string `json:"content"`
+			QuoteOf *int   `json:"quote_of"`
 		}
 		if err := readJSON(r, &req); err != nil {
 			jsonError(w, http.StatusBadRequest, "неверный формат запроса")
 			return
 		}
 		content = req.Content
+		quoteOf = req.QuoteOf
 	}
 
 	if content == "" && imageURL == "" {
@@ -81,8 +94,12 @@ func (h *Handler) createPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post, err := h.postService.Create(userID, content, imageURL)
+	post, err := h.postService.Create(userID, content, imageURL, quoteOf)
 	if err != nil {
+		if err == service.ErrQuoteNotFound {
+			jsonError(w, http.StatusBadRequest, "цитируемый пост не найден")
+			return
+		}
 		jsonError(w, http.StatusInternalServerError, "ошибка создания поста")
 		return
 	}
@@ -90,6 +107,158 @@ func (h *Handler) createPost(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, post)
 }
 
+// getPost обрабатывает GET /v1/posts/{id}
+// Параметр expand (через запятую): comments — первая страница комментариев, author — профиль автора
+func (h *Handler) getPost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID поста")
+		return
+	}
+
+	var expandComments, expandAuthor bool
+	if expand := r.URL.Query().Get("expand"); expand != "" {
+		for _, field := range strings.Split(expand, ",") {
+			switch strings.TrimSpace(field) {
+			case "comments":
+				expandComments = true
+			case "author":
+				expandAuthor = true
+			default:
+				jsonError(w, http.StatusBadRequest, "expand поддерживает только comments и author")
+				return
+			}
+		}
+	}
+
+	currentUserID := getUserID(r)
+	post, err := h.postService.GetByID(postID, currentUserID)
+	if err != nil {
+		if err == service.ErrPostNotFound {
+			jsonError(w, http.StatusNotFound, "пост не найден")
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "ошибка получения поста")
+		return
+	}
+
+	detail := &model.PostDetail{Post: post}
+	if expandAuthor {
+		detail.Author, err = h.userService.GetProfile(post.UserID, currentUserID)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "ошибка получения профиля автора")
+			return
+		}
+	}
+	if expandComments {
+		detail.Comments, err = h.commentService.GetPreview(postID, post.UserID, currentUserID)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, "ошибка получения комментариев")
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, detail)
+}
+
+// updatePostRequest — тело запроса редактирования поста
+type updatePostRequest struct {
+	Content string `json:"content"`
+}
+
+// updatePost обрабатывает PATCH /v1/posts/{id}
+func (h *Handler) updatePost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID поста")
+		return
+	}
+
+	var req updatePostRequest
+	if err := readJSON(r, &req); err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	post, err := h.postService.Update(postID, getUserID(r), req.Content)
+	if err != nil {
+		switch err {
+		case service.ErrNotPostOwner:
+			jsonError(w, http.StatusForbidden, "вы не являетесь автором поста")
+		case service.ErrPostNotFound:
+			jsonError(w, http.StatusNotFound, "пост не найден")
+		case service.ErrEmptyPost:
+			jsonError(w, http.StatusBadRequest, "текст или изображение обязательны")
+		default:
+			jsonError(w, http.StatusInternalServerError, "ошибка редактирования поста")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, post)
+}
+
+// getPostRevisions обрабатывает GET /v1/posts/{id}/revisions
+func (h *Handler) getPostRevisions(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID поста")
+		return
+	}
+
+	revisions, err := h.postService.GetRevisions(postID, getUserID(r))
+	if err != nil {
+		if err == service.ErrPostNotFound {
+			jsonError(w, http.StatusNotFound, "пост не найден")
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "ошибка получения истории правок")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, revisions)
+}
+
+// repost обрабатывает POST /v1/posts/{id}/repost
+func (h *Handler) repost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID поста")
+		return
+	}
+
+	if err := h.postService.Repost(getUserID(r), postID); err != nil {
+		if err == service.ErrPostNotFound {
+			jsonError(w, http.StatusNotFound, "пост не найден")
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "ошибка репоста")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "репост сделан"})
+}
+
+// unrepost обрабатывает DELETE /v1/posts/{id}/repost
+func (h *Handler) unrepost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный ID поста")
+		return
+	}
+
+	if err := h.postService.Unrepost(getUserID(r), postID); err != nil {
+		if err == service.ErrPostNotFound {
+			jsonError(w, http.StatusNotFound, "пост не найден")
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "ошибка отмены репоста")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "репост отменён"})
+}
+
 // deletePost обрабатывает DELETE /v1/posts/{id}
 func (h *Handler) deletePost(w http.ResponseWriter, r *http.Request) {
 	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
@@ -117,17 +286,19 @@ func (h *Handler) deletePost(w http.ResponseWriter, r *http.Request) {
 
 // getFeed обрабатывает GET /v1/feed
 func (h *Handler) getFeed(w http.ResponseWriter, r *http.Request) {
-	userID := getUserID(r)
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	page, err := readPage(r)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный курсор")
+		return
+	}
 
-	posts, err := h.postService.GetFeed(userID, limit, offset)
+	posts, err := h.postService.GetFeed(getUserID(r), page)
 	if err != nil {
 		jsonError(w, http.StatusInternalServerError, "ошибка получения ленты")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, posts)
+	writePage(w, r, posts)
 }
 
 // getUserPosts обрабатывает GET /v1/users/{id}/posts
@@ -138,30 +309,35 @@ func (h *Handler) getUserPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	currentUserID := getUserID(r)
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	page, err := readPage(r)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный курсор")
+		return
+	}
 
-	posts, err := h.postService.GetByUserID(userID, currentUserID, limit, offset)
+	currentUserID := getUserID(r)
+	posts, err := h.postService.GetByUserID(userID, currentUserID, page)
 	if err != nil {
 		jsonError(w, http.StatusInternalServerError, "ошибка получения постов пользователя")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, posts)
+	writePage(w, r, posts)
 }
 
 // getFollowingFeed обрабатывает GET /v1/feed/following
 func (h *Handler) getFollowingFeed(w http.ResponseWriter, r *http.Request) {
-	userID := getUserID(r)
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	page, err := readPage(r)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный курсор")
+		return
+	}
 
-	posts, err := h.postService.GetFollowingFeed(userID, limit, offset)
+	posts, err := h.postService.GetFollowingFeed(getUserID(r), page)
 	if err != nil {
 		jsonError(w, http.StatusInternalServerError, "ошибка получения ленты подписок")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, posts)
+	writePage(w, r, posts)
 }
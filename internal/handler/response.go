@@ -2,7 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+
+	"social-network/internal/model"
+	"social-network/internal/service"
 )
 
 // writeJSON отправляет JSON-ответ с указанным статусом
@@ -23,3 +29,52 @@ func readJSON(r *http.Request, dst any) error {
 func jsonError(w http.ResponseWriter, status int, message string) {
 	writeJSON(w, status, map[string]string{"error": message})
 }
+
+// readPage читает из query limit, cursor и устаревший offset
+func readPage(r *http.Request) (model.PageRequest, error) {
+	q := r.URL.Query()
+	page := model.PageRequest{}
+	page.Limit, _ = strconv.Atoi(q.Get("limit"))
+	if cursor := q.Get("cursor"); cursor != "" {
+		after, err := model.ParseCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		page.After = after
+	} else {
+		page.Offset, _ = strconv.Atoi(q.Get("offset"))
+	}
+	return page, nil
+}
+
+// writePage отправляет страницу в конверте {items, next_cursor}.
+// Клиенты, листающие через offset, пока получают прежний массив с заголовком Deprecation
+func writePage[T any](w http.ResponseWriter, r *http.Request, page *model.Page[T]) {
+	q := r.URL.Query()
+	if q.Has("offset") && q.Get("cursor") == "" {
+		w.Header().Set("Deprecation", "true")
+		writeJSON(w, http.StatusOK, page.Items)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// throttled отвечает 423 (аккаунт заблокирован) или 429 (слишком частые попытки)
+// с заголовком Retry-After, если err — ограничение попыток входа
+func throttled(w http.ResponseWriter, err error) bool {
+	var limitErr *service.LoginThrottledError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+
+	retryAfter := int(math.Ceil(limitErr.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+
+	if limitErr.Locked {
+		jsonError(w, http.StatusLocked, "аккаунт временно заблокирован из-за неудачных попыток входа; "+
+			"подождите или сбросьте пароль")
+		return true
+	}
+	jsonError(w, http.StatusTooManyRequests, "слишком много попыток входа, попробуйте позже")
+	return true
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+
+	"social-network/internal/model"
 )
 
 // Routes возвращает настроенный chi-роутер со всеми маршрутами
@@ -20,6 +22,9 @@ func (h *Handler) Routes() http.Handler {
 		http.ServeFile(w, r, "web/index.html")
 	})
 
+	// Открытые ключи для проверки access-токенов другими сервисами
+	r.Get("/.well-known/jwks.json", h.jwks)
+
 	r.Route("/v1", func(r chi.Router) {
 		// Healthcheck
 		r.Get("/health", h.healthCheck)
@@ -28,12 +33,23 @@ func (h *Handler) Routes() http.Handler {
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/register", h.register)
 			r.Post("/login", h.login)
+			r.Post("/login/mfa", h.loginMFA)
 			r.Post("/refresh", h.refresh)
+			r.Post("/password/forgot", h.forgotPassword)
+			r.Post("/password/reset", h.resetPassword)
+			r.Post("/verify-email", h.verifyEmail)
+			r.Get("/oauth/{provider}/start", h.oauthStart)
+			r.Get("/oauth/{provider}/callback", h.oauthCallback)
 
-			// Logout — защищённый
+			// Logout и управление сессиями — защищённые, только из сессии
 			r.Group(func(r chi.Router) {
 				r.Use(h.AuthMiddleware)
+				r.Use(RequireSession)
 				r.Post("/logout", h.logout)
+				r.Post("/verify-email/resend", h.resendVerification)
+				r.Get("/sessions", h.getSessions)
+				r.Delete("/sessions", h.revokeOtherSessions)
+				r.Delete("/sessions/{id}", h.revokeSession)
 			})
 		})
 
@@ -46,6 +62,7 @@ func (h *Handler) Routes() http.Handler {
 		// Лента подписок — защищённая
 		r.Group(func(r chi.Router) {
 			r.Use(h.AuthMiddleware)
+			r.Use(RequireScope(model.ScopePostsRead, model.ScopeFollowsRead))
 			r.Get("/feed/following", h.getFollowingFeed)
 		})
 
@@ -54,17 +71,51 @@ func (h *Handler) Routes() http.Handler {
 			// /me маршруты — защищённые (должны быть ДО /{id})
 			r.Group(func(r chi.Router) {
 				r.Use(h.AuthMiddleware)
-				r.Get("/me", h.getMe)
-				r.Put("/me", h.updateProfile)
-				r.Post("/me/avatar", h.uploadAvatar)
+				r.With(RequireScope(model.ScopeProfileRead)).Get("/me", h.getMe)
+				r.With(RequireScope(model.ScopePostsRead)).Get("/me/mentions", h.getMyMentions)
+				r.With(RequireScope(model.ScopeFollowsRead)).Get("/me/blocks", h.getBlocks)
+				r.With(RequireScope(model.ScopeFollowsRead)).Get("/me/mutes", h.getMutes)
+				r.With(RequireScope(model.ScopeFollowsRead)).Get("/me/follow-requests", h.getFollowRequests)
+
+				r.Group(func(r chi.Router) {
+					r.Use(RequireScope(model.ScopeFollowsWrite))
+					r.Post("/me/blocks", h.blockUser)
+					r.Delete("/me/blocks/{id}", h.unblockUser)
+					r.Post("/me/mutes", h.muteUser)
+					r.Delete("/me/mutes/{id}", h.unmuteUser)
+					r.Post("/me/follow-requests/{id}", h.approveFollowRequest)
+					r.Delete("/me/follow-requests/{id}", h.denyFollowRequest)
+				})
+
+				r.Group(func(r chi.Router) {
+					r.Use(RequireScope(model.ScopeProfileWrite))
+					r.Put("/me", h.updateProfile)
+					r.Post("/me/avatar", h.uploadAvatar)
+				})
+
+				// Управление аккаунтом — только из сессии, не персональным токеном
+				r.Group(func(r chi.Router) {
+					r.Use(RequireSession)
+					r.Put("/me/password", h.changePassword)
+					r.Get("/me/mfa", h.getMFAStatus)
+					r.Post("/me/mfa/totp", h.enrollTOTP)
+					r.Post("/me/mfa/totp/confirm", h.confirmTOTP)
+					r.Delete("/me/mfa/totp", h.disableTOTP)
+					r.Post("/me/mfa/recovery-codes", h.regenerateRecoveryCodes)
+					r.Get("/me/tokens", h.getAccessTokens)
+					r.Post("/me/tokens", h.createAccessToken)
+					r.Delete("/me/tokens/{id}", h.revokeAccessToken)
+					r.Get("/me/identities", h.getIdentities)
+					r.Post("/me/identities/{provider}", h.linkIdentity)
+					r.Delete("/me/identities/{provider}", h.unlinkIdentity)
+				})
 			})
 
-			// Публичные по ID
-			r.Get("/{id}/followers", h.getFollowers)
-			r.Get("/{id}/following", h.getFollowing)
-
+			// Публичные по ID (с опциональной авторизацией — заблокированные не видны)
 			r.Group(func(r chi.Router) {
 				r.Use(h.OptionalAuthMiddleware)
+				r.Get("/{id}/followers", h.getFollowers)
+				r.Get("/{id}/following", h.getFollowing)
 				r.Get("/{id}", h.getUser)
 				r.Get("/{id}/posts", h.getUserPosts)
 			})
@@ -72,27 +123,107 @@ func (h *Handler) Routes() http.Handler {
 			// Защищённые по ID
 			r.Group(func(r chi.Router) {
 				r.Use(h.AuthMiddleware)
+				r.Use(RequireScope(model.ScopeFollowsWrite))
 				r.Post("/{id}/follow", h.followUser)
 				r.Delete("/{id}/follow", h.unfollowUser)
 			})
 		})
 
+		// Модерация и администрирование — только из сессии, роль из access-токена
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(h.AuthMiddleware)
+			r.Use(RequireSession)
+			r.Use(RequireRole(model.RoleModerator))
+
+			r.Get("/users", h.adminFindUser)
+			r.Get("/users/{id}", h.adminGetUser)
+			r.Post("/users/{id}/suspend", h.adminSuspendUser)
+			r.Delete("/users/{id}/suspend", h.adminUnsuspendUser)
+			r.Delete("/posts/{id}", h.adminDeletePost)
+			r.With(RequireRole(model.RoleAdmin)).Put("/users/{id}/role", h.adminSetRole)
+		})
+
+		// Ответы на комментарии (с опциональной авторизацией — скрытые видны участникам)
+		r.With(h.OptionalAuthMiddleware).Get("/comments/{id}/replies", h.getCommentReplies)
+
+		// Уведомления
+		r.Route("/notifications", func(r chi.Router) {
+			r.Use(h.AuthMiddleware)
+			r.With(RequireScope(model.ScopeProfileRead)).Get("/", h.getNotifications)
+			r.With(RequireScope(model.ScopeProfileRead)).Get("/unread-count", h.getUnreadNotificationsCount)
+			r.With(RequireScope(model.ScopeProfileWrite)).Post("/read", h.markAllNotificationsRead)
+			r.With(RequireScope(model.ScopeProfileWrite)).Post("/{id}/read", h.markNotificationRead)
+		})
+
+		// Личные сообщения
+		r.Route("/conversations", func(r chi.Router) {
+			r.Use(h.AuthMiddleware)
+
+			r.Group(func(r chi.Router) {
+				r.Use(RequireScope(model.ScopeMessagesRead))
+				r.Get("/", h.getConversations)
+				r.Get("/unread-count", h.getUnreadConversationsCount)
+				r.Get("/{id}", h.getConversation)
+				r.Get("/{id}/messages", h.getMessages)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(RequireScope(model.ScopeMessagesWrite))
+				r.Post("/", h.createConversation)
+				r.Delete("/{id}", h.leaveConversation)
+				r.Post("/{id}/messages", h.sendMessage)
+				r.Post("/{id}/read", h.markConversationRead)
+				r.Post("/{id}/mute", h.muteConversation)
+				r.Delete("/{id}/mute", h.unmuteConversation)
+				r.Post("/{id}/archive", h.archiveConversation)
+				r.Delete("/{id}/archive", h.unarchiveConversation)
+			})
+		})
+
+		// Поток событий в реальном времени: уведомления, новые посты подписок, счётчики постов
+		r.With(h.AuthMiddleware, RequireScope(model.ScopeProfileRead, model.ScopePostsRead)).Get("/stream", h.stream)
+
+		// Поиск (с опциональной авторизацией — для is_liked в найденных постах)
+		r.With(h.OptionalAuthMiddleware).Get("/search", h.search)
+
+		// Хештеги
+		r.Route("/hashtags", func(r chi.Router) {
+			r.Use(h.OptionalAuthMiddleware)
+			r.Get("/trending", h.getTrendingHashtags)
+			r.Get("/{tag}/posts", h.getHashtagPosts)
+		})
+
 		// Посты
 		r.Route("/posts", func(r chi.Router) {
 			// Публичные (с опциональной авторизацией)
 			r.Group(func(r chi.Router) {
 				r.Use(h.OptionalAuthMiddleware)
+				r.Get("/{id}", h.getPost)
 				r.Get("/{id}/comments", h.getComments)
+				r.Get("/{id}/revisions", h.getPostRevisions)
 			})
 
 			// Защищённые
 			r.Group(func(r chi.Router) {
 				r.Use(h.AuthMiddleware)
-				r.Post("/", h.createPost)
+				r.Use(RequireScope(model.ScopePostsWrite))
 				r.Delete("/{id}", h.deletePost)
-				r.Post("/{id}/comments", h.createComment)
 				r.Post("/{id}/like", h.likePost)
 				r.Delete("/{id}/like", h.unlikePost)
+				r.Post("/{id}/repost", h.repost)
+				r.Delete("/{id}/repost", h.unrepost)
+				r.Delete("/{id}/comments/{commentID}", h.deleteComment)
+				r.Post("/{id}/comments/{commentID}/hide", h.hideComment)
+				r.Delete("/{id}/comments/{commentID}/hide", h.unhideComment)
+
+				// Публикация — только с подтверждённым email, если этого требует политика
+				r.Group(func(r chi.Router) {
+					r.Use(h.RequireVerifiedEmail)
+					r.Post("/", h.createPost)
+					r.Patch("/{id}", h.updatePost)
+					r.Post("/{id}/comments", h.createComment)
+					r.Patch("/{id}/comments/{commentID}", h.updateComment)
+				})
 			})
 		})
 	})
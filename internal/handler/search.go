@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"social-network/internal/model"
+	"social-network/internal/service"
+)
+
+// search обрабатывает GET /v1/search?q=&type=posts|users&limit=&cursor=
+func (h *Handler) search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	req := model.SearchRequest{Query: q.Get("q")}
+	req.Limit, _ = strconv.Atoi(q.Get("limit"))
+	if c := q.Get("cursor"); c != "" {
+		after, err := model.ParseRankCursor(c)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, "неверный курсор")
+			return
+		}
+		req.After = after
+	}
+
+	switch q.Get("type") {
+	case "", model.SearchPosts:
+		page, err := h.searchService.SearchPosts(req, getUserID(r))
+		if err != nil {
+			searchError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	case model.SearchUsers:
+		page, err := h.searchService.SearchUsers(req, getUserID(r))
+		if err != nil {
+			searchError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	default:
+		jsonError(w, http.StatusBadRequest, service.ErrUnknownSearchType.Error())
+	}
+}
+
+// searchError переводит ошибку поиска в HTTP-ответ
+func searchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmptySearchQuery), errors.Is(err, service.ErrSearchQueryTooLong):
+		jsonError(w, http.StatusBadRequest, err.Error())
+	default:
+		jsonError(w, http.StatusInternalServerError, "ошибка поиска")
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"social-network/internal/service"
+)
+
+// getSessions обрабатывает GET /v1/auth/sessions
+func (h *Handler) getSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.authService.ListSessions(getUserID(r), getSessionID(r))
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка получения сессий")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+// revokeSession обрабатывает DELETE /v1/auth/sessions/{id}
+func (h *Handler) revokeSession(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+
+	if err := h.authService.RevokeSession(getUserID(r), sessionID); err != nil {
+		if err == service.ErrSessionNotFound {
+			jsonError(w, http.StatusNotFound, "сессия не найдена")
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "ошибка завершения сессии")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "сессия завершена"})
+}
+
+// revokeOtherSessions обрабатывает DELETE /v1/auth/sessions — выход на всех остальных устройствах
+func (h *Handler) revokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	if err := h.authService.RevokeOtherSessions(getUserID(r), getSessionID(r)); err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка завершения сессий")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "остальные сессии завершены"})
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"social-network/internal/realtime"
+	"social-network/internal/service"
+)
+
+// streamPingInterval — как часто слать комментарий, чтобы прокси не закрывали соединение
+const streamPingInterval = 25 * time.Second
+
+// stream обрабатывает GET /v1/stream?posts=1,2,3 — поток Server-Sent Events.
+// posts — посты, счётчики которых клиент сейчас показывает. Поток закрывается, когда
+// истекает токен: клиент обновляет токен и переподключается, так что отозванная сессия
+// не держит поток дольше срока access-токена. Токен перепроверяется на каждом ping —
+// так поток отозванного персонального токена тоже закрывается
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request) {
+	postIDs, err := parseIDList(r.URL.Query().Get("posts"))
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный список постов")
+		return
+	}
+
+	userID := getUserID(r)
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	sub, err := h.streamService.Subscribe(userID, postIDs)
+	if errors.Is(err, service.ErrTooManyStreamPosts) {
+		jsonError(w, http.StatusBadRequest, fmt.Sprintf("можно отслеживать не больше %d постов", service.MaxStreamPosts))
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка подписки на события")
+		return
+	}
+	defer sub.Close()
+
+	// Поток бессрочный: снимаем таймаут записи сервера, если он задан
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	// У бессрочных персональных токенов канал остаётся nil и никогда не срабатывает
+	var expired <-chan time.Time
+	if claims := getClaims(r); claims != nil && !claims.ExpiresAt.IsZero() {
+		timer := time.NewTimer(time.Until(claims.ExpiresAt))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-expired:
+			return
+		case <-ping.C:
+			if _, err := h.authService.ParseToken(token); err != nil {
+				return
+			}
+			fmt.Fprint(w, ": ping\n\n")
+		case event, ok := <-sub.Events():
+			// Канал закрыт — сервер останавливается
+			if !ok {
+				return
+			}
+			h.streamService.Track(userID, sub, event)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, event.Data)
+			// Заблокированному пользователю сообщаем причину и закрываем поток
+			if event.Type == realtime.EventSuspended {
+				rc.Flush()
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// parseIDList разбирает список ID через запятую; пустая строка — пустой список
+func parseIDList(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var ids []int
+	for _, part := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id <= 0 {
+			return nil, errors.New("неверный ID")
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -8,9 +8,11 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-// updateProfileRequest — тело запроса обновления профиля
+// updateProfileRequest — тело запроса обновления профиля; отсутствующие поля не меняются
 type updateProfileRequest struct {
-	Bio string `json:"bio"`
+	Bio                   *string `json:"bio"`
+	MessagesFollowingOnly *bool   `json:"messages_following_only"`
+	IsPrivate             *bool   `json:"is_private"`
 }
 
 // getMe обрабатывает GET /v1/users/me
@@ -24,6 +26,23 @@ func (h *Handler) getMe(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, user)
 }
 
+// getMyMentions обрабатывает GET /v1/users/me/mentions
+func (h *Handler) getMyMentions(w http.ResponseWriter, r *http.Request) {
+	page, err := readPage(r)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный курсор")
+		return
+	}
+
+	mentions, err := h.userService.GetMentions(getUserID(r), page)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "ошибка получения упоминаний")
+		return
+	}
+
+	writePage(w, r, mentions)
+}
+
 // getUser обрабатывает GET /v1/users/{id}
 func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
@@ -55,9 +74,24 @@ func (h *Handler) updateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userID := getUserID(r)
-	if err := h.userService.UpdateBio(userID, req.Bio); err != nil {
-		jsonError(w, http.StatusInternalServerError, "ошибка обновления профиля")
-		return
+	if req.Bio != nil {
+		if err := h.userService.UpdateBio(userID, *req.Bio); err != nil {
+			jsonError(w, http.StatusInternalServerError, "ошибка обновления профиля")
+			return
+		}
+	}
+	if req.MessagesFollowingOnly != nil {
+		if err := h.userService.SetMessagesFollowingOnly(userID, *req.MessagesFollowingOnly); err != nil {
+			jsonError(w, http.StatusInternalServerError, "ошибка обновления профиля")
+			return
+		}
+	}
+	// Закрытость меняет правила подписки: при открытии аккаунта ожидающие запросы одобряются
+	if req.IsPrivate != nil {
+		if err := h.followService.SetPrivate(userID, *req.IsPrivate); err != nil {
+			jsonError(w, http.StatusInternalServerError, "ошибка обновления профиля")
+			return
+		}
 	}
 
 	// Возвращаем обновлённого пользователя
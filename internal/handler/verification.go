@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"social-network/internal/service"
+)
+
+// verifyEmailRequest — тело запроса подтверждения email
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// verifyEmail обрабатывает POST /v1/auth/verify-email
+func (h *Handler) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailRequest
+	if err := readJSON(r, &req); err != nil {
+		jsonError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	if req.Token == "" {
+		jsonError(w, http.StatusBadRequest, "токен обязателен")
+		return
+	}
+
+	if err := h.authService.VerifyEmail(req.Token); err != nil {
+		if err == service.ErrInvalidToken {
+			jsonError(w, http.StatusBadRequest, "ссылка недействительна или устарела")
+			return
+		}
+		jsonError(w, http.StatusInternalServerError, "ошибка подтверждения email")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "email подтверждён"})
+}
+
+// resendVerification обрабатывает POST /v1/auth/verify-email/resend
+func (h *Handler) resendVerification(w http.ResponseWriter, r *http.Request) {
+	if err := h.authService.ResendVerification(getUserID(r)); err != nil {
+		if err == service.ErrEmailAlreadyVerified {
+			jsonError(w, http.StatusConflict, "email уже подтверждён")
+			return
+		}
+		log.Printf("Ошибка повторной отправки подтверждения: %v", err)
+		jsonError(w, http.StatusInternalServerError, "ошибка отправки письма")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "письмо отправлено"})
+}
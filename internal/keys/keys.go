@@ -0,0 +1,360 @@
+// Package keys управляет ключами подписи JWT: загрузка из директории,
+// плановая ротация и публикация открытых ключей в формате JWKS.
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Поддерживаемые алгоритмы подписи
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+	AlgHS256 = "HS256"
+)
+
+// kidTimeLayout — формат времени создания в начале kid. При разборе допускаются
+// доли секунды: их пишет kidFormat, чтобы ключи одной секунды различались по времени
+const (
+	kidTimeLayout = "20060102T150405"
+	kidFormat     = "20060102T150405.000000000"
+)
+
+// JWKSMaxAge — сколько клиенты кэшируют JWKS. Новый ключ должен пробыть
+// опубликованным не меньше этого срока, прежде чем начнёт подписывать токены
+const JWKSMaxAge = 5 * time.Minute
+
+var ErrUnknownKey = errors.New("неизвестный ключ подписи")
+
+// Key — ключ подписи JWT
+type Key struct {
+	ID        string // Попадает в заголовок kid
+	Method    jwt.SigningMethod
+	Private   any // *rsa.PrivateKey, ed25519.PrivateKey или []byte для HMAC
+	Public    any // Ключ проверки подписи
+	CreatedAt time.Time
+}
+
+// Manager хранит набор ключей: последний активированный используется для подписи,
+// остальные — только для проверки уже выданных токенов
+type Manager struct {
+	mu         sync.RWMutex
+	dir        string
+	alg        string
+	retention  time.Duration
+	activation time.Duration
+	keys       map[string]*Key
+	sorted     []*Key // keys от старых к новым
+}
+
+// NewHMACManager создаёт менеджер с одним общим секретом HS256 (режим без директории ключей)
+func NewHMACManager(secret string) *Manager {
+	key := &Key{
+		Method:  jwt.SigningMethodHS256,
+		Private: []byte(secret),
+		Public:  []byte(secret),
+	}
+	return &Manager{alg: AlgHS256, keys: map[string]*Key{"": key}, sorted: []*Key{key}}
+}
+
+// NewManager загружает ключи из dir; если там пусто — создаёт первый ключ алгоритма alg.
+// retention — сколько ключ остаётся доступным для проверки после того, как подписывать
+// начал более новый. activation — сколько новый ключ только публикуется в JWKS, прежде
+// чем начнёт подписывать (не меньше JWKSMaxAge, иначе кэши других сервисов его не знают)
+func NewManager(dir, alg string, retention, activation time.Duration) (*Manager, error) {
+	if alg != AlgRS256 && alg != AlgEdDSA {
+		return nil, fmt.Errorf("неподдерживаемый алгоритм подписи JWT: %s", alg)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию ключей: %w", err)
+	}
+
+	m := &Manager{dir: dir, alg: alg, retention: retention, activation: activation}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	if len(m.sorted) == 0 {
+		if err := m.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Sign подписывает claims текущим ключом и проставляет kid
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	m.mu.RLock()
+	key := m.signingKey(time.Now())
+	m.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.Private)
+}
+
+// Keyfunc возвращает ключ проверки по kid из заголовка токена (для jwt.Parse)
+func (m *Manager) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	m.mu.RLock()
+	key, ok := m.keys[kid]
+	m.mu.RUnlock()
+
+	if !ok || token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrUnknownKey
+	}
+	return key.Public, nil
+}
+
+// Methods возвращает алгоритмы, которые допустимы при проверке подписи
+func (m *Manager) Methods() []string {
+	return []string{m.alg}
+}
+
+// Reload перечитывает ключи из директории: так экземпляры с общей
+// директорией подхватывают ключи, созданные друг другом
+func (m *Manager) Reload() error {
+	if m.dir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать директорию ключей: %w", err)
+	}
+
+	loaded := make(map[string]*Key)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".pem") {
+			continue
+		}
+		key, err := loadKey(filepath.Join(m.dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if key.Method.Alg() != m.alg {
+			continue
+		}
+		loaded[key.ID] = key
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = loaded
+	m.sorted = sortedKeys(loaded)
+	return nil
+}
+
+// signingKey возвращает ключ подписи на момент now: самый новый из тех, что опубликованы
+// не меньше activation назад. Если таких нет (первый запуск) — самый старый из имеющихся,
+// он опубликован дольше всех. Вызывается под m.mu
+func (m *Manager) signingKey(now time.Time) *Key {
+	if len(m.sorted) == 0 {
+		return nil
+	}
+	for i := len(m.sorted) - 1; i >= 0; i-- {
+		if !now.Before(m.sorted[i].CreatedAt.Add(m.activation)) {
+			return m.sorted[i]
+		}
+	}
+	return m.sorted[0]
+}
+
+// Rotate создаёт и публикует новый ключ; подписывать он начнёт через activation
+func (m *Manager) Rotate() error {
+	if m.dir == "" {
+		return nil
+	}
+
+	var private crypto.Signer
+	switch m.alg {
+	case AlgRS256:
+		k, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return err
+		}
+		private = k
+	case AlgEdDSA:
+		_, k, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		private = k
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	kid := time.Now().UTC().Format(kidFormat) + "-" + hex.EncodeToString(suffix)
+
+	path := filepath.Join(m.dir, kid+".pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("не удалось сохранить ключ: %w", err)
+	}
+
+	log.Printf("Создан ключ подписи JWT %s (%s)", kid, m.alg)
+	return m.Reload()
+}
+
+// Run раз в interval перечитывает директорию, создаёт новый ключ, если самый новый
+// старше interval, и удаляет вытесненные ключи после срока retention
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	if m.dir == "" {
+		return
+	}
+
+	// Проверяем чаще, чем ротируем, чтобы не проспать ротацию после рестарта
+	ticker := time.NewTicker(min(interval, time.Hour))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.tick(interval); err != nil {
+				log.Printf("Ошибка ротации ключей JWT: %v", err)
+			}
+		}
+	}
+}
+
+// tick — один шаг плановой ротации
+func (m *Manager) tick(interval time.Duration) error {
+	if err := m.Reload(); err != nil {
+		return err
+	}
+
+	// Смотрим на самый новый ключ, а не на подписывающий: ожидающий активации
+	// ключ уже выпущен, и второй выпускать не нужно
+	m.mu.RLock()
+	latest := newest(m.sorted)
+	m.mu.RUnlock()
+
+	if latest == nil || time.Since(latest.CreatedAt) >= interval {
+		if err := m.Rotate(); err != nil {
+			return err
+		}
+	}
+	return m.prune()
+}
+
+// prune удаляет ключи, вытесненные более новым ключом раньше, чем retention назад.
+// Преемник вытесняет ключ, только когда сам начинает подписывать — через activation
+func (m *Manager) prune() error {
+	m.mu.RLock()
+	keys := m.sorted
+	m.mu.RUnlock()
+
+	removed := false
+	for i := 0; i < len(keys)-1; i++ {
+		successor := keys[i+1]
+		if time.Since(successor.CreatedAt) < m.activation+m.retention {
+			continue
+		}
+		err := os.Remove(filepath.Join(m.dir, keys[i].ID+".pem"))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		log.Printf("Удалён устаревший ключ подписи JWT %s", keys[i].ID)
+		removed = true
+	}
+
+	if removed {
+		return m.Reload()
+	}
+	return nil
+}
+
+// loadKey читает PKCS#8 приватный ключ из PEM-файла; kid — имя файла без расширения
+func loadKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("файл %s не содержит PEM", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось разобрать ключ %s: %w", path, err)
+	}
+
+	// Время создания зашито в kid; для ключей, положенных вручную, берём время файла
+	id := strings.TrimSuffix(filepath.Base(path), ".pem")
+	createdAt, err := time.Parse(kidTimeLayout, strings.SplitN(id, "-", 2)[0])
+	if err != nil {
+		createdAt = info.ModTime()
+	}
+
+	key := &Key{
+		ID:        id,
+		CreatedAt: createdAt,
+		Private:   parsed,
+	}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method = jwt.SigningMethodRS256
+		key.Public = &k.PublicKey
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.Public = k.Public()
+	default:
+		return nil, fmt.Errorf("неподдерживаемый тип ключа в %s", path)
+	}
+	return key, nil
+}
+
+// sortedKeys возвращает ключи от старых к новым
+func sortedKeys(keys map[string]*Key) []*Key {
+	list := make([]*Key, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, k)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].ID < list[j].ID
+		}
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// newest возвращает самый новый ключ из отсортированного списка или nil
+func newest(sorted []*Key) *Key {
+	if len(sorted) == 0 {
+		return nil
+	}
+	return sorted[len(sorted)-1]
+}
@@ -0,0 +1,13 @@
+package mailer
+
+// Message — письмо пользователю
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer — интерфейс отправки писем
+type Mailer interface {
+	Send(msg Message) error
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// Области доступа персональных токенов
+const (
+	ScopeProfileRead   = "profile:read"
+	ScopeProfileWrite  = "profile:write"
+	ScopePostsRead     = "posts:read"
+	ScopePostsWrite    = "posts:write"
+	ScopeFollowsRead   = "follows:read"
+	ScopeFollowsWrite  = "follows:write"
+	ScopeMessagesRead  = "messages:read"
+	ScopeMessagesWrite = "messages:write"
+)
+
+// Scopes — все допустимые области доступа
+var Scopes = []string{
+	ScopeProfileRead, ScopeProfileWrite,
+	ScopePostsRead, ScopePostsWrite,
+	ScopeFollowsRead, ScopeFollowsWrite,
+	ScopeMessagesRead, ScopeMessagesWrite,
+}
+
+// PersonalAccessToken — долгоживущий токен для ботов и скриптов
+type PersonalAccessToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"` // nil — бессрочный
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAccessToken — ответ на создание токена; Token показывается только один раз
+type CreatedAccessToken struct {
+	*PersonalAccessToken
+	Token string `json:"token"`
+}
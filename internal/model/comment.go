@@ -4,11 +4,18 @@ import "time"
 
 // Comment — модель комментария
 type Comment struct {
-	ID        int       `json:"id"`
-	PostID    int       `json:"post_id"`
-	UserID    int       `json:"user_id"`
-	Username  string    `json:"username"`   // JOIN с users
-	AvatarURL string    `json:"avatar_url"` // JOIN с users
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           int        `json:"id"`
+	PostID       int        `json:"post_id"`
+	UserID       int        `json:"user_id"`
+	ParentID     *int       `json:"parent_id"`          // nil — комментарий к самому посту
+	Depth        int        `json:"depth"`              // 0 — верхний уровень
+	Username     string     `json:"username"`           // JOIN с users
+	AvatarURL    string     `json:"avatar_url"`         // JOIN с users
+	Content      string     `json:"content"`            // Пусто у удалённых и скрытых от текущего пользователя
+	Mentions     []Mention  `json:"mentions,omitempty"` // Упомянутые пользователи
+	RepliesCount int        `json:"replies_count"`      // Прямые ответы
+	CreatedAt    time.Time  `json:"created_at"`
+	EditedAt     *time.Time `json:"edited_at"`  // nil — не редактировался
+	DeletedAt    *time.Time `json:"deleted_at"` // Не nil — «комментарий удалён», остаётся ради ответов
+	HiddenAt     *time.Time `json:"hidden_at"`  // Не nil — скрыт автором поста
 }
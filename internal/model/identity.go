@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// UserIdentity — внешний аккаунт провайдера OpenID Connect, привязанный к пользователю
+type UserIdentity struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OAuthState — незавершённый вход через провайдера
+type OAuthState struct {
+	StateHash    string
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	UserID       *int // Задан, если пользователь привязывает провайдера к своему аккаунту
+	ExpiresAt    time.Time
+}
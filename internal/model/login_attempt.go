@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// LoginAttempt — неудачные попытки входа для одного ключа (аккаунта или IP)
+type LoginAttempt struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time // До какого момента вход заблокирован
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Mention — @username в тексте поста или комментария, сопоставленный с пользователем.
+// Offset и Length считаются в символах (рунах) текста, Length включает @
+type Mention struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+}
+
+// MentionNotice — пост или комментарий, где упомянули пользователя
+type MentionNotice struct {
+	ID        int       `json:"id"`
+	PostID    int       `json:"post_id"`
+	CommentID *int      `json:"comment_id"` // nil — упоминание в самом посте
+	AuthorID  int       `json:"author_id"`
+	Username  string    `json:"username"`   // Автор, JOIN с users
+	AvatarURL string    `json:"avatar_url"` // Автор, JOIN с users
+	Content   string    `json:"content"`
+	Mentions  []Mention `json:"mentions"`
+	CreatedAt time.Time `json:"created_at"`
+}
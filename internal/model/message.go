@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// Conversation — диалог с точки зрения одного участника: личный (двое) или групповой
+type Conversation struct {
+	ID            int                        `json:"id"`
+	IsGroup       bool                       `json:"is_group"`
+	Title         string                     `json:"title"` // Только у групп
+	Participants  []*ConversationParticipant `json:"participants"`
+	LastMessage   *Message                   `json:"last_message"` // nil — сообщений ещё нет
+	UnreadCount   int                        `json:"unread_count"`
+	Muted         bool                       `json:"muted"`
+	Archived      bool                       `json:"archived"`
+	LastMessageAt time.Time                  `json:"last_message_at"` // По нему сортируется список диалогов
+	CreatedAt     time.Time                  `json:"created_at"`
+}
+
+// ConversationParticipant — участник диалога. LastReadID — до какого сообщения
+// он прочитал диалог: по нему клиент показывает отметки о прочтении
+type ConversationParticipant struct {
+	UserID     int    `json:"user_id"`
+	Username   string `json:"username"`
+	AvatarURL  string `json:"avatar_url"`
+	LastReadID int    `json:"last_read_id"`
+}
+
+// Message — сообщение в диалоге
+type Message struct {
+	ID             int       `json:"id"`
+	ConversationID int       `json:"conversation_id"`
+	UserID         int       `json:"user_id"`
+	Username       string    `json:"username"`   // JOIN с users
+	AvatarURL      string    `json:"avatar_url"` // JOIN с users
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// TOTPSettings — настройки TOTP пользователя
+type TOTPSettings struct {
+	UserID      int        `json:"user_id"`
+	Secret      string     `json:"-"`
+	ConfirmedAt *time.Time `json:"confirmed_at"` // nil — подключение не завершено
+	LastStep    int64      `json:"-"`            // Последний принятый шаг, защита от повтора кода
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TOTPEnrollment — данные для подключения приложения-аутентификатора
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// MFAStatus — состояние двухфакторной аутентификации
+type MFAStatus struct {
+	TOTPEnabled       bool `json:"totp_enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// LoginResult — результат входа: либо пара токенов, либо MFA-челлендж
+type LoginResult struct {
+	User        *User      `json:"user,omitempty"`
+	Tokens      *TokenPair `json:"tokens,omitempty"`
+	MFARequired bool       `json:"mfa_required,omitempty"`
+	MFAToken    string     `json:"mfa_token,omitempty"` // Короткоживущий токен для второго шага
+}
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// Типы уведомлений
+const (
+	NotificationLike    = "like"
+	NotificationComment = "comment" // Комментарий к посту получателя
+	NotificationReply   = "reply"   // Ответ на комментарий получателя
+	NotificationFollow  = "follow"
+	NotificationMention = "mention"
+
+	NotificationFollowRequest = "follow_request" // Запрос на подписку к закрытому аккаунту
+)
+
+// NotificationEvent — одно событие для получателя UserID. Непрочитанные события
+// с одинаковым GroupKey показываются одной группой
+type NotificationEvent struct {
+	UserID    int
+	ActorID   int
+	Type      string
+	PostID    *int
+	CommentID *int
+	GroupKey  string
+}
+
+// Notification — группа событий в списке уведомлений
+type Notification struct {
+	ID          int               `json:"id"` // ID группы — для отметки о прочтении
+	Type        string            `json:"type"`
+	PostID      *int              `json:"post_id"`
+	CommentID   *int              `json:"comment_id"` // Последнего события группы
+	Actor       NotificationActor `json:"actor"`      // Последний, кто совершил действие
+	ActorsCount int               `json:"actors_count"`
+	Summary     string            `json:"summary"` // «alice и ещё 5 оценили ваш пост»
+	Read        bool              `json:"read"`
+	CreatedAt   time.Time         `json:"created_at"` // Время последнего события
+}
+
+// NotificationActor — автор действия в уведомлении
+type NotificationActor struct {
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+}
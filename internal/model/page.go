@@ -0,0 +1,58 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cursorTimeLayout — время в курсоре без часового пояса: колонки created_at хранятся как TIMESTAMP
+const cursorTimeLayout = "2006-01-02T15:04:05.999999"
+
+// Cursor — позиция в списке, упорядоченном по (created_at, id)
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// Encode упаковывает курсор в непрозрачную для клиента строку
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.Format(cursorTimeLayout) + "," + strconv.Itoa(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor разбирает строку, полученную из Encode
+func ParseCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("неверный курсор")
+	}
+	ts, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return nil, errors.New("неверный курсор")
+	}
+	createdAt, err := time.Parse(cursorTimeLayout, ts)
+	if err != nil {
+		return nil, errors.New("неверный курсор")
+	}
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("неверный курсор")
+	}
+	return &Cursor{CreatedAt: createdAt, ID: n}, nil
+}
+
+// PageRequest — параметры запроса страницы списка
+type PageRequest struct {
+	Limit  int
+	After  *Cursor // nil — с начала списка
+	Offset int     // Устаревшая пагинация; работает, только если After не задан
+}
+
+// Page — страница списка. NextCursor пуст на последней странице
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
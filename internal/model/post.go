@@ -4,14 +4,63 @@ import "time"
 
 // Post — модель поста
 type Post struct {
-	ID         int       `json:"id"`
+	ID            int         `json:"id"`
+	UserID        int         `json:"user_id"`
+	Username      string      `json:"username"`   // JOIN с users
+	AvatarURL     string      `json:"avatar_url"` // JOIN с users
+	Content       string      `json:"content"`
+	Mentions      []Mention   `json:"mentions,omitempty"` // Упомянутые пользователи; в превью цитаты нет
+	ImageURL      string      `json:"image_url"`          // Изображение поста
+	LikesCount    int         `json:"likes_count"`        // Подсчёт лайков
+	CommentsCount int         `json:"comments_count"`     // Без удалённых, вместе с ответами
+	RepostsCount  int         `json:"reposts_count"`
+	IsLiked       bool        `json:"is_liked"`    // Лайкнул ли текущий пользователь
+	IsReposted    bool        `json:"is_reposted"` // Сделал ли текущий пользователь репост
+	IsQuote       bool        `json:"is_quote"`    // Цитата; Quote == nil — оригинал удалён
+	Quote         *Post       `json:"quote,omitempty"`
+	RepostedBy    *RepostInfo `json:"reposted_by,omitempty"` // Только в ленте подписок
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	EditedAt      *time.Time  `json:"edited_at"` // nil — пост не редактировался
+}
+
+// PostDraft — новый пост или правка, разобранные сервисом перед сохранением
+type PostDraft struct {
+	UserID   int
+	Content  string
+	ImageURL string
+	QuoteOf  *int      // ID цитируемого поста; при правке не меняется
+	Hashtags []string  // Теги из Content в нижнем регистре
+	Mentions []Mention // Упоминания существующих пользователей в Content
+}
+
+// TrendingTag — тег и его популярность за окно трендов
+type TrendingTag struct {
+	Tag          string `json:"tag"`
+	PostsCount   int    `json:"posts_count"`
+	AuthorsCount int    `json:"authors_count"` // Разные авторы — рейтинг устойчивее к накрутке
+}
+
+// RepostInfo — кто из подписок поделился постом
+type RepostInfo struct {
 	UserID     int       `json:"user_id"`
-	Username   string    `json:"username"`    // JOIN с users
-	AvatarURL  string    `json:"avatar_url"`  // JOIN с users
+	Username   string    `json:"username"`
+	RepostedAt time.Time `json:"reposted_at"`
+}
+
+// PostDetail — пост для отдельной страницы с раскрытыми по запросу связями
+type PostDetail struct {
+	*Post
+	Author   *UserProfile    `json:"author,omitempty"`   // ?expand=author
+	Comments *Page[*Comment] `json:"comments,omitempty"` // ?expand=comments — первая страница
+}
+
+// PostRevision — прежняя версия отредактированного поста
+type PostRevision struct {
+	ID         int       `json:"id"`
+	PostID     int       `json:"post_id"`
 	Content    string    `json:"content"`
-	ImageURL   string    `json:"image_url"`   // Изображение поста
-	LikesCount int       `json:"likes_count"` // Подсчёт лайков
-	IsLiked    bool      `json:"is_liked"`    // Лайкнул ли текущий пользователь
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ImageURL   string    `json:"image_url"`
+	CreatedAt  time.Time `json:"created_at"`  // Когда версия была опубликована
+	ReplacedAt time.Time `json:"replaced_at"` // Когда её заменила правка
 }
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Типы поиска
+const (
+	SearchPosts = "posts"
+	SearchUsers = "users"
+)
+
+// RankCursor — позиция в выдаче поиска, упорядоченной по (rank, id) по убыванию.
+// Ранг — float4 из PostgreSQL, поэтому хранится как float32 и переживает круг без потерь
+type RankCursor struct {
+	Rank float32
+	ID   int
+}
+
+// Encode упаковывает курсор в непрозрачную для клиента строку
+func (c RankCursor) Encode() string {
+	raw := strconv.FormatFloat(float64(c.Rank), 'g', -1, 32) + "," + strconv.Itoa(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseRankCursor разбирает строку, полученную из RankCursor.Encode
+func ParseRankCursor(s string) (*RankCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("неверный курсор")
+	}
+	rank, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return nil, errors.New("неверный курсор")
+	}
+	r, err := strconv.ParseFloat(rank, 32)
+	if err != nil {
+		return nil, errors.New("неверный курсор")
+	}
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errors.New("неверный курсор")
+	}
+	return &RankCursor{Rank: float32(r), ID: n}, nil
+}
+
+// SearchRequest — запрос поиска
+type SearchRequest struct {
+	Query string
+	Limit int
+	After *RankCursor // nil — с начала выдачи
+}
+
+// PostSearchResult — найденный пост. Snippet — фрагмент текста с <mark> вокруг совпадений
+type PostSearchResult struct {
+	*Post
+	Snippet string  `json:"snippet"`
+	Rank    float32 `json:"rank"`
+}
+
+// UserSearchResult — найденный пользователь; только публичные поля
+type UserSearchResult struct {
+	ID        int     `json:"id"`
+	Username  string  `json:"username"`
+	AvatarURL string  `json:"avatar_url"`
+	Bio       string  `json:"bio"`
+	Snippet   string  `json:"snippet"` // bio с <mark> вокруг совпавших слов
+	Rank      float32 `json:"rank"`
+}
@@ -0,0 +1,53 @@
+package model
+
+// Данные событий потока /v1/stream. События короткие: подробности клиент
+// запрашивает обычными эндпоинтами
+
+// NotificationNotice — событие notification: получателю пришло уведомление
+type NotificationNotice struct {
+	Type      string `json:"type"`
+	ActorID   int    `json:"actor_id"`
+	PostID    *int   `json:"post_id"`
+	CommentID *int   `json:"comment_id"`
+}
+
+// PostNotice — событие post.created: новый пост автора из подписок
+type PostNotice struct {
+	PostID   int    `json:"post_id"`
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// PostCounters — событие post.counters: текущие счётчики поста
+type PostCounters struct {
+	PostID        int `json:"post_id"`
+	LikesCount    int `json:"likes_count"`
+	CommentsCount int `json:"comments_count"`
+	RepostsCount  int `json:"reposts_count"`
+}
+
+// FollowingChange — событие following: получатель подписался на UserID или отписался
+type FollowingChange struct {
+	UserID    int  `json:"user_id"`
+	Following bool `json:"following"`
+}
+
+// MutingChange — событие muting: получатель скрыл UserID или вернул его в ленты
+type MutingChange struct {
+	UserID int  `json:"user_id"`
+	Muted  bool `json:"muted"`
+}
+
+// MessageNotice — событие message в потоке: в диалоге новое сообщение
+type MessageNotice struct {
+	ConversationID int `json:"conversation_id"`
+	MessageID      int `json:"message_id"`
+	UserID         int `json:"user_id"`
+}
+
+// ReadNotice — событие message.read в потоке: участник прочитал диалог до MessageID
+type ReadNotice struct {
+	ConversationID int `json:"conversation_id"`
+	UserID         int `json:"user_id"`
+	MessageID      int `json:"message_id"`
+}
@@ -10,9 +10,48 @@ type TokenPair struct {
 
 // RefreshToken — запись refresh-токена в БД
 type RefreshToken struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	TokenHash string    `json:"-"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
+	ID               int        `json:"id"`
+	UserID           int        `json:"user_id"`
+	FamilyID         string     `json:"family_id"` // Общий для всех токенов одного входа
+	TokenHash        string     `json:"-"`
+	UserAgent        string     `json:"user_agent"`
+	IPAddress        string     `json:"ip_address"`
+	SessionStartedAt time.Time  `json:"session_started_at"` // Когда был выполнен вход
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RotatedAt        *time.Time `json:"rotated_at"` // Когда токен был обменян на новый
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// ClientInfo — данные клиента, от которого пришёл запрос
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// Session — активная сессия пользователя (семейство refresh-токенов)
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	IsCurrent  bool      `json:"is_current"` // Сессия, из которой сделан запрос
+}
+
+// Назначения одноразовых токенов из писем
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+// UserToken — одноразовый токен действия, отправляемый по email
+type UserToken struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	Purpose   string     `json:"purpose"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
 }
@@ -4,14 +4,45 @@ import "time"
 
 // User — модель пользователя
 type User struct {
-	ID           int       `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"` // Никогда не отдаём в JSON
-	Bio          string    `json:"bio"`
-	AvatarURL    string    `json:"avatar_url"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              int        `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	PasswordHash    string     `json:"-"` // Никогда не отдаём в JSON
+	Bio             string     `json:"bio"`
+	AvatarURL       string     `json:"avatar_url"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"` // nil — email не подтверждён
+	Role            string     `json:"role"`
+	SuspendedAt     *time.Time `json:"suspended_at"` // Не nil — аккаунт заблокирован модератором
+
+	// MessagesFollowingOnly — писать в личные сообщения могут только те, на кого подписан пользователь
+	MessagesFollowingOnly bool `json:"messages_following_only"`
+	// IsPrivate — закрытый аккаунт: подписка по одобренному запросу, посты и списки
+	// подписок видны только подписчикам
+	IsPrivate bool `json:"is_private"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Роли пользователей: каждая следующая включает права предыдущей
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// roleRank — порядок ролей для сравнения прав
+var roleRank = map[string]int{RoleUser: 1, RoleModerator: 2, RoleAdmin: 3}
+
+// ValidRole проверяет, что роль существует
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// RoleAtLeast проверяет, что роль role не ниже required
+func RoleAtLeast(role, required string) bool {
+	return roleRank[role] >= roleRank[required] && ValidRole(required)
 }
 
 // UserProfile — публичный профиль с подсчётом подписчиков
@@ -19,5 +50,8 @@ type UserProfile struct {
 	User
 	FollowersCount int  `json:"followers_count"`
 	FollowingCount int  `json:"following_count"`
+	PostsCount     int  `json:"posts_count"`
 	IsFollowing    bool `json:"is_following"` // Подписан ли текущий пользователь
+	IsMuted        bool `json:"is_muted"`     // Скрыл ли его текущий пользователь из лент
+	IsRequested    bool `json:"is_requested"` // Ждёт ли одобрения запрос текущего пользователя на подписку
 }
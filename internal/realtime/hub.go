@@ -0,0 +1,64 @@
+// Package realtime — публикация событий клиентам, подключённым к потоку /v1/stream.
+// Хаб в памяти обслуживает один экземпляр приложения, хаб на LISTEN/NOTIFY
+// PostgreSQL раздаёт события всем экземплярам
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Типы событий
+const (
+	EventNotification = "notification"  // Новое уведомление получателю
+	EventPostCreated  = "post.created"  // Новый пост автора, на которого подписан получатель
+	EventPostCounters = "post.counters" // Изменились счётчики лайков, комментариев или репостов
+	EventFollowing    = "following"     // Получатель подписался или отписался
+	EventMuting       = "muting"        // Получатель скрыл пользователя или вернул его в ленты
+	EventMessage      = "message"       // Новое сообщение в диалоге получателя
+	EventMessageRead  = "message.read"  // Участник диалога прочитал сообщения
+	EventSuspended    = "suspended"     // Аккаунт получателя заблокирован; поток закрывается
+)
+
+// Event — событие потока. Data уже сериализован: один раз при публикации,
+// а не для каждого подписчика
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// NewEvent собирает событие с данными data
+func NewEvent(eventType string, data any) Event {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		raw = json.RawMessage("null")
+	}
+	return Event{Type: eventType, Data: raw}
+}
+
+// Publisher отправляет событие всем подписчикам темы
+type Publisher interface {
+	Publish(topic string, event Event)
+}
+
+// Hub — шина событий: публикация и подписка на темы
+type Hub interface {
+	Publisher
+	Subscribe(topics ...string) *Subscription
+	Close() error
+}
+
+// UserTopic — личные события пользователя: уведомления и изменения подписок
+func UserTopic(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// AuthorTopic — новые посты автора
+func AuthorTopic(userID int) string {
+	return fmt.Sprintf("author:%d", userID)
+}
+
+// PostTopic — изменения счётчиков поста
+func PostTopic(postID int) string {
+	return fmt.Sprintf("post:%d", postID)
+}
@@ -0,0 +1,153 @@
+package realtime
+
+import "sync"
+
+// subscriptionBuffer — сколько событий ждёт медленного клиента; лишние отбрасываются,
+// чтобы один клиент не задерживал публикацию для остальных
+const subscriptionBuffer = 64
+
+// Subscription — подписка одного клиента на набор тем
+type Subscription struct {
+	hub    *MemoryHub
+	events chan Event
+	topics map[string]bool // Под hub.mu
+	closed bool            // Под hub.mu
+}
+
+// Events возвращает канал событий; он закрывается при Close подписки или хаба
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Add подписывает на темы
+func (s *Subscription) Add(topics ...string) {
+	s.hub.add(s, topics)
+}
+
+// Remove отписывает от тем
+func (s *Subscription) Remove(topics ...string) {
+	s.hub.remove(s, topics)
+}
+
+// Close отменяет подписку
+func (s *Subscription) Close() {
+	s.hub.unsubscribe(s)
+}
+
+// MemoryHub — шина событий в памяти одного процесса
+type MemoryHub struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription]bool
+	subs   map[*Subscription]bool
+	closed bool
+}
+
+// NewMemoryHub создаёт шину событий в памяти
+func NewMemoryHub() *MemoryHub {
+	return &MemoryHub{
+		topics: make(map[string]map[*Subscription]bool),
+		subs:   make(map[*Subscription]bool),
+	}
+}
+
+// Publish отправляет событие подписчикам темы, не дожидаясь их
+func (h *MemoryHub) Publish(topic string, event Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.topics[topic] {
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe создаёт подписку на темы. После Close хаба подписка сразу закрыта
+func (h *MemoryHub) Subscribe(topics ...string) *Subscription {
+	sub := &Subscription{
+		hub:    h,
+		events: make(chan Event, subscriptionBuffer),
+		topics: make(map[string]bool),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		sub.closed = true
+		close(sub.events)
+		return sub
+	}
+	h.subs[sub] = true
+	h.addLocked(sub, topics)
+	return sub
+}
+
+// Close закрывает все подписки
+func (h *MemoryHub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		sub.closed = true
+		close(sub.events)
+	}
+	h.topics = make(map[string]map[*Subscription]bool)
+	h.subs = make(map[*Subscription]bool)
+	return nil
+}
+
+func (h *MemoryHub) add(sub *Subscription, topics []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !sub.closed {
+		h.addLocked(sub, topics)
+	}
+}
+
+func (h *MemoryHub) addLocked(sub *Subscription, topics []string) {
+	for _, topic := range topics {
+		if h.topics[topic] == nil {
+			h.topics[topic] = make(map[*Subscription]bool)
+		}
+		h.topics[topic][sub] = true
+		sub.topics[topic] = true
+	}
+}
+
+func (h *MemoryHub) remove(sub *Subscription, topics []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.removeLocked(sub, topics)
+}
+
+func (h *MemoryHub) removeLocked(sub *Subscription, topics []string) {
+	for _, topic := range topics {
+		delete(h.topics[topic], sub)
+		if len(h.topics[topic]) == 0 {
+			delete(h.topics, topic)
+		}
+		delete(sub.topics, topic)
+	}
+}
+
+func (h *MemoryHub) unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if sub.closed {
+		return
+	}
+	topics := make([]string, 0, len(sub.topics))
+	for topic := range sub.topics {
+		topics = append(topics, topic)
+	}
+	h.removeLocked(sub, topics)
+	delete(h.subs, sub)
+	sub.closed = true
+	close(sub.events)
+}
@@ -0,0 +1,101 @@
+package realtime
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// notifyChannel — канал LISTEN/NOTIFY, общий для всех экземпляров приложения
+const notifyChannel = "realtime_events"
+
+// listenerPingInterval — как часто проверять соединение слушателя
+const listenerPingInterval = 90 * time.Second
+
+// envelope — событие вместе с темой в payload NOTIFY
+type envelope struct {
+	Topic string `json:"topic"`
+	Event Event  `json:"event"`
+}
+
+// PostgresHub — шина событий через LISTEN/NOTIFY: событие, опубликованное любым
+// экземпляром, приходит каждому экземпляру и раздаётся его локальным подписчикам.
+// Payload NOTIFY ограничен 8000 байтами, поэтому события должны быть короткими
+type PostgresHub struct {
+	db       *sql.DB
+	listener *pq.Listener
+	local    *MemoryHub
+}
+
+// NewPostgresHub подключает слушателя канала событий. dsn — строка подключения
+// для отдельного соединения слушателя
+func NewPostgresHub(db *sql.DB, dsn string) (*PostgresHub, error) {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(_ pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Слушатель событий PostgreSQL: %v", err)
+		}
+	})
+	if err := listener.Listen(notifyChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	h := &PostgresHub{db: db, listener: listener, local: NewMemoryHub()}
+	go h.run()
+	return h, nil
+}
+
+// Publish отправляет событие через NOTIFY; локальные подписчики получат его
+// вместе с остальными экземплярами
+func (h *PostgresHub) Publish(topic string, event Event) {
+	payload, err := json.Marshal(envelope{Topic: topic, Event: event})
+	if err != nil {
+		log.Printf("Не удалось сериализовать событие %s: %v", event.Type, err)
+		return
+	}
+	if _, err := h.db.Exec(`SELECT pg_notify($1, $2)`, notifyChannel, string(payload)); err != nil {
+		log.Printf("Не удалось опубликовать событие %s в %s: %v", event.Type, topic, err)
+	}
+}
+
+// Subscribe создаёт подписку на темы
+func (h *PostgresHub) Subscribe(topics ...string) *Subscription {
+	return h.local.Subscribe(topics...)
+}
+
+// Close останавливает слушателя и закрывает все подписки
+func (h *PostgresHub) Close() error {
+	err := h.listener.Close()
+	h.local.Close()
+	return err
+}
+
+// run раздаёт полученные уведомления локальным подписчикам до закрытия слушателя
+func (h *PostgresHub) run() {
+	ping := time.NewTicker(listenerPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case n, ok := <-h.listener.Notify:
+			if !ok {
+				return
+			}
+			// nil — соединение восстановлено; события за время обрыва потеряны
+			if n == nil {
+				continue
+			}
+			var env envelope
+			if err := json.Unmarshal([]byte(n.Extra), &env); err != nil {
+				log.Printf("Некорректное событие в канале %s: %v", notifyChannel, err)
+				continue
+			}
+			h.local.Publish(env.Topic, env.Event)
+		case <-ping.C:
+			go h.listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"social-network/internal/model"
+)
+
+// accessTokenRepo — реализация AccessTokenRepository для PostgreSQL
+type accessTokenRepo struct {
+	db *sql.DB
+}
+
+// NewAccessTokenRepo создаёт новый репозиторий персональных токенов
+func NewAccessTokenRepo(db *sql.DB) AccessTokenRepository {
+	return &accessTokenRepo{db: db}
+}
+
+func (r *accessTokenRepo) Create(token *model.PersonalAccessToken) error {
+	return r.db.QueryRow(
+		`INSERT INTO personal_access_tokens (user_id, name, token_hash, scopes, expires_at)
+		 VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		token.UserID, token.Name, token.TokenHash, pq.Array(token.Scopes), token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+// GetByHash находит токен; токены заблокированных пользователей не находятся
+func (r *accessTokenRepo) GetByHash(tokenHash string) (*model.PersonalAccessToken, error) {
+	token := &model.PersonalAccessToken{}
+	err := r.db.QueryRow(
+		`SELECT t.id, t.user_id, t.name, t.token_hash, t.scopes, t.expires_at, t.last_used_at, t.created_at
+		 FROM personal_access_tokens t
+		 JOIN users u ON u.id = t.user_id
+		 WHERE t.token_hash = $1 AND u.suspended_at IS NULL`, tokenHash,
+	).Scan(&token.ID, &token.UserID, &token.Name, &token.TokenHash, pq.Array(&token.Scopes),
+		&token.ExpiresAt, &token.LastUsedAt, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *accessTokenRepo) GetByUserID(userID int) ([]*model.PersonalAccessToken, error) {
+	rows, err := r.db.Query(
+		`SELECT id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at
+		 FROM personal_access_tokens WHERE user_id = $1
+		 ORDER BY created_at DESC, id DESC`, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*model.PersonalAccessToken{}
+	for rows.Next() {
+		token := &model.PersonalAccessToken{}
+		if err := rows.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenHash, pq.Array(&token.Scopes),
+			&token.ExpiresAt, &token.LastUsedAt, &token.CreatedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (r *accessTokenRepo) TouchLastUsed(id int, usedAt time.Time) error {
+	_, err := r.db.Exec(`UPDATE personal_access_tokens SET last_used_at = $1 WHERE id = $2`, usedAt.UTC(), id)
+	return err
+}
+
+// Delete удаляет токен пользователя. Возвращает false, если такого токена у него нет
+func (r *accessTokenRepo) Delete(id, userID int) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
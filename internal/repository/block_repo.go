@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"social-network/internal/model"
+)
+
+// notBlocked — условие: между пользователем из колонки userCol и зрителем viewer
+// (плейсхолдер, например "$2") нет блокировки ни в одну сторону. Для гостя (0) всегда истинно
+func notBlocked(userCol, viewer string) string {
+	return `NOT EXISTS (SELECT 1 FROM user_blocks ub
+			WHERE (ub.blocker_id = ` + viewer + ` AND ub.blocked_id = ` + userCol + `)
+			   OR (ub.blocker_id = ` + userCol + ` AND ub.blocked_id = ` + viewer + `))`
+}
+
+// blockRepo — реализация BlockRepository для PostgreSQL
+type blockRepo struct {
+	db *sql.DB
+}
+
+// NewBlockRepo создаёт новый репозиторий блокировок
+func NewBlockRepo(db *sql.DB) BlockRepository {
+	return &blockRepo{db: db}
+}
+
+// Block блокирует пользователя и удаляет подписки и запросы на подписку в обе стороны
+func (r *blockRepo) Block(blockerID, blockedID int) error {
+	return inTx(r.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			`INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)
+			 ON CONFLICT DO NOTHING`, blockerID, blockedID,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			`DELETE FROM follow_requests
+			 WHERE (follower_id = $1 AND following_id = $2) OR (follower_id = $2 AND following_id = $1)`,
+			blockerID, blockedID,
+		)
+		if err != nil {
+			return err
+		}
+		for _, pair := range [][2]int{{blockerID, blockedID}, {blockedID, blockerID}} {
+			res, err := tx.Exec(
+				`DELETE FROM follows WHERE follower_id = $1 AND following_id = $2`, pair[0], pair[1],
+			)
+			if err != nil {
+				return err
+			}
+			if err := adjustFollows(tx, res, pair[0], pair[1], -1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *blockRepo) Unblock(blockerID, blockedID int) error {
+	_, err := r.db.Exec(
+		`DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2`, blockerID, blockedID,
+	)
+	return err
+}
+
+// GetBlocked возвращает заблокированных пользователем, последних первыми
+func (r *blockRepo) GetBlocked(userID int, page model.PageRequest) (*model.Page[*model.User], error) {
+	afterAt, afterID := afterArgs(page.After)
+	rows, err := r.db.Query(
+		`SELECT `+listedUserColumns+`, b.created_at
+		 FROM users u
+		 JOIN user_blocks b ON u.id = b.blocked_id
+		 WHERE b.blocker_id = $1
+		   AND ($2::timestamp IS NULL OR (b.created_at, u.id) < ($2::timestamp, $3::int))
+		 ORDER BY b.created_at DESC, u.id DESC
+		 LIMIT $4 OFFSET $5`, userID, afterAt, afterID, page.Limit+1, page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pageOfListedUsers(rows, page.Limit)
+}
+
+// IsBlocked проверяет, есть ли блокировка между пользователями в любую сторону
+func (r *blockRepo) IsBlocked(userID, otherID int) (bool, error) {
+	var blocked bool
+	err := r.db.QueryRow(`SELECT NOT `+notBlocked("$2", "$1"), userID, otherID).Scan(&blocked)
+	return blocked, err
+}
+
+func (r *blockRepo) AnyBlocked(userIDs []int) (bool, error) {
+	var blocked bool
+	err := r.db.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM user_blocks
+		 WHERE blocker_id = ANY($1::int[]) AND blocked_id = ANY($1::int[]))`, pq.Array(userIDs),
+	).Scan(&blocked)
+	return blocked, err
+}
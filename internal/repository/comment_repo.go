@@ -6,6 +6,14 @@ import (
 	"social-network/internal/model"
 )
 
+// selectComments — общий SELECT комментариев с автором, упоминаниями и числом ответов в порядке scanComment
+var selectComments = `SELECT c.id, c.post_id, c.user_id, c.parent_id, c.depth, u.username, u.avatar_url, c.content,
+		` + commentMentions + `,
+		(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id) as replies_count,
+		c.created_at, c.edited_at, c.deleted_at, c.hidden_at
+	 FROM comments c
+	 JOIN users u ON c.user_id = u.id`
+
 // commentRepo — реализация CommentRepository для PostgreSQL
 type commentRepo struct {
 	db *sql.DB
@@ -16,49 +24,149 @@ func NewCommentRepo(db *sql.DB) CommentRepository {
 	return &commentRepo{db: db}
 }
 
-func (r *commentRepo) Create(postID, userID int, content string) (*model.Comment, error) {
-	comment := &model.Comment{}
-	err := r.db.QueryRow(
-		`INSERT INTO comments (post_id, user_id, content)
-		 VALUES ($1, $2, $3)
-		 RETURNING id, post_id, user_id, content, created_at`,
-		postID, userID, content,
-	).Scan(&comment.ID, &comment.PostID, &comment.UserID, &comment.Content, &comment.CreatedAt)
+// Create добавляет комментарий с упоминаниями; глубина ответа — на единицу больше родителя
+func (r *commentRepo) Create(postID, userID int, parentID *int, content string, mentions []model.Mention) (*model.Comment, error) {
+	var id int
+	err := inTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(
+			`INSERT INTO comments (post_id, user_id, parent_id, depth, content)
+			 VALUES ($1, $2, $3, COALESCE((SELECT depth + 1 FROM comments WHERE id = $3), 0), $4)
+			 RETURNING id`,
+			postID, userID, parentID, content,
+		).Scan(&id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE posts SET comments_count = comments_count + 1 WHERE id = $1`, postID); err != nil {
+			return err
+		}
+		return replaceMentions(tx, postID, &id, mentions)
+	})
 	if err != nil {
 		return nil, err
 	}
+	return r.GetByID(id, userID)
+}
+
+// GetByID возвращает комментарий; если между автором и viewerID блокировка — sql.ErrNoRows
+func (r *commentRepo) GetByID(id, viewerID int) (*model.Comment, error) {
+	return scanComment(r.db.QueryRow(
+		selectComments+` WHERE c.id = $1 AND `+notBlocked("c.user_id", "$2"), id, viewerID,
+	))
+}
+
+// Update заменяет текст и упоминания; удалённый комментарий не меняется
+func (r *commentRepo) Update(id int, content string, mentions []model.Mention) error {
+	return inTx(r.db, func(tx *sql.Tx) error {
+		var postID int
+		err := tx.QueryRow(
+			`UPDATE comments SET content = $1, edited_at = NOW() WHERE id = $2 AND deleted_at IS NULL
+			 RETURNING post_id`, content, id,
+		).Scan(&postID)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return replaceMentions(tx, postID, &id, mentions)
+	})
+}
 
-	// Подтягиваем имя пользователя
-	r.db.QueryRow(`SELECT username, avatar_url FROM users WHERE id = $1`, userID).
-		Scan(&comment.Username, &comment.AvatarURL)
+// SoftDelete стирает текст и оставляет «надгробие», чтобы ответы не потеряли родителя
+func (r *commentRepo) SoftDelete(id int) error {
+	return inTx(r.db, func(tx *sql.Tx) error {
+		var postID int
+		err := tx.QueryRow(
+			`UPDATE comments SET content = '', deleted_at = NOW(), hidden_at = NULL
+			 WHERE id = $1 AND deleted_at IS NULL
+			 RETURNING post_id`, id,
+		).Scan(&postID)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM mentions WHERE comment_id = $1`, id); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE posts SET comments_count = comments_count - 1 WHERE id = $1`, postID)
+		return err
+	})
+}
 
-	return comment, nil
+func (r *commentRepo) SetHidden(id int, hidden bool) error {
+	_, err := r.db.Exec(
+		`UPDATE comments SET hidden_at = CASE WHEN $2 THEN COALESCE(hidden_at, NOW()) END
+		 WHERE id = $1 AND deleted_at IS NULL`, id, hidden,
+	)
+	return err
 }
 
-func (r *commentRepo) GetByPostID(postID int) ([]*model.Comment, error) {
+// GetByPostID возвращает комментарии верхнего уровня от старых к новым; курсор — последний отданный.
+// Комментарии тех, с кем у viewerID блокировка, пропускаются
+func (r *commentRepo) GetByPostID(postID, viewerID int, page model.PageRequest) (*model.Page[*model.Comment], error) {
+	afterAt, afterID := afterArgs(page.After)
 	rows, err := r.db.Query(
-		`SELECT c.id, c.post_id, c.user_id, u.username, u.avatar_url, c.content, c.created_at
-		 FROM comments c
-		 JOIN users u ON c.user_id = u.id
-		 WHERE c.post_id = $1
-		 ORDER BY c.created_at ASC`, postID,
+		selectComments+`
+		 WHERE c.post_id = $1 AND c.parent_id IS NULL AND `+notBlocked("c.user_id", "$6")+`
+		   AND ($2::timestamp IS NULL OR (c.created_at, c.id) > ($2::timestamp, $3::int))
+		 ORDER BY c.created_at ASC, c.id ASC
+		 LIMIT $4 OFFSET $5`, postID, afterAt, afterID, page.Limit+1, page.Offset, viewerID,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var comments []*model.Comment
+	return pageOfComments(rows, page.Limit)
+}
+
+// GetReplies возвращает прямые ответы на комментарий от старых к новым, кроме ответов
+// тех, с кем у viewerID блокировка
+func (r *commentRepo) GetReplies(parentID, viewerID int, page model.PageRequest) (*model.Page[*model.Comment], error) {
+	afterAt, afterID := afterArgs(page.After)
+	rows, err := r.db.Query(
+		selectComments+`
+		 WHERE c.parent_id = $1 AND `+notBlocked("c.user_id", "$6")+`
+		   AND ($2::timestamp IS NULL OR (c.created_at, c.id) > ($2::timestamp, $3::int))
+		 ORDER BY c.created_at ASC, c.id ASC
+		 LIMIT $4 OFFSET $5`, parentID, afterAt, afterID, page.Limit+1, page.Offset, viewerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pageOfComments(rows, page.Limit)
+}
+
+// scanComment сканирует строку с колонками selectComments
+func scanComment(row rowScanner) (*model.Comment, error) {
+	c := &model.Comment{}
+	err := row.Scan(&c.ID, &c.PostID, &c.UserID, &c.ParentID, &c.Depth, &c.Username, &c.AvatarURL,
+		&c.Content, (*jsonMentions)(&c.Mentions), &c.RepliesCount, &c.CreatedAt, &c.EditedAt, &c.DeletedAt, &c.HiddenAt)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// pageOfComments сканирует выборку комментариев в страницу с курсором по (created_at, id)
+func pageOfComments(rows *sql.Rows, limit int) (*model.Page[*model.Comment], error) {
+	comments := []*model.Comment{}
 	for rows.Next() {
-		c := &model.Comment{}
-		err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.Username, &c.AvatarURL, &c.Content, &c.CreatedAt)
+		c, err := scanComment(rows)
 		if err != nil {
 			return nil, err
 		}
 		comments = append(comments, c)
 	}
-	if comments == nil {
-		comments = []*model.Comment{}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	return comments, rows.Err()
+	return pageOf(comments, limit, func(i int) model.Cursor {
+		return model.Cursor{CreatedAt: comments[i].CreatedAt, ID: comments[i].ID}
+	}), nil
 }
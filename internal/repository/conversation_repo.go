@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"social-network/internal/model"
+)
+
+// conversationRepo — реализация ConversationRepository для PostgreSQL
+type conversationRepo struct {
+	db *sql.DB
+}
+
+// NewConversationRepo создаёт новый репозиторий диалогов
+func NewConversationRepo(db *sql.DB) ConversationRepository {
+	return &conversationRepo{db: db}
+}
+
+// selectConversations — диалоги глазами участника $1 (строка conversation_participants p).
+// Непрочитанные — чужие сообщения после его отметки о прочтении
+const selectConversations = `SELECT c.id, c.is_group, c.title, c.last_message_at, c.created_at, p.muted, p.archived,
+		(SELECT COUNT(*) FROM messages um
+		 WHERE um.conversation_id = c.id AND um.id > p.last_read_id AND um.user_id <> p.user_id),
+		COALESCE((SELECT json_agg(json_build_object('user_id', pu.id, 'username', pu.username,
+				'avatar_url', pu.avatar_url, 'last_read_id', cp.last_read_id) ORDER BY cp.joined_at, pu.id)
+			FROM conversation_participants cp JOIN users pu ON pu.id = cp.user_id
+			WHERE cp.conversation_id = c.id AND cp.left_at IS NULL), '[]'),
+		lm.id, lm.user_id, lu.username, lu.avatar_url, lm.content, lm.created_at
+	FROM conversation_participants p
+	JOIN conversations c ON c.id = p.conversation_id
+	LEFT JOIN LATERAL (
+		SELECT * FROM messages WHERE conversation_id = c.id ORDER BY created_at DESC, id DESC LIMIT 1
+	) lm ON TRUE
+	LEFT JOIN users lu ON lu.id = lm.user_id
+	WHERE p.user_id = $1 AND p.left_at IS NULL`
+
+// CreateDirect возвращает личный диалог двух пользователей, создавая его при первом обращении
+func (r *conversationRepo) CreateDirect(userID, otherID int) (int, error) {
+	key := fmt.Sprintf("%d:%d", min(userID, otherID), max(userID, otherID))
+
+	var id int
+	err := inTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(
+			`INSERT INTO conversations (direct_key) VALUES ($1)
+			 ON CONFLICT (direct_key) DO UPDATE SET direct_key = EXCLUDED.direct_key
+			 RETURNING id`, key,
+		).Scan(&id)
+		if err != nil {
+			return err
+		}
+		return addParticipants(tx, id, []int{userID, otherID})
+	})
+	return id, err
+}
+
+// CreateGroup создаёт групповой диалог создателя с участниками memberIDs
+func (r *conversationRepo) CreateGroup(creatorID int, title string, memberIDs []int) (int, error) {
+	var id int
+	err := inTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(
+			`INSERT INTO conversations (is_group, title) VALUES (TRUE, $1) RETURNING id`, title,
+		).Scan(&id)
+		if err != nil {
+			return err
+		}
+		return addParticipants(tx, id, append([]int{creatorID}, memberIDs...))
+	})
+	return id, err
+}
+
+// addParticipants добавляет участников; уже состоящие в диалоге пропускаются
+func addParticipants(tx *sql.Tx, conversationID int, userIDs []int) error {
+	ids := make([]int64, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = int64(id)
+	}
+	_, err := tx.Exec(
+		`INSERT INTO conversation_participants (conversation_id, user_id)
+		 SELECT $1, unnest($2::int[])
+		 ON CONFLICT DO NOTHING`,
+		conversationID, pq.Array(ids),
+	)
+	return err
+}
+
+// GetByID возвращает диалог, если userID — его участник, иначе sql.ErrNoRows
+func (r *conversationRepo) GetByID(id, userID int) (*model.Conversation, error) {
+	return scanConversation(r.db.QueryRow(selectConversations+` AND c.id = $2`, userID, id))
+}
+
+// GetByUserID возвращает диалоги пользователя — архивные или нет, с последними сообщениями первыми
+func (r *conversationRepo) GetByUserID(userID int, archived bool, page model.PageRequest) (*model.Page[*model.Conversation], error) {
+	afterAt, afterID := afterArgs(page.After)
+	rows, err := r.db.Query(
+		selectConversations+`
+		   AND p.archived = $2
+		   AND ($3::timestamp IS NULL OR (c.last_message_at, c.id) < ($3::timestamp, $4::int))
+		 ORDER BY c.last_message_at DESC, c.id DESC
+		 LIMIT $5 OFFSET $6`, userID, archived, afterAt, afterID, page.Limit+1, page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversations := []*model.Conversation{}
+	for rows.Next() {
+		c, err := scanConversation(rows)
+		if err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pageOf(conversations, page.Limit, func(i int) model.Cursor {
+		return model.Cursor{CreatedAt: conversations[i].LastMessageAt, ID: conversations[i].ID}
+	}), nil
+}
+
+// AddMessage сохраняет сообщение. Для автора диалог сразу прочитан и возвращается
+// из архива; у остальных — возвращается, если не заглушён
+func (r *conversationRepo) AddMessage(conversationID, userID int, content string) (*model.Message, error) {
+	var msg *model.Message
+	err := inTx(r.db, func(tx *sql.Tx) error {
+		var err error
+		msg, err = scanMessage(tx.QueryRow(
+			`WITH m AS (
+				INSERT INTO messages (conversation_id, user_id, content) VALUES ($1, $2, $3)
+				RETURNING id, conversation_id, user_id, content, created_at
+			 )
+			 SELECT m.id, m.conversation_id, m.user_id, u.username, u.avatar_url, m.content, m.created_at
+			 FROM m JOIN users u ON u.id = m.user_id`,
+			conversationID, userID, content,
+		))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			`UPDATE conversations SET last_message_at = $2 WHERE id = $1`, conversationID, msg.CreatedAt,
+		); err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			`UPDATE conversation_participants
+			 SET last_read_id = CASE WHEN user_id = $2 THEN $3 ELSE last_read_id END,
+			     archived = archived AND muted AND user_id <> $2
+			 WHERE conversation_id = $1 AND left_at IS NULL`,
+			conversationID, userID, msg.ID,
+		)
+		return err
+	})
+	return msg, err
+}
+
+// GetMessages возвращает сообщения диалога глазами viewerID, новые первыми.
+// Сообщения тех, с кем у viewerID блокировка, не показываются
+func (r *conversationRepo) GetMessages(conversationID, viewerID int, page model.PageRequest) (*model.Page[*model.Message], error) {
+	afterAt, afterID := afterArgs(page.After)
+	rows, err := r.db.Query(
+		`SELECT m.id, m.conversation_id, m.user_id, u.username, u.avatar_url, m.content, m.created_at
+		 FROM messages m
+		 JOIN users u ON u.id = m.user_id
+		 WHERE m.conversation_id = $1 AND `+notBlocked("m.user_id", "$6")+`
+		   AND ($2::timestamp IS NULL OR (m.created_at, m.id) < ($2::timestamp, $3::int))
+		 ORDER BY m.created_at DESC, m.id DESC
+		 LIMIT $4 OFFSET $5`, conversationID, afterAt, afterID, page.Limit+1, page.Offset, viewerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*model.Message{}
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pageOf(messages, page.Limit, func(i int) model.Cursor {
+		return model.Cursor{CreatedAt: messages[i].CreatedAt, ID: messages[i].ID}
+	}), nil
+}
+
+// MarkRead отмечает диалог прочитанным до messageID (0 — до последнего сообщения).
+// Отметка не сдвигается назад. Возвращает итоговую отметку
+func (r *conversationRepo) MarkRead(conversationID, userID, messageID int) (int, error) {
+	var lastReadID int
+	err := r.db.QueryRow(
+		`WITH latest AS (
+			SELECT COALESCE(MAX(id), 0) AS id FROM messages WHERE conversation_id = $1
+		 )
+		 UPDATE conversation_participants
+		 SET last_read_id = GREATEST(last_read_id,
+			CASE WHEN $3 = 0 THEN (SELECT id FROM latest) ELSE LEAST($3, (SELECT id FROM latest)) END)
+		 WHERE conversation_id = $1 AND user_id = $2 AND left_at IS NULL
+		 RETURNING last_read_id`,
+		conversationID, userID, messageID,
+	).Scan(&lastReadID)
+	return lastReadID, err
+}
+
+func (r *conversationRepo) SetMuted(conversationID, userID int, muted bool) error {
+	_, err := r.db.Exec(
+		`UPDATE conversation_participants SET muted = $3
+		 WHERE conversation_id = $1 AND user_id = $2 AND left_at IS NULL`,
+		conversationID, userID, muted,
+	)
+	return err
+}
+
+func (r *conversationRepo) SetArchived(conversationID, userID int, archived bool) error {
+	_, err := r.db.Exec(
+		`UPDATE conversation_participants SET archived = $3
+		 WHERE conversation_id = $1 AND user_id = $2 AND left_at IS NULL`,
+		conversationID, userID, archived,
+	)
+	return err
+}
+
+// Leave выводит участника из группы: он больше не видит диалог и не получает сообщений
+func (r *conversationRepo) Leave(conversationID, userID int) error {
+	_, err := r.db.Exec(
+		`UPDATE conversation_participants SET left_at = NOW()
+		 WHERE conversation_id = $1 AND user_id = $2 AND left_at IS NULL`,
+		conversationID, userID,
+	)
+	return err
+}
+
+// CountUnread считает диалоги с непрочитанными сообщениями; заглушённые не учитываются
+func (r *conversationRepo) CountUnread(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COUNT(DISTINCT m.conversation_id)
+		 FROM conversation_participants p
+		 JOIN messages m ON m.conversation_id = p.conversation_id
+		 WHERE p.user_id = $1 AND p.left_at IS NULL AND NOT p.muted
+		   AND m.id > p.last_read_id AND m.user_id <> $1`, userID,
+	).Scan(&count)
+	return count, err
+}
+
+// scanConversation сканирует строку selectConversations
+func scanConversation(row rowScanner) (*model.Conversation, error) {
+	c := &model.Conversation{}
+	var participants []byte
+	var (
+		lastID, lastUserID                *int
+		lastUsername, lastAvatar, content *string
+		lastAt                            *time.Time
+	)
+	err := row.Scan(&c.ID, &c.IsGroup, &c.Title, &c.LastMessageAt, &c.CreatedAt, &c.Muted, &c.Archived,
+		&c.UnreadCount, &participants,
+		&lastID, &lastUserID, &lastUsername, &lastAvatar, &content, &lastAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(participants, &c.Participants); err != nil {
+		return nil, fmt.Errorf("участники диалога: %w", err)
+	}
+	if lastID != nil {
+		c.LastMessage = &model.Message{
+			ID:             *lastID,
+			ConversationID: c.ID,
+			UserID:         *lastUserID,
+			Username:       *lastUsername,
+			AvatarURL:      *lastAvatar,
+			Content:        *content,
+			CreatedAt:      *lastAt,
+		}
+	}
+	return c, nil
+}
+
+// scanMessage сканирует сообщение с автором
+func scanMessage(row rowScanner) (*model.Message, error) {
+	m := &model.Message{}
+	err := row.Scan(&m.ID, &m.ConversationID, &m.UserID, &m.Username, &m.AvatarURL, &m.Content, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import "database/sql"
+
+// counterRepo — реализация CounterRepository для PostgreSQL
+type counterRepo struct {
+	db *sql.DB
+}
+
+// NewCounterRepo создаёт репозиторий обслуживания счётчиков
+func NewCounterRepo(db *sql.DB) CounterRepository {
+	return &counterRepo{db: db}
+}
+
+// RecountPosts пересчитывает likes_count, comments_count и reposts_count постов.
+// Возвращает число постов, где счётчики разошлись с данными
+func (r *counterRepo) RecountPosts() (int64, error) {
+	res, err := r.db.Exec(
+		`WITH actual AS (
+			SELECT p.id,
+				(SELECT COUNT(*) FROM likes WHERE post_id = p.id) AS likes,
+				(SELECT COUNT(*) FROM comments WHERE post_id = p.id AND deleted_at IS NULL) AS comments,
+				(SELECT COUNT(*) FROM reposts WHERE post_id = p.id) AS reposts
+			FROM posts p
+		 )
+		 UPDATE posts p SET likes_count = a.likes, comments_count = a.comments, reposts_count = a.reposts
+		 FROM actual a
+		 WHERE a.id = p.id
+		   AND (p.likes_count, p.comments_count, p.reposts_count) IS DISTINCT FROM (a.likes, a.comments, a.reposts)`,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RecountUsers пересчитывает followers_count, following_count и posts_count пользователей
+func (r *counterRepo) RecountUsers() (int64, error) {
+	res, err := r.db.Exec(
+		`WITH actual AS (
+			SELECT u.id,
+				(SELECT COUNT(*) FROM follows WHERE following_id = u.id) AS followers,
+				(SELECT COUNT(*) FROM follows WHERE follower_id = u.id) AS following,
+				(SELECT COUNT(*) FROM posts WHERE user_id = u.id) AS posts
+			FROM users u
+		 )
+		 UPDATE users u SET followers_count = a.followers, following_count = a.following, posts_count = a.posts
+		 FROM actual a
+		 WHERE a.id = u.id
+		   AND (u.followers_count, u.following_count, u.posts_count) IS DISTINCT FROM (a.followers, a.following, a.posts)`,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// inTx выполняет fn в транзакции: изменение и его счётчик применяются вместе
+func inTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -2,10 +2,26 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
 
 	"social-network/internal/model"
 )
 
+// listedUserColumns — колонки пользователя в списках подписок, блокировок и скрытых
+// в порядке pageOfListedUsers
+const listedUserColumns = `u.id, u.username, u.email, u.bio, u.avatar_url, u.email_verified_at, u.role, u.suspended_at,
+			u.messages_following_only, u.is_private, u.created_at, u.updated_at`
+
+// visibleTo — условие: зритель viewer видит посты и подписки пользователя из строки users
+// с псевдонимом alias — аккаунт открыт, это он сам или его одобренный подписчик
+func visibleTo(alias, viewer string) string {
+	return `(NOT ` + alias + `.is_private OR ` + alias + `.id = ` + viewer + `
+			OR EXISTS (SELECT 1 FROM follows vf WHERE vf.follower_id = ` + viewer + ` AND vf.following_id = ` + alias + `.id))`
+}
+
 // followRepo — реализация FollowRepository для PostgreSQL
 type followRepo struct {
 	db *sql.DB
@@ -16,54 +32,254 @@ func NewFollowRepo(db *sql.DB) FollowRepository {
 	return &followRepo{db: db}
 }
 
-func (r *followRepo) Follow(followerID, followingID int) error {
-	_, err := r.db.Exec(
-		`INSERT INTO follows (follower_id, following_id)
-		 VALUES ($1, $2)
-		 ON CONFLICT DO NOTHING`,
-		followerID, followingID,
-	)
-	return err
+// Follow подписывает followerID на followingID. На закрытый аккаунт, если подписки ещё нет,
+// вместо неё создаётся запрос. При блокировке между ними ничего не меняется;
+// если пользователя нет — sql.ErrNoRows
+func (r *followRepo) Follow(followerID, followingID int) (bool, error) {
+	var requested bool
+	err := inTx(r.db, func(tx *sql.Tx) error {
+		var private, following bool
+		err := tx.QueryRow(
+			`SELECT is_private, EXISTS(SELECT 1 FROM follows WHERE follower_id = $1 AND following_id = $2)
+			 FROM users WHERE id = $2`, followerID, followingID,
+		).Scan(&private, &following)
+		if err != nil || following {
+			return err
+		}
+
+		if private {
+			_, err := tx.Exec(
+				`INSERT INTO follow_requests (follower_id, following_id)
+				 SELECT $1, $2 WHERE `+notBlocked("$2", "$1")+`
+				 ON CONFLICT DO NOTHING`,
+				followerID, followingID,
+			)
+			requested = err == nil
+			return err
+		}
+
+		res, err := tx.Exec(
+			`INSERT INTO follows (follower_id, following_id)
+			 SELECT $1, $2 WHERE `+notBlocked("$2", "$1")+`
+			 ON CONFLICT DO NOTHING`,
+			followerID, followingID,
+		)
+		if err != nil {
+			return err
+		}
+		return adjustFollows(tx, res, followerID, followingID, 1)
+	})
+	return requested, err
 }
 
+// Unfollow отменяет подписку вместе с неодобренным запросом
 func (r *followRepo) Unfollow(followerID, followingID int) error {
-	_, err := r.db.Exec(
-		`DELETE FROM follows WHERE follower_id = $1 AND following_id = $2`,
+	return inTx(r.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(
+			`DELETE FROM follow_requests WHERE follower_id = $1 AND following_id = $2`,
+			followerID, followingID,
+		); err != nil {
+			return err
+		}
+		res, err := tx.Exec(
+			`DELETE FROM follows WHERE follower_id = $1 AND following_id = $2`,
+			followerID, followingID,
+		)
+		if err != nil {
+			return err
+		}
+		return adjustFollows(tx, res, followerID, followingID, -1)
+	})
+}
+
+// GetRequests возвращает ожидающие запросы на подписку к userID, новые первыми
+func (r *followRepo) GetRequests(userID int, page model.PageRequest) (*model.Page[*model.User], error) {
+	afterAt, afterID := afterArgs(page.After)
+	rows, err := r.db.Query(
+		`SELECT `+listedUserColumns+`, fr.created_at
+		 FROM users u
+		 JOIN follow_requests fr ON u.id = fr.follower_id
+		 WHERE fr.following_id = $1
+		   AND ($2::timestamp IS NULL OR (fr.created_at, u.id) < ($2::timestamp, $3::int))
+		 ORDER BY fr.created_at DESC, u.id DESC
+		 LIMIT $4 OFFSET $5`, userID, afterAt, afterID, page.Limit+1, page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pageOfListedUsers(rows, page.Limit)
+}
+
+// ApproveRequest превращает запрос в подписку; false — запроса не было
+func (r *followRepo) ApproveRequest(followerID, followingID int) (bool, error) {
+	var approved bool
+	err := inTx(r.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(
+			`DELETE FROM follow_requests WHERE follower_id = $1 AND following_id = $2`,
+			followerID, followingID,
+		)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		approved = true
+
+		res, err = tx.Exec(
+			`INSERT INTO follows (follower_id, following_id) VALUES ($1, $2)
+			 ON CONFLICT DO NOTHING`,
+			followerID, followingID,
+		)
+		if err != nil {
+			return err
+		}
+		return adjustFollows(tx, res, followerID, followingID, 1)
+	})
+	return approved, err
+}
+
+// DeleteRequest отклоняет или отзывает запрос; false — запроса не было
+func (r *followRepo) DeleteRequest(followerID, followingID int) (bool, error) {
+	res, err := r.db.Exec(
+		`DELETE FROM follow_requests WHERE follower_id = $1 AND following_id = $2`,
 		followerID, followingID,
 	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ApproveAllRequests одобряет разом все запросы к userID — когда аккаунт открывают
+func (r *followRepo) ApproveAllRequests(userID int) ([]int, error) {
+	var ids []int64
+	err := inTx(r.db, func(tx *sql.Tx) error {
+		rows, err := tx.Query(
+			`WITH approved AS (
+				DELETE FROM follow_requests WHERE following_id = $1 RETURNING follower_id
+			 )
+			 INSERT INTO follows (follower_id, following_id)
+			 SELECT follower_id, $1 FROM approved
+			 ON CONFLICT DO NOTHING
+			 RETURNING follower_id`, userID,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		if err := rows.Err(); err != nil || len(ids) == 0 {
+			return err
+		}
+
+		_, err = tx.Exec(
+			`UPDATE users SET
+				following_count = following_count + CASE WHEN id = $1 THEN 0 ELSE 1 END,
+				followers_count = followers_count + CASE WHEN id = $1 THEN $3 ELSE 0 END
+			 WHERE id = $1 OR id = ANY($2)`, userID, pq.Array(ids), len(ids),
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	followers := make([]int, len(ids))
+	for i, id := range ids {
+		followers[i] = int(id)
+	}
+	return followers, nil
+}
+
+// adjustFollows меняет счётчики обеих сторон одним UPDATE, если подписка действительно изменилась
+func adjustFollows(tx *sql.Tx, res sql.Result, followerID, followingID, delta int) error {
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	_, err := tx.Exec(
+		`UPDATE users SET
+			following_count = following_count + CASE WHEN id = $1 THEN $3 ELSE 0 END,
+			followers_count = followers_count + CASE WHEN id = $2 THEN $3 ELSE 0 END
+		 WHERE id IN ($1, $2)`, followerID, followingID, delta,
+	)
 	return err
 }
 
-func (r *followRepo) GetFollowers(userID int) ([]*model.User, error) {
+// GetFollowers возвращает подписчиков userID; те, с кем у зрителя viewerID блокировка, пропускаются.
+// Подписчики закрытого аккаунта видны только его подписчикам
+func (r *followRepo) GetFollowers(userID, viewerID int, page model.PageRequest) (*model.Page[*model.User], error) {
+	afterAt, afterID := afterArgs(page.After)
 	rows, err := r.db.Query(
-		`SELECT u.id, u.username, u.email, u.bio, u.avatar_url, u.created_at, u.updated_at
+		`SELECT `+listedUserColumns+`, f.created_at
 		 FROM users u
 		 JOIN follows f ON u.id = f.follower_id
-		 WHERE f.following_id = $1
-		 ORDER BY f.created_at DESC`, userID,
+		 WHERE f.following_id = $1 AND `+notBlocked("u.id", "$6")+`
+		   AND EXISTS (SELECT 1 FROM users t WHERE t.id = $1 AND `+visibleTo("t", "$6")+`)
+		   AND ($2::timestamp IS NULL OR (f.created_at, u.id) < ($2::timestamp, $3::int))
+		 ORDER BY f.created_at DESC, u.id DESC
+		 LIMIT $4 OFFSET $5`, userID, afterAt, afterID, page.Limit+1, page.Offset, viewerID,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return scanUsers(rows)
+	return pageOfListedUsers(rows, page.Limit)
 }
 
-func (r *followRepo) GetFollowing(userID int) ([]*model.User, error) {
+// GetFollowing возвращает подписки userID; те, с кем у зрителя viewerID блокировка, пропускаются.
+// Подписки закрытого аккаунта видны только его подписчикам
+func (r *followRepo) GetFollowing(userID, viewerID int, page model.PageRequest) (*model.Page[*model.User], error) {
+	afterAt, afterID := afterArgs(page.After)
 	rows, err := r.db.Query(
-		`SELECT u.id, u.username, u.email, u.bio, u.avatar_url, u.created_at, u.updated_at
+		`SELECT `+listedUserColumns+`, f.created_at
 		 FROM users u
 		 JOIN follows f ON u.id = f.following_id
-		 WHERE f.follower_id = $1
-		 ORDER BY f.created_at DESC`, userID,
+		 WHERE f.follower_id = $1 AND `+notBlocked("u.id", "$6")+`
+		   AND EXISTS (SELECT 1 FROM users t WHERE t.id = $1 AND `+visibleTo("t", "$6")+`)
+		   AND ($2::timestamp IS NULL OR (f.created_at, u.id) < ($2::timestamp, $3::int))
+		 ORDER BY f.created_at DESC, u.id DESC
+		 LIMIT $4 OFFSET $5`, userID, afterAt, afterID, page.Limit+1, page.Offset, viewerID,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return scanUsers(rows)
+	return pageOfListedUsers(rows, page.Limit)
+}
+
+// pageOfListedUsers сканирует пользователей со временем подписки, блокировки или скрытия —
+// оно и служит курсором
+func pageOfListedUsers(rows *sql.Rows, limit int) (*model.Page[*model.User], error) {
+	users := []*model.User{}
+	var followedAt []time.Time
+	for rows.Next() {
+		u := &model.User{}
+		var at time.Time
+		err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.Bio, &u.AvatarURL, &u.EmailVerifiedAt, &u.Role, &u.SuspendedAt,
+			&u.MessagesFollowingOnly, &u.IsPrivate, &u.CreatedAt, &u.UpdatedAt, &at)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+		followedAt = append(followedAt, at)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pageOf(users, limit, func(i int) model.Cursor {
+		return model.Cursor{CreatedAt: followedAt[i], ID: users[i].ID}
+	}), nil
 }
 
 func (r *followRepo) IsFollowing(followerID, followingID int) (bool, error) {
@@ -75,19 +291,31 @@ func (r *followRepo) IsFollowing(followerID, followingID int) (bool, error) {
 	return exists, err
 }
 
-// scanUsers сканирует строки результата в слайс пользователей
-func scanUsers(rows *sql.Rows) ([]*model.User, error) {
-	var users []*model.User
+func (r *followRepo) CanView(viewerID, userID int) (bool, error) {
+	var visible bool
+	err := r.db.QueryRow(
+		`SELECT `+visibleTo("u", "$1")+` FROM users u WHERE u.id = $2`, viewerID, userID,
+	).Scan(&visible)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return visible, err
+}
+
+func (r *followRepo) GetFollowingIDs(followerID int) ([]int, error) {
+	rows, err := r.db.Query(`SELECT following_id FROM follows WHERE follower_id = $1`, followerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
 	for rows.Next() {
-		u := &model.User{}
-		err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.Bio, &u.AvatarURL, &u.CreatedAt, &u.UpdatedAt)
-		if err != nil {
+		var id int
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
-		users = append(users, u)
-	}
-	if users == nil {
-		users = []*model.User{}
+		ids = append(ids, id)
 	}
-	return users, rows.Err()
+	return ids, rows.Err()
 }
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"database/sql"
+
+	"social-network/internal/model"
+)
+
+// hashtagRepo — реализация HashtagRepository для PostgreSQL
+type hashtagRepo struct {
+	db *sql.DB
+}
+
+// NewHashtagRepo создаёт новый репозиторий хештегов
+func NewHashtagRepo(db *sql.DB) HashtagRepository {
+	return &hashtagRepo{db: db}
+}
+
+// Trending ранжирует теги по числу разных авторов, затем по числу постов.
+// Окно отсчитывается от NOW() базы, в том же часовом поясе, что и created_at
+func (r *hashtagRepo) Trending(windowHours, limit int) ([]*model.TrendingTag, error) {
+	rows, err := r.db.Query(
+		`SELECT h.tag, COUNT(*) AS posts_count, COUNT(DISTINCT p.user_id) AS authors_count
+		 FROM post_hashtags h
+		 JOIN posts p ON p.id = h.post_id
+		 WHERE h.created_at >= NOW() - make_interval(hours => $1)
+		 GROUP BY h.tag
+		 ORDER BY authors_count DESC, posts_count DESC, h.tag
+		 LIMIT $2`, windowHours, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*model.TrendingTag{}
+	for rows.Next() {
+		t := &model.TrendingTag{}
+		if err := rows.Scan(&t.Tag, &t.PostsCount, &t.AuthorsCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
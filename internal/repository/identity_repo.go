@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"database/sql"
+
+	"social-network/internal/model"
+)
+
+// identityRepo — реализация IdentityRepository для PostgreSQL
+type identityRepo struct {
+	db *sql.DB
+}
+
+// NewIdentityRepo создаёт новый репозиторий внешних аккаунтов
+func NewIdentityRepo(db *sql.DB) IdentityRepository {
+	return &identityRepo{db: db}
+}
+
+func (r *identityRepo) Create(identity *model.UserIdentity) error {
+	return r.db.QueryRow(
+		`INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4)
+		 RETURNING id, created_at`,
+		identity.UserID, identity.Provider, identity.Subject, identity.Email,
+	).Scan(&identity.ID, &identity.CreatedAt)
+}
+
+func (r *identityRepo) GetBySubject(provider, subject string) (*model.UserIdentity, error) {
+	identity := &model.UserIdentity{}
+	err := r.db.QueryRow(
+		`SELECT id, user_id, provider, subject, email, created_at
+		 FROM user_identities WHERE provider = $1 AND subject = $2`, provider, subject,
+	).Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (r *identityRepo) GetByUserID(userID int) ([]*model.UserIdentity, error) {
+	rows, err := r.db.Query(
+		`SELECT id, user_id, provider, subject, email, created_at
+		 FROM user_identities WHERE user_id = $1 ORDER BY created_at`, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []*model.UserIdentity{}
+	for rows.Next() {
+		identity := &model.UserIdentity{}
+		if err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject,
+			&identity.Email, &identity.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// Delete отвязывает провайдера. Возвращает false, если он не был привязан
+func (r *identityRepo) Delete(userID int, provider string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`, userID, provider)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
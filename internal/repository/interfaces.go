@@ -1,6 +1,10 @@
 package repository
 
-import "social-network/internal/model"
+import (
+	"time"
+
+	"social-network/internal/model"
+)
 
 // UserRepository — интерфейс работы с пользователями
 type UserRepository interface {
@@ -9,33 +13,128 @@ type UserRepository interface {
 	GetByEmail(email string) (*model.User, error)
 	GetByUsername(username string) (*model.User, error)
 	UpdateBio(id int, bio string) error
+	SetMessagesFollowingOnly(id int, enabled bool) error
+	SetPrivate(id int, private bool) error
 	UpdateAvatar(id int, avatarURL string) error
+	UpdatePassword(id int, passwordHash string) error
+	MarkEmailVerified(id int) error
+	SetRole(id int, role string) error
+	SetSuspended(id int, at *time.Time) error
 	GetProfile(id, currentUserID int) (*model.UserProfile, error)
 }
 
 // PostRepository — интерфейс работы с постами
 type PostRepository interface {
-	Create(userID int, content, imageURL string) (*model.Post, error)
+	Create(draft *model.PostDraft) (*model.Post, error)
 	GetByID(id, currentUserID int) (*model.Post, error)
+	GetByIDUnfiltered(id int) (*model.Post, error)
+	Update(id int, draft *model.PostDraft) error
+	GetRevisions(postID int) ([]*model.PostRevision, error)
 	Delete(id int) error
-	GetFeed(currentUserID, limit, offset int) ([]*model.Post, error)
-	GetFollowingFeed(userID, limit, offset int) ([]*model.Post, error)
-	GetByUserID(userID, currentUserID, limit, offset int) ([]*model.Post, error)
+	GetFeed(currentUserID int, page model.PageRequest) (*model.Page[*model.Post], error)
+	GetFollowingFeed(userID int, page model.PageRequest) (*model.Page[*model.Post], error)
+	GetByUserID(userID, currentUserID int, page model.PageRequest) (*model.Page[*model.Post], error)
+	GetByHashtag(tag string, currentUserID int, page model.PageRequest) (*model.Page[*model.Post], error)
+}
+
+// HashtagRepository — интерфейс статистики хештегов
+type HashtagRepository interface {
+	// Trending возвращает самые популярные теги постов за последние windowHours часов
+	Trending(windowHours, limit int) ([]*model.TrendingTag, error)
 }
 
 // CommentRepository — интерфейс работы с комментариями
 type CommentRepository interface {
-	Create(postID, userID int, content string) (*model.Comment, error)
-	GetByPostID(postID int) ([]*model.Comment, error)
+	Create(postID, userID int, parentID *int, content string, mentions []model.Mention) (*model.Comment, error)
+	GetByID(id, viewerID int) (*model.Comment, error)
+	Update(id int, content string, mentions []model.Mention) error
+	SoftDelete(id int) error
+	SetHidden(id int, hidden bool) error
+	GetByPostID(postID, viewerID int, page model.PageRequest) (*model.Page[*model.Comment], error)
+	GetReplies(parentID, viewerID int, page model.PageRequest) (*model.Page[*model.Comment], error)
+}
+
+// MentionRepository — интерфейс работы с упоминаниями
+type MentionRepository interface {
+	GetByUserID(userID int, page model.PageRequest) (*model.Page[*model.MentionNotice], error)
+}
+
+// SearchRepository — интерфейс поиска постов и пользователей
+type SearchRepository interface {
+	SearchPosts(req model.SearchRequest, currentUserID int) (*model.Page[*model.PostSearchResult], error)
+	SearchUsers(req model.SearchRequest, currentUserID int) (*model.Page[*model.UserSearchResult], error)
+}
+
+// NotificationRepository — интерфейс работы с уведомлениями
+type NotificationRepository interface {
+	Create(event *model.NotificationEvent) error
+	DeleteEvent(event *model.NotificationEvent) error
+	DeleteByComment(commentID int) error
+	DeleteStaleMentions(postID int, commentID *int, keepUserIDs []int64) error
+	GetByUserID(userID int, page model.PageRequest) (*model.Page[*model.Notification], error)
+	CountUnread(userID int) (int, error)
+	MarkRead(userID, groupID int) (bool, error)
+	MarkAllRead(userID int) error
+}
+
+// ConversationRepository — интерфейс работы с диалогами и сообщениями
+type ConversationRepository interface {
+	CreateDirect(userID, otherID int) (int, error)
+	CreateGroup(creatorID int, title string, memberIDs []int) (int, error)
+	GetByID(id, userID int) (*model.Conversation, error)
+	GetByUserID(userID int, archived bool, page model.PageRequest) (*model.Page[*model.Conversation], error)
+	AddMessage(conversationID, userID int, content string) (*model.Message, error)
+	GetMessages(conversationID, viewerID int, page model.PageRequest) (*model.Page[*model.Message], error)
+	MarkRead(conversationID, userID, messageID int) (int, error)
+	SetMuted(conversationID, userID int, muted bool) error
+	SetArchived(conversationID, userID int, archived bool) error
+	Leave(conversationID, userID int) error
+	CountUnread(userID int) (int, error)
 }
 
-// FollowRepository — интерфейс работы с подписками
+// FollowRepository — интерфейс работы с подписками и запросами на подписку
 type FollowRepository interface {
-	Follow(followerID, followingID int) error
+	// Follow подписывает сразу или, если аккаунт закрыт, создаёт запрос (requested = true)
+	Follow(followerID, followingID int) (requested bool, err error)
 	Unfollow(followerID, followingID int) error
-	GetFollowers(userID int) ([]*model.User, error)
-	GetFollowing(userID int) ([]*model.User, error)
+	GetRequests(userID int, page model.PageRequest) (*model.Page[*model.User], error)
+	ApproveRequest(followerID, followingID int) (bool, error)
+	DeleteRequest(followerID, followingID int) (bool, error)
+	// ApproveAllRequests одобряет все запросы к userID и возвращает ID новых подписчиков
+	ApproveAllRequests(userID int) ([]int, error)
+	GetFollowers(userID, viewerID int, page model.PageRequest) (*model.Page[*model.User], error)
+	GetFollowing(userID, viewerID int, page model.PageRequest) (*model.Page[*model.User], error)
 	IsFollowing(followerID, followingID int) (bool, error)
+	// CanView проверяет, видит ли viewerID посты userID: аккаунт открыт или viewerID — он сам
+	// либо его одобренный подписчик
+	CanView(viewerID, userID int) (bool, error)
+	GetFollowingIDs(followerID int) ([]int, error)
+}
+
+// BlockRepository — интерфейс работы с блокировками пользователей
+type BlockRepository interface {
+	Block(blockerID, blockedID int) error
+	Unblock(blockerID, blockedID int) error
+	GetBlocked(userID int, page model.PageRequest) (*model.Page[*model.User], error)
+	// IsBlocked проверяет блокировку в любую сторону
+	IsBlocked(userID, otherID int) (bool, error)
+	// AnyBlocked проверяет, есть ли блокировка между какими-нибудь двумя из userIDs
+	AnyBlocked(userIDs []int) (bool, error)
+}
+
+// MuteRepository — интерфейс работы со скрытыми пользователями
+type MuteRepository interface {
+	Mute(muterID, mutedID int) error
+	Unmute(muterID, mutedID int) error
+	GetMuted(userID int, page model.PageRequest) (*model.Page[*model.User], error)
+	IsMuted(muterID, mutedID int) (bool, error)
+	GetMutedIDs(muterID int) ([]int, error)
+}
+
+// CounterRepository — пересчёт денормализованных счётчиков по исходным данным
+type CounterRepository interface {
+	RecountPosts() (int64, error)
+	RecountUsers() (int64, error)
 }
 
 // LikeRepository — интерфейс работы с лайками
@@ -45,10 +144,74 @@ type LikeRepository interface {
 	IsLiked(userID, postID int) (bool, error)
 }
 
+// RepostRepository — интерфейс работы с репостами
+type RepostRepository interface {
+	Repost(userID, postID int) error
+	Unrepost(userID, postID int) error
+}
+
 // TokenRepository — интерфейс работы с refresh-токенами
 type TokenRepository interface {
-	Create(userID int, tokenHash string, expiresAt any) error
+	Create(token *model.RefreshToken) error
 	GetByHash(tokenHash string) (*model.RefreshToken, error)
+	GetSessions(userID int) ([]*model.Session, error)
+	MarkRotated(tokenHash string) (bool, error)
 	DeleteByHash(tokenHash string) error
+	DeleteByFamily(familyID string) error
 	DeleteByUserID(userID int) error
+	DeleteByUserIDExcept(userID int, familyID string) error
+}
+
+// UserTokenRepository — интерфейс работы с одноразовыми токенами из писем
+type UserTokenRepository interface {
+	Create(userID int, purpose, tokenHash string, expiresAt time.Time) error
+	Consume(purpose, tokenHash string) (*model.UserToken, error)
+	DeleteByUserID(userID int, purpose string) error
+}
+
+// MFARepository — интерфейс работы с настройками двухфакторной аутентификации
+type MFARepository interface {
+	SaveTOTPSecret(userID int, secret string) error
+	GetTOTP(userID int) (*model.TOTPSettings, error)
+	ConfirmTOTP(userID int) error
+	AdvanceTOTPStep(userID int, step int64) (bool, error)
+	DeleteTOTP(userID int) error
+	ReplaceRecoveryCodes(userID int, codeHashes []string) error
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+	CountRecoveryCodes(userID int) (int, error)
+}
+
+// AccessTokenRepository — интерфейс работы с персональными токенами доступа
+type AccessTokenRepository interface {
+	Create(token *model.PersonalAccessToken) error
+	GetByHash(tokenHash string) (*model.PersonalAccessToken, error)
+	GetByUserID(userID int) ([]*model.PersonalAccessToken, error)
+	TouchLastUsed(id int, usedAt time.Time) error
+	Delete(id, userID int) (bool, error)
+}
+
+// LoginAttemptRepository — интерфейс хранения неудачных попыток входа.
+// Get возвращает sql.ErrNoRows, если неудач по ключу не было
+type LoginAttemptRepository interface {
+	Get(key string) (*model.LoginAttempt, error)
+	// RegisterFailure увеличивает счётчик; неудачи до windowStart забываются
+	RegisterFailure(key string, at, windowStart time.Time) (*model.LoginAttempt, error)
+	Lock(key string, until time.Time) error
+	Reset(key string) error
+}
+
+// IdentityRepository — интерфейс работы с привязанными внешними аккаунтами
+type IdentityRepository interface {
+	Create(identity *model.UserIdentity) error
+	GetBySubject(provider, subject string) (*model.UserIdentity, error)
+	GetByUserID(userID int) ([]*model.UserIdentity, error)
+	Delete(userID int, provider string) (bool, error)
+}
+
+// OAuthStateRepository — интерфейс хранения незавершённых входов через провайдера
+type OAuthStateRepository interface {
+	Create(state *model.OAuthState) error
+	// Consume удаляет и возвращает state; повторный callback с тем же state получит sql.ErrNoRows
+	Consume(stateHash string) (*model.OAuthState, error)
+	DeleteExpired(before time.Time) error
 }
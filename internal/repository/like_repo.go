@@ -13,20 +13,39 @@ func NewLikeRepo(db *sql.DB) LikeRepository {
 }
 
 func (r *likeRepo) Like(userID, postID int) error {
-	_, err := r.db.Exec(
-		`INSERT INTO likes (user_id, post_id)
-		 VALUES ($1, $2)
-		 ON CONFLICT DO NOTHING`,
-		userID, postID,
-	)
-	return err
+	return inTx(r.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(
+			`INSERT INTO likes (user_id, post_id)
+			 VALUES ($1, $2)
+			 ON CONFLICT DO NOTHING`,
+			userID, postID,
+		)
+		if err != nil {
+			return err
+		}
+		return adjustLikes(tx, res, postID, 1)
+	})
 }
 
 func (r *likeRepo) Unlike(userID, postID int) error {
-	_, err := r.db.Exec(
-		`DELETE FROM likes WHERE user_id = $1 AND post_id = $2`,
-		userID, postID,
-	)
+	return inTx(r.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(
+			`DELETE FROM likes WHERE user_id = $1 AND post_id = $2`,
+			userID, postID,
+		)
+		if err != nil {
+			return err
+		}
+		return adjustLikes(tx, res, postID, -1)
+	})
+}
+
+// adjustLikes меняет likes_count, только если лайк действительно добавился или удалился
+func adjustLikes(tx *sql.Tx, res sql.Result, postID, delta int) error {
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	_, err := tx.Exec(`UPDATE posts SET likes_count = likes_count + $1 WHERE id = $2`, delta, postID)
 	return err
 }
 
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"social-network/internal/model"
+)
+
+// memoryLoginAttemptRepo — хранение попыток входа в памяти процесса.
+// Подходит для одного экземпляра приложения: счётчики теряются при перезапуске
+type memoryLoginAttemptRepo struct {
+	mu        sync.Mutex
+	attempts  map[string]model.LoginAttempt
+	lastSweep time.Time
+}
+
+// memorySweepInterval — как часто выбрасывать из памяти забытые ключи
+const memorySweepInterval = time.Minute
+
+// NewMemoryLoginAttemptRepo создаёт репозиторий попыток входа в памяти
+func NewMemoryLoginAttemptRepo() LoginAttemptRepository {
+	return &memoryLoginAttemptRepo{attempts: make(map[string]model.LoginAttempt)}
+}
+
+func (r *memoryLoginAttemptRepo) Get(key string) (*model.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &attempt, nil
+}
+
+func (r *memoryLoginAttemptRepo) RegisterFailure(key string, at, windowStart time.Time) (*model.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok || attempt.LastFailureAt.Before(windowStart) {
+		attempt = model.LoginAttempt{Key: key}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = at
+	r.attempts[key] = attempt
+
+	// Время от времени выбрасываем забытые ключи, чтобы карта не росла бесконечно
+	if at.Sub(r.lastSweep) >= memorySweepInterval {
+		for k, a := range r.attempts {
+			if a.LastFailureAt.Before(windowStart) && (a.LockedUntil == nil || a.LockedUntil.Before(at)) {
+				delete(r.attempts, k)
+			}
+		}
+		r.lastSweep = at
+	}
+
+	return &attempt, nil
+}
+
+func (r *memoryLoginAttemptRepo) Lock(key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if attempt, ok := r.attempts[key]; ok {
+		attempt.LockedUntil = &until
+		r.attempts[key] = attempt
+	}
+	return nil
+}
+
+func (r *memoryLoginAttemptRepo) Reset(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"social-network/internal/model"
+)
+
+// loginAttemptRepo — реализация LoginAttemptRepository для PostgreSQL
+type loginAttemptRepo struct {
+	db *sql.DB
+}
+
+// NewLoginAttemptRepo создаёт репозиторий попыток входа в PostgreSQL
+func NewLoginAttemptRepo(db *sql.DB) LoginAttemptRepository {
+	return &loginAttemptRepo{db: db}
+}
+
+func (r *loginAttemptRepo) Get(key string) (*model.LoginAttempt, error) {
+	attempt := &model.LoginAttempt{}
+	err := r.db.QueryRow(
+		`SELECT key, failures, last_failure_at, locked_until FROM login_attempts WHERE key = $1`, key,
+	).Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &attempt.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// RegisterFailure атомарно увеличивает счётчик, чтобы параллельные попытки не терялись.
+// Время пишем в UTC: колонки TIMESTAMP хранят его без часового пояса
+func (r *loginAttemptRepo) RegisterFailure(key string, at, windowStart time.Time) (*model.LoginAttempt, error) {
+	attempt := &model.LoginAttempt{}
+	err := r.db.QueryRow(
+		`INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 1, $2)
+		 ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1
+				ELSE login_attempts.failures + 1 END,
+			locked_until = CASE WHEN login_attempts.last_failure_at < $3 THEN NULL
+				ELSE login_attempts.locked_until END,
+			last_failure_at = $2
+		 RETURNING key, failures, last_failure_at, locked_until`,
+		key, at.UTC(), windowStart.UTC(),
+	).Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &attempt.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+func (r *loginAttemptRepo) Lock(key string, until time.Time) error {
+	_, err := r.db.Exec(`UPDATE login_attempts SET locked_until = $1 WHERE key = $2`, until.UTC(), key)
+	return err
+}
+
+func (r *loginAttemptRepo) Reset(key string) error {
+	_, err := r.db.Exec(`DELETE FROM login_attempts WHERE key = $1`, key)
+	return err
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"social-network/internal/model"
+)
+
+// mentionsOf — подзапрос упоминаний одного текста JSON-массивом для jsonMentions.
+// cond отбирает строки mentions mt
+func mentionsOf(cond string) string {
+	return `COALESCE((SELECT json_agg(json_build_object('user_id', mt.user_id, 'username', mu.username,
+			'offset', mt.start_offset, 'length', mt.length) ORDER BY mt.start_offset)
+		 FROM mentions mt JOIN users mu ON mu.id = mt.user_id
+		 WHERE ` + cond + `), '[]')`
+}
+
+var (
+	// postMentions — упоминания в тексте поста p
+	postMentions = mentionsOf("mt.post_id = p.id AND mt.comment_id IS NULL")
+	// commentMentions — упоминания в тексте комментария c
+	commentMentions = mentionsOf("mt.comment_id = c.id")
+)
+
+// jsonMentions сканирует результат mentionsOf в срез упоминаний
+type jsonMentions []model.Mention
+
+func (m *jsonMentions) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, (*[]model.Mention)(m))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]model.Mention)(m))
+	default:
+		return fmt.Errorf("упоминания: неожиданный тип %T", src)
+	}
+}
+
+// replaceMentions заменяет упоминания текста поста (commentID == nil) или комментария.
+// Пользователь, упомянутый и до правки, сохраняет прежнее время упоминания
+func replaceMentions(tx *sql.Tx, postID int, commentID *int, mentions []model.Mention) error {
+	userIDs := make([]int64, len(mentions))
+	offsets := make([]int64, len(mentions))
+	lengths := make([]int64, len(mentions))
+	for i, m := range mentions {
+		userIDs[i], offsets[i], lengths[i] = int64(m.UserID), int64(m.Offset), int64(m.Length)
+	}
+
+	_, err := tx.Exec(
+		`WITH old AS (
+			DELETE FROM mentions WHERE post_id = $1 AND comment_id IS NOT DISTINCT FROM $2::int
+			RETURNING user_id, created_at
+		 )
+		 INSERT INTO mentions (user_id, post_id, comment_id, start_offset, length, created_at)
+		 SELECT n.user_id, $1, $2::int, n.start_offset, n.length,
+		        COALESCE((SELECT MIN(old.created_at) FROM old WHERE old.user_id = n.user_id), NOW())
+		 FROM unnest($3::int[], $4::int[], $5::int[]) AS n(user_id, start_offset, length)`,
+		postID, commentID, pq.Array(userIDs), pq.Array(offsets), pq.Array(lengths),
+	)
+	return err
+}
+
+// mentionRepo — реализация MentionRepository для PostgreSQL
+type mentionRepo struct {
+	db *sql.DB
+}
+
+// NewMentionRepo создаёт новый репозиторий упоминаний
+func NewMentionRepo(db *sql.DB) MentionRepository {
+	return &mentionRepo{db: db}
+}
+
+// GetByUserID возвращает посты и комментарии, где упомянут пользователь, новые первыми.
+// Несколько упоминаний в одном тексте дают одну запись; свои тексты, удалённые
+// и скрытые комментарии, тексты тех, с кем блокировка, и тексты к постам закрытых
+// аккаунтов, которые пользователю не видны, не показываются
+func (r *mentionRepo) GetByUserID(userID int, page model.PageRequest) (*model.Page[*model.MentionNotice], error) {
+	afterAt, afterID := afterArgs(page.After)
+	rows, err := r.db.Query(
+		`SELECT m.id, m.post_id, m.comment_id, a.id, a.username, a.avatar_url,
+		        COALESCE(c.content, p.content),
+		        CASE WHEN m.comment_id IS NULL THEN `+postMentions+` ELSE `+commentMentions+` END,
+		        m.created_at
+		 FROM mentions m
+		 JOIN posts p ON p.id = m.post_id
+		 LEFT JOIN comments c ON c.id = m.comment_id
+		 JOIN users a ON a.id = COALESCE(c.user_id, p.user_id)
+		 JOIN users pa ON pa.id = p.user_id
+		 WHERE m.user_id = $1 AND a.id <> $1 AND `+notBlocked("a.id", "$1")+` AND `+visibleTo("pa", "$1")+`
+		   AND (c.id IS NULL OR (c.deleted_at IS NULL AND c.hidden_at IS NULL))
+		   AND NOT EXISTS (
+		       SELECT 1 FROM mentions d
+		       WHERE d.user_id = m.user_id AND d.post_id = m.post_id
+		         AND d.comment_id IS NOT DISTINCT FROM m.comment_id AND d.id < m.id)
+		   AND ($2::timestamp IS NULL OR (m.created_at, m.id) < ($2::timestamp, $3::int))
+		 ORDER BY m.created_at DESC, m.id DESC
+		 LIMIT $4 OFFSET $5`, userID, afterAt, afterID, page.Limit+1, page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notices := []*model.MentionNotice{}
+	for rows.Next() {
+		n := &model.MentionNotice{}
+		err := rows.Scan(&n.ID, &n.PostID, &n.CommentID, &n.AuthorID, &n.Username, &n.AvatarURL,
+			&n.Content, (*jsonMentions)(&n.Mentions), &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		notices = append(notices, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pageOf(notices, page.Limit, func(i int) model.Cursor {
+		return model.Cursor{CreatedAt: notices[i].CreatedAt, ID: notices[i].ID}
+	}), nil
+}
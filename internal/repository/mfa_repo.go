@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"database/sql"
+
+	"social-network/internal/model"
+)
+
+// mfaRepo — реализация MFARepository для PostgreSQL
+type mfaRepo struct {
+	db *sql.DB
+}
+
+// NewMFARepo создаёт новый репозиторий настроек двухфакторной аутентификации
+func NewMFARepo(db *sql.DB) MFARepository {
+	return &mfaRepo{db: db}
+}
+
+// SaveTOTPSecret сохраняет секрет неподтверждённого подключения (заменяя прежний)
+func (r *mfaRepo) SaveTOTPSecret(userID int, secret string) error {
+	_, err := r.db.Exec(
+		`INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		 ON CONFLICT (user_id) DO UPDATE
+		 SET secret = EXCLUDED.secret, last_step = 0, created_at = NOW()
+		 WHERE user_totp.confirmed_at IS NULL`,
+		userID, secret,
+	)
+	return err
+}
+
+func (r *mfaRepo) GetTOTP(userID int) (*model.TOTPSettings, error) {
+	settings := &model.TOTPSettings{}
+	err := r.db.QueryRow(
+		`SELECT user_id, secret, confirmed_at, last_step, created_at
+		 FROM user_totp WHERE user_id = $1`, userID,
+	).Scan(&settings.UserID, &settings.Secret, &settings.ConfirmedAt, &settings.LastStep, &settings.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (r *mfaRepo) ConfirmTOTP(userID int) error {
+	_, err := r.db.Exec(`UPDATE user_totp SET confirmed_at = NOW() WHERE user_id = $1`, userID)
+	return err
+}
+
+// AdvanceTOTPStep запоминает принятый шаг. Возвращает false, если код
+// этого или более позднего шага уже использовался
+func (r *mfaRepo) AdvanceTOTPStep(userID int, step int64) (bool, error) {
+	res, err := r.db.Exec(
+		`UPDATE user_totp SET last_step = $2 WHERE user_id = $1 AND last_step < $2`,
+		userID, step,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// DeleteTOTP отключает TOTP вместе с кодами восстановления
+func (r *mfaRepo) DeleteTOTP(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes заменяет все коды восстановления новым набором
+func (r *mfaRepo) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(
+			`INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode атомарно помечает код использованным
+func (r *mfaRepo) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	res, err := r.db.Exec(
+		`UPDATE mfa_recovery_codes SET used_at = NOW()
+		 WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userID, codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *mfaRepo) CountRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID,
+	).Scan(&count)
+	return count, err
+}
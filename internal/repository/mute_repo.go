@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"database/sql"
+
+	"social-network/internal/model"
+)
+
+// notMuted — условие: зритель viewer не скрывал пользователя из колонки userCol
+func notMuted(userCol, viewer string) string {
+	return `NOT EXISTS (SELECT 1 FROM user_mutes mu WHERE mu.muter_id = ` + viewer + ` AND mu.muted_id = ` + userCol + `)`
+}
+
+// muteRepo — реализация MuteRepository для PostgreSQL
+type muteRepo struct {
+	db *sql.DB
+}
+
+// NewMuteRepo создаёт новый репозиторий скрытых пользователей
+func NewMuteRepo(db *sql.DB) MuteRepository {
+	return &muteRepo{db: db}
+}
+
+func (r *muteRepo) Mute(muterID, mutedID int) error {
+	_, err := r.db.Exec(
+		`INSERT INTO user_mutes (muter_id, muted_id) VALUES ($1, $2)
+		 ON CONFLICT DO NOTHING`, muterID, mutedID,
+	)
+	return err
+}
+
+func (r *muteRepo) Unmute(muterID, mutedID int) error {
+	_, err := r.db.Exec(
+		`DELETE FROM user_mutes WHERE muter_id = $1 AND muted_id = $2`, muterID, mutedID,
+	)
+	return err
+}
+
+// GetMuted возвращает скрытых пользователем, последних первыми
+func (r *muteRepo) GetMuted(userID int, page model.PageRequest) (*model.Page[*model.User], error) {
+	afterAt, afterID := afterArgs(page.After)
+	rows, err := r.db.Query(
+		`SELECT `+listedUserColumns+`, m.created_at
+		 FROM users u
+		 JOIN user_mutes m ON u.id = m.muted_id
+		 WHERE m.muter_id = $1
+		   AND ($2::timestamp IS NULL OR (m.created_at, u.id) < ($2::timestamp, $3::int))
+		 ORDER BY m.created_at DESC, u.id DESC
+		 LIMIT $4 OFFSET $5`, userID, afterAt, afterID, page.Limit+1, page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pageOfListedUsers(rows, page.Limit)
+}
+
+func (r *muteRepo) IsMuted(muterID, mutedID int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM user_mutes WHERE muter_id = $1 AND muted_id = $2)`, muterID, mutedID,
+	).Scan(&exists)
+	return exists, err
+}
+
+// GetMutedIDs возвращает ID всех скрытых пользователем — для подписок потока
+func (r *muteRepo) GetMutedIDs(muterID int) ([]int, error) {
+	rows, err := r.db.Query(`SELECT muted_id FROM user_mutes WHERE muter_id = $1`, muterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
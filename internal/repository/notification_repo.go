@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"social-network/internal/model"
+)
+
+// notificationRepo — реализация NotificationRepository для PostgreSQL
+type notificationRepo struct {
+	db *sql.DB
+}
+
+// NewNotificationRepo создаёт новый репозиторий уведомлений
+func NewNotificationRepo(db *sql.DB) NotificationRepository {
+	return &notificationRepo{db: db}
+}
+
+// Create добавляет событие в последнюю непрочитанную группу с тем же ключом
+// или начинает новую. Повтор того же события ничего не меняет
+func (r *notificationRepo) Create(e *model.NotificationEvent) error {
+	_, err := r.db.Exec(
+		`INSERT INTO notifications (user_id, actor_id, type, post_id, comment_id, group_key, group_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, (
+			SELECT COALESCE(group_id, id) FROM notifications
+			WHERE user_id = $1 AND group_key = $6 AND read_at IS NULL
+			ORDER BY id DESC LIMIT 1
+		 ))
+		 ON CONFLICT DO NOTHING`,
+		e.UserID, e.ActorID, e.Type, e.PostID, e.CommentID, e.GroupKey,
+	)
+	return err
+}
+
+// DeleteEvent удаляет событие, если действие отменили (снятый лайк, отписка)
+func (r *notificationRepo) DeleteEvent(e *model.NotificationEvent) error {
+	_, err := r.db.Exec(
+		`DELETE FROM notifications
+		 WHERE user_id = $1 AND actor_id = $2 AND type = $3
+		   AND post_id IS NOT DISTINCT FROM $4::int AND comment_id IS NOT DISTINCT FROM $5::int`,
+		e.UserID, e.ActorID, e.Type, e.PostID, e.CommentID,
+	)
+	return err
+}
+
+// DeleteByComment удаляет все события удалённого комментария
+func (r *notificationRepo) DeleteByComment(commentID int) error {
+	_, err := r.db.Exec(`DELETE FROM notifications WHERE comment_id = $1`, commentID)
+	return err
+}
+
+// DeleteStaleMentions удаляет упоминания текста, которых после правки не осталось
+func (r *notificationRepo) DeleteStaleMentions(postID int, commentID *int, keepUserIDs []int64) error {
+	_, err := r.db.Exec(
+		`DELETE FROM notifications
+		 WHERE type = $1 AND post_id = $2 AND comment_id IS NOT DISTINCT FROM $3::int
+		   AND NOT (user_id = ANY($4::int[]))`,
+		model.NotificationMention, postID, commentID, pq.Array(keepUserIDs),
+	)
+	return err
+}
+
+// GetByUserID возвращает группы уведомлений, начиная с последних событий.
+// Группа показывается по последнему событию, actors_count — разные авторы в ней.
+// События от тех, с кем блокировка, не учитываются
+func (r *notificationRepo) GetByUserID(userID int, page model.PageRequest) (*model.Page[*model.Notification], error) {
+	afterAt, afterID := afterArgs(page.After)
+	rows, err := r.db.Query(
+		`WITH groups AS (
+			SELECT COALESCE(group_id, id) AS gid, MAX(id) AS last_id,
+			       COUNT(DISTINCT actor_id) AS actors_count, bool_and(read_at IS NOT NULL) AS is_read
+			FROM notifications
+			WHERE user_id = $1 AND `+notBlocked("actor_id", "$1")+`
+			GROUP BY 1
+		 )
+		 SELECT g.gid, n.type, n.post_id, n.comment_id, a.id, a.username, a.avatar_url,
+		        g.actors_count, g.is_read, n.created_at
+		 FROM groups g
+		 JOIN notifications n ON n.id = g.last_id
+		 JOIN users a ON a.id = n.actor_id
+		 WHERE ($2::timestamp IS NULL OR (n.created_at, g.gid) < ($2::timestamp, $3::int))
+		 ORDER BY n.created_at DESC, g.gid DESC
+		 LIMIT $4 OFFSET $5`, userID, afterAt, afterID, page.Limit+1, page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []*model.Notification{}
+	for rows.Next() {
+		n := &model.Notification{}
+		err := rows.Scan(&n.ID, &n.Type, &n.PostID, &n.CommentID, &n.Actor.ID, &n.Actor.Username, &n.Actor.AvatarURL,
+			&n.ActorsCount, &n.Read, &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pageOf(notifications, page.Limit, func(i int) model.Cursor {
+		return model.Cursor{CreatedAt: notifications[i].CreatedAt, ID: notifications[i].ID}
+	}), nil
+}
+
+// CountUnread считает непрочитанные группы без событий от тех, с кем блокировка
+func (r *notificationRepo) CountUnread(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COUNT(DISTINCT COALESCE(group_id, id)) FROM notifications
+		 WHERE user_id = $1 AND read_at IS NULL AND `+notBlocked("actor_id", "$1"), userID,
+	).Scan(&count)
+	return count, err
+}
+
+// MarkRead отмечает группу прочитанной; false — у пользователя нет такой группы
+func (r *notificationRepo) MarkRead(userID, groupID int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(
+		`WITH updated AS (
+			UPDATE notifications SET read_at = NOW()
+			WHERE user_id = $1 AND COALESCE(group_id, id) = $2 AND read_at IS NULL
+		 )
+		 SELECT EXISTS(SELECT 1 FROM notifications WHERE user_id = $1 AND COALESCE(group_id, id) = $2)`,
+		userID, groupID,
+	).Scan(&exists)
+	return exists, err
+}
+
+// MarkAllRead отмечает прочитанными все уведомления пользователя
+func (r *notificationRepo) MarkAllRead(userID int) error {
+	_, err := r.db.Exec(
+		`UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`, userID,
+	)
+	return err
+}
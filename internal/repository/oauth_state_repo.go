@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"social-network/internal/model"
+)
+
+// oauthStateRepo — реализация OAuthStateRepository для PostgreSQL
+type oauthStateRepo struct {
+	db *sql.DB
+}
+
+// NewOAuthStateRepo создаёт новый репозиторий незавершённых входов
+func NewOAuthStateRepo(db *sql.DB) OAuthStateRepository {
+	return &oauthStateRepo{db: db}
+}
+
+func (r *oauthStateRepo) Create(state *model.OAuthState) error {
+	_, err := r.db.Exec(
+		`INSERT INTO oauth_states (state_hash, provider, code_verifier, nonce, user_id, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		state.StateHash, state.Provider, state.CodeVerifier, state.Nonce, state.UserID, state.ExpiresAt.UTC(),
+	)
+	return err
+}
+
+// DeleteExpired убирает брошенные входы, чтобы таблица не росла
+func (r *oauthStateRepo) DeleteExpired(before time.Time) error {
+	_, err := r.db.Exec(`DELETE FROM oauth_states WHERE expires_at < $1`, before.UTC())
+	return err
+}
+
+func (r *oauthStateRepo) Consume(stateHash string) (*model.OAuthState, error) {
+	state := &model.OAuthState{}
+	err := r.db.QueryRow(
+		`DELETE FROM oauth_states WHERE state_hash = $1
+		 RETURNING state_hash, provider, code_verifier, nonce, user_id, expires_at`, stateHash,
+	).Scan(&state.StateHash, &state.Provider, &state.CodeVerifier, &state.Nonce, &state.UserID, &state.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
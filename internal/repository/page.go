@@ -0,0 +1,23 @@
+package repository
+
+import "social-network/internal/model"
+
+// afterArgs раскладывает курсор в параметры запроса; без курсора оба NULL.
+// В SQL условие пишется как ($n::timestamp IS NULL OR (created_at, id) < ($n, $m))
+func afterArgs(after *model.Cursor) (any, any) {
+	if after == nil {
+		return nil, nil
+	}
+	return after.CreatedAt, after.ID
+}
+
+// pageOf собирает страницу из выборки на limit+1 строк: лишняя строка означает,
+// что дальше есть ещё, и курсор ставится на последний отданный элемент
+func pageOf[T any](items []T, limit int, key func(i int) model.Cursor) *model.Page[T] {
+	page := &model.Page[T]{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = key(limit - 1).Encode()
+	}
+	return page
+}
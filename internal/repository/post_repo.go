@@ -2,10 +2,40 @@ package repository
 
 import (
 	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
 
 	"social-network/internal/model"
 )
 
+// postColumns — колонки поста с автором, упоминаниями, счётчиками и цитируемым постом в порядке scanPost.
+// viewer — плейсхолдер с ID текущего пользователя для is_liked и is_reposted (например, "$1")
+func postColumns(viewer string) string {
+	return `p.id, p.user_id, u.username, u.avatar_url, p.content, ` + postMentions + `, p.image_url,
+			p.likes_count, p.comments_count, p.reposts_count,
+			EXISTS(SELECT 1 FROM likes WHERE post_id = p.id AND user_id = ` + viewer + `) as is_liked,
+			EXISTS(SELECT 1 FROM reposts WHERE post_id = p.id AND user_id = ` + viewer + `) as is_reposted,
+			p.is_quote, q.id, q.user_id, qu.username, qu.avatar_url, q.content, q.image_url, q.created_at,
+			p.created_at, p.updated_at, p.edited_at`
+}
+
+// postJoins — JOIN автора и цитируемого поста для postColumns. Цитата автора, с которым
+// у зрителя viewer блокировка или чей закрытый аккаунт ему не виден, не подтягивается — как удалённая
+func postJoins(viewer string) string {
+	return `
+		 JOIN users u ON p.user_id = u.id
+		 LEFT JOIN posts q ON q.id = p.quote_of AND ` + notBlocked("q.user_id", viewer) + `
+			AND EXISTS (SELECT 1 FROM users qa WHERE qa.id = q.user_id AND ` + visibleTo("qa", viewer) + `)
+		 LEFT JOIN users qu ON qu.id = q.user_id`
+}
+
+// selectPosts — общий SELECT постов в порядке, ожидаемом scanPost
+func selectPosts(viewer string) string {
+	return `SELECT ` + postColumns(viewer) + `
+		 FROM posts p` + postJoins(viewer)
+}
+
 // postRepo — реализация PostRepository для PostgreSQL
 type postRepo struct {
 	db *sql.DB
@@ -16,121 +46,315 @@ func NewPostRepo(db *sql.DB) PostRepository {
 	return &postRepo{db: db}
 }
 
-func (r *postRepo) Create(userID int, content, imageURL string) (*model.Post, error) {
-	post := &model.Post{}
-	err := r.db.QueryRow(
-		`INSERT INTO posts (user_id, content, image_url)
-		 VALUES ($1, $2, $3)
-		 RETURNING id, user_id, content, image_url, created_at, updated_at`,
-		userID, content, imageURL,
-	).Scan(&post.ID, &post.UserID, &post.Content, &post.ImageURL, &post.CreatedAt, &post.UpdatedAt)
+// Create публикует пост вместе с его хештегами и упоминаниями
+func (r *postRepo) Create(draft *model.PostDraft) (*model.Post, error) {
+	var id int
+	err := inTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(
+			`INSERT INTO posts (user_id, content, image_url, quote_of, is_quote)
+			 VALUES ($1, $2, $3, $4, $4 IS NOT NULL)
+			 RETURNING id`,
+			draft.UserID, draft.Content, draft.ImageURL, draft.QuoteOf,
+		).Scan(&id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE users SET posts_count = posts_count + 1 WHERE id = $1`, draft.UserID); err != nil {
+			return err
+		}
+		if err := insertHashtags(tx, id, draft.Hashtags); err != nil {
+			return err
+		}
+		return replaceMentions(tx, id, nil, draft.Mentions)
+	})
 	if err != nil {
 		return nil, err
 	}
+	return r.GetByID(id, draft.UserID)
+}
 
-	// Подтягиваем имя пользователя
-	r.db.QueryRow(`SELECT username, avatar_url FROM users WHERE id = $1`, userID).
-		Scan(&post.Username, &post.AvatarURL)
+// GetByID возвращает пост глазами currentUserID; если между ним и автором блокировка
+// или закрытый аккаунт автора ему не виден — sql.ErrNoRows
+func (r *postRepo) GetByID(id, currentUserID int) (*model.Post, error) {
+	return scanPost(r.db.QueryRow(
+		selectPosts("$2")+` WHERE p.id = $1 AND `+notBlocked("p.user_id", "$2")+` AND `+visibleTo("u", "$2"),
+		id, currentUserID,
+	))
+}
 
-	return post, nil
+// GetByIDUnfiltered возвращает пост без проверок блокировок и приватности — для
+// служебных операций: модерации, счётчиков, снятия своих лайков и репостов
+func (r *postRepo) GetByIDUnfiltered(id int) (*model.Post, error) {
+	return scanPost(r.db.QueryRow(selectPosts("0")+` WHERE p.id = $1`, id))
 }
 
-func (r *postRepo) GetByID(id, currentUserID int) (*model.Post, error) {
-	post := &model.Post{}
-	err := r.db.QueryRow(
-		`SELECT p.id, p.user_id, u.username, u.avatar_url, p.content, p.image_url,
-			(SELECT COUNT(*) FROM likes WHERE post_id = p.id) as likes_count,
-			EXISTS(SELECT 1 FROM likes WHERE post_id = p.id AND user_id = $2) as is_liked,
-			p.created_at, p.updated_at
-		 FROM posts p
-		 JOIN users u ON p.user_id = u.id
-		 WHERE p.id = $1`, id, currentUserID,
-	).Scan(&post.ID, &post.UserID, &post.Username, &post.AvatarURL, &post.Content, &post.ImageURL,
-		&post.LikesCount, &post.IsLiked, &post.CreatedAt, &post.UpdatedAt)
+// Update сохраняет текущую версию поста в post_revisions, заменяет текст, хештеги и упоминания.
+// Строка поста блокируется, чтобы параллельные правки не потеряли версию
+func (r *postRepo) Update(id int, draft *model.PostDraft) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`INSERT INTO post_revisions (post_id, content, image_url, created_at)
+		 SELECT id, content, image_url, COALESCE(edited_at, created_at)
+		 FROM posts WHERE id = $1
+		 FOR UPDATE`, id,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE posts SET content = $1, edited_at = NOW(), updated_at = NOW() WHERE id = $2`, draft.Content, id,
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM post_hashtags WHERE post_id = $1`, id); err != nil {
+		return err
+	}
+	if err := insertHashtags(tx, id, draft.Hashtags); err != nil {
+		return err
+	}
+	if err := replaceMentions(tx, id, nil, draft.Mentions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertHashtags сохраняет теги поста; время берётся у поста, а не у правки
+func insertHashtags(tx *sql.Tx, postID int, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(
+		`INSERT INTO post_hashtags (post_id, tag, created_at)
+		 SELECT p.id, tag, p.created_at FROM posts p, unnest($2::text[]) AS tag
+		 WHERE p.id = $1
+		 ON CONFLICT DO NOTHING`, postID, pq.Array(tags),
+	)
+	return err
+}
+
+func (r *postRepo) GetRevisions(postID int) ([]*model.PostRevision, error) {
+	rows, err := r.db.Query(
+		`SELECT id, post_id, content, image_url, created_at, replaced_at
+		 FROM post_revisions WHERE post_id = $1
+		 ORDER BY replaced_at DESC, id DESC`, postID,
+	)
 	if err != nil {
 		return nil, err
 	}
-	return post, nil
+	defer rows.Close()
+
+	revisions := []*model.PostRevision{}
+	for rows.Next() {
+		rev := &model.PostRevision{}
+		if err := rows.Scan(&rev.ID, &rev.PostID, &rev.Content, &rev.ImageURL, &rev.CreatedAt, &rev.ReplacedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
 }
 
 func (r *postRepo) Delete(id int) error {
-	_, err := r.db.Exec(`DELETE FROM posts WHERE id = $1`, id)
-	return err
+	return inTx(r.db, func(tx *sql.Tx) error {
+		var userID int
+		err := tx.QueryRow(`DELETE FROM posts WHERE id = $1 RETURNING user_id`, id).Scan(&userID)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE users SET posts_count = posts_count - 1 WHERE id = $1`, userID)
+		return err
+	})
 }
 
-func (r *postRepo) GetFeed(currentUserID, limit, offset int) ([]*model.Post, error) {
+// GetFeed возвращает все посты, новые первыми, кроме заблокированных и скрытых currentUserID авторов
+// и закрытых аккаунтов, на которые он не подписан
+func (r *postRepo) GetFeed(currentUserID int, page model.PageRequest) (*model.Page[*model.Post], error) {
+	afterAt, afterID := afterArgs(page.After)
 	rows, err := r.db.Query(
-		`SELECT p.id, p.user_id, u.username, u.avatar_url, p.content, p.image_url,
-			(SELECT COUNT(*) FROM likes WHERE post_id = p.id) as likes_count,
-			EXISTS(SELECT 1 FROM likes WHERE post_id = p.id AND user_id = $1) as is_liked,
-			p.created_at, p.updated_at
-		 FROM posts p
-		 JOIN users u ON p.user_id = u.id
-		 ORDER BY p.created_at DESC
-		 LIMIT $2 OFFSET $3`, currentUserID, limit, offset,
+		selectPosts("$1")+`
+		 WHERE `+notBlocked("p.user_id", "$1")+` AND `+notMuted("p.user_id", "$1")+` AND `+visibleTo("u", "$1")+`
+		   AND ($2::timestamp IS NULL OR (p.created_at, p.id) < ($2::timestamp, $3::int))
+		 ORDER BY p.created_at DESC, p.id DESC
+		 LIMIT $4 OFFSET $5`, currentUserID, afterAt, afterID, page.Limit+1, page.Offset,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return scanPosts(rows)
+	return pageOfPosts(rows, page.Limit)
 }
 
-func (r *postRepo) GetFollowingFeed(userID, limit, offset int) ([]*model.Post, error) {
+// GetByHashtag возвращает посты с тегом tag, новые первыми, кроме заблокированных, скрытых
+// и недоступных закрытых авторов
+func (r *postRepo) GetByHashtag(tag string, currentUserID int, page model.PageRequest) (*model.Page[*model.Post], error) {
+	afterAt, afterID := afterArgs(page.After)
 	rows, err := r.db.Query(
-		`SELECT p.id, p.user_id, u.username, u.avatar_url, p.content, p.image_url,
-			(SELECT COUNT(*) FROM likes WHERE post_id = p.id) as likes_count,
-			EXISTS(SELECT 1 FROM likes WHERE post_id = p.id AND user_id = $1) as is_liked,
-			p.created_at, p.updated_at
-		 FROM posts p
-		 JOIN users u ON p.user_id = u.id
-		 WHERE p.user_id IN (SELECT following_id FROM follows WHERE follower_id = $1)
-		 ORDER BY p.created_at DESC
-		 LIMIT $2 OFFSET $3`, userID, limit, offset,
+		selectPosts("$2")+`
+		 JOIN post_hashtags h ON h.post_id = p.id
+		 WHERE h.tag = $1 AND `+notBlocked("p.user_id", "$2")+` AND `+notMuted("p.user_id", "$2")+`
+		   AND `+visibleTo("u", "$2")+`
+		   AND ($3::timestamp IS NULL OR (h.created_at, h.post_id) < ($3::timestamp, $4::int))
+		 ORDER BY h.created_at DESC, h.post_id DESC
+		 LIMIT $5 OFFSET $6`, tag, currentUserID, afterAt, afterID, page.Limit+1, page.Offset,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return scanPosts(rows)
+	return pageOfPosts(rows, page.Limit)
 }
 
-func (r *postRepo) GetByUserID(userID, currentUserID, limit, offset int) ([]*model.Post, error) {
+// GetFollowingFeed собирает посты и репосты подписок. Пост, которым поделились
+// несколько раз, показывается один раз — по последнему событию, с последним репостнувшим.
+// Репосты скрытых пользователей и посты скрытых, заблокированных или недоступных закрытых
+// авторов пропускаются
+func (r *postRepo) GetFollowingFeed(userID int, page model.PageRequest) (*model.Page[*model.Post], error) {
+	afterAt, afterID := afterArgs(page.After)
 	rows, err := r.db.Query(
-		`SELECT p.id, p.user_id, u.username, u.avatar_url, p.content, p.image_url,
-			(SELECT COUNT(*) FROM likes WHERE post_id = p.id) as likes_count,
-			EXISTS(SELECT 1 FROM likes WHERE post_id = p.id AND user_id = $2) as is_liked,
-			p.created_at, p.updated_at
-		 FROM posts p
-		 JOIN users u ON p.user_id = u.id
-		 WHERE p.user_id = $1
-		 ORDER BY p.created_at DESC
-		 LIMIT $3 OFFSET $4`, userID, currentUserID, limit, offset,
+		`WITH entries AS (
+			SELECT id AS post_id, created_at AS at, NULL::int AS reposter_id
+			FROM posts
+			WHERE user_id IN (SELECT following_id FROM follows WHERE follower_id = $1)
+			UNION ALL
+			SELECT post_id, created_at, user_id
+			FROM reposts
+			WHERE user_id IN (SELECT following_id FROM follows WHERE follower_id = $1)
+			  AND `+notMuted("user_id", "$1")+`
+		 ), latest AS (
+			SELECT DISTINCT ON (post_id) post_id, at, reposter_id
+			FROM entries
+			ORDER BY post_id, at DESC
+		 )
+		 SELECT `+postColumns("$1")+`, e.at, ru.id, ru.username
+		 FROM latest e
+		 JOIN posts p ON p.id = e.post_id`+postJoins("$1")+`
+		 LEFT JOIN users ru ON ru.id = e.reposter_id
+		 WHERE `+notBlocked("p.user_id", "$1")+` AND `+notMuted("p.user_id", "$1")+` AND `+visibleTo("u", "$1")+`
+		   AND ($2::timestamp IS NULL OR (e.at, p.id) < ($2::timestamp, $3::int))
+		 ORDER BY e.at DESC, p.id DESC
+		 LIMIT $4 OFFSET $5`, userID, afterAt, afterID, page.Limit+1, page.Offset,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return scanPosts(rows)
+	posts := []*model.Post{}
+	for rows.Next() {
+		var at time.Time
+		var reposterID sql.NullInt64
+		var reposterName sql.NullString
+		post, err := scanPost(rows, &at, &reposterID, &reposterName)
+		if err != nil {
+			return nil, err
+		}
+		if reposterID.Valid {
+			post.RepostedBy = &model.RepostInfo{UserID: int(reposterID.Int64), Username: reposterName.String, RepostedAt: at}
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pageOf(posts, page.Limit, func(i int) model.Cursor {
+		return postCursor(posts[i])
+	}), nil
+}
+
+// GetByUserID возвращает посты автора; при блокировке между ним и currentUserID или если
+// закрытый аккаунт ему не виден — пустую страницу
+func (r *postRepo) GetByUserID(userID, currentUserID int, page model.PageRequest) (*model.Page[*model.Post], error) {
+	afterAt, afterID := afterArgs(page.After)
+	rows, err := r.db.Query(
+		selectPosts("$2")+`
+		 WHERE p.user_id = $1 AND `+notBlocked("p.user_id", "$2")+` AND `+visibleTo("u", "$2")+`
+		   AND ($3::timestamp IS NULL OR (p.created_at, p.id) < ($3::timestamp, $4::int))
+		 ORDER BY p.created_at DESC, p.id DESC
+		 LIMIT $5 OFFSET $6`, userID, currentUserID, afterAt, afterID, page.Limit+1, page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return pageOfPosts(rows, page.Limit)
+}
+
+// pageOfPosts сканирует выборку постов в страницу с курсором по (created_at, id)
+func pageOfPosts(rows *sql.Rows, limit int) (*model.Page[*model.Post], error) {
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return pageOf(posts, limit, func(i int) model.Cursor {
+		return postCursor(posts[i])
+	}), nil
+}
+
+// postCursor — позиция поста в ленте: время репоста, если он попал в ленту репостом
+func postCursor(post *model.Post) model.Cursor {
+	if post.RepostedBy != nil {
+		return model.Cursor{CreatedAt: post.RepostedBy.RepostedAt, ID: post.ID}
+	}
+	return model.Cursor{CreatedAt: post.CreatedAt, ID: post.ID}
+}
+
+// scanPost сканирует строку с колонками selectPosts
+// extra — дополнительные колонки запроса после колонок поста
+func scanPost(row rowScanner, extra ...any) (*model.Post, error) {
+	post := &model.Post{}
+	var (
+		quoteID, quoteUserID                                 sql.NullInt64
+		quoteUsername, quoteAvatar, quoteContent, quoteImage sql.NullString
+		quoteCreatedAt                                       sql.NullTime
+	)
+	dest := []any{&post.ID, &post.UserID, &post.Username, &post.AvatarURL,
+		&post.Content, (*jsonMentions)(&post.Mentions), &post.ImageURL, &post.LikesCount, &post.CommentsCount, &post.RepostsCount,
+		&post.IsLiked, &post.IsReposted,
+		&post.IsQuote, &quoteID, &quoteUserID, &quoteUsername, &quoteAvatar, &quoteContent, &quoteImage, &quoteCreatedAt,
+		&post.CreatedAt, &post.UpdatedAt, &post.EditedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	// Цитируемый пост — только превью; nil, если его удалили
+	if quoteID.Valid {
+		post.Quote = &model.Post{
+			ID:        int(quoteID.Int64),
+			UserID:    int(quoteUserID.Int64),
+			Username:  quoteUsername.String,
+			AvatarURL: quoteAvatar.String,
+			Content:   quoteContent.String,
+			ImageURL:  quoteImage.String,
+			CreatedAt: quoteCreatedAt.Time,
+		}
+	}
+	return post, nil
 }
 
 // scanPosts сканирует строки результата в слайс постов
 func scanPosts(rows *sql.Rows) ([]*model.Post, error) {
-	var posts []*model.Post
+	posts := []*model.Post{}
 	for rows.Next() {
-		post := &model.Post{}
-		err := rows.Scan(&post.ID, &post.UserID, &post.Username, &post.AvatarURL,
-			&post.Content, &post.ImageURL, &post.LikesCount, &post.IsLiked, &post.CreatedAt, &post.UpdatedAt)
+		post, err := scanPost(rows)
 		if err != nil {
 			return nil, err
 		}
 		posts = append(posts, post)
 	}
-	if posts == nil {
-		posts = []*model.Post{}
-	}
 	return posts, rows.Err()
 }
@@ -0,0 +1,50 @@
+package repository
+
+import "database/sql"
+
+// repostRepo — реализация RepostRepository для PostgreSQL
+type repostRepo struct {
+	db *sql.DB
+}
+
+// NewRepostRepo создаёт новый репозиторий репостов
+func NewRepostRepo(db *sql.DB) RepostRepository {
+	return &repostRepo{db: db}
+}
+
+func (r *repostRepo) Repost(userID, postID int) error {
+	return inTx(r.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(
+			`INSERT INTO reposts (user_id, post_id)
+			 VALUES ($1, $2)
+			 ON CONFLICT DO NOTHING`,
+			userID, postID,
+		)
+		if err != nil {
+			return err
+		}
+		return adjustReposts(tx, res, postID, 1)
+	})
+}
+
+func (r *repostRepo) Unrepost(userID, postID int) error {
+	return inTx(r.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(
+			`DELETE FROM reposts WHERE user_id = $1 AND post_id = $2`,
+			userID, postID,
+		)
+		if err != nil {
+			return err
+		}
+		return adjustReposts(tx, res, postID, -1)
+	})
+}
+
+// adjustReposts меняет reposts_count, только если репост действительно добавился или удалился
+func adjustReposts(tx *sql.Tx, res sql.Result, postID, delta int) error {
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	_, err := tx.Exec(`UPDATE posts SET reposts_count = reposts_count + $1 WHERE id = $2`, delta, postID)
+	return err
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+
+	"social-network/internal/model"
+)
+
+// headlineOptions — разметка совпадений во фрагментах поиска
+const headlineOptions = `'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2'`
+
+// escapedHTML экранирует текст колонки до ts_headline: во фрагмент попадает пользовательский
+// текст, а клиенту отдаётся готовый HTML, где разметкой может быть только <mark>
+func escapedHTML(column string) string {
+	return `replace(replace(replace(replace(` + column + `, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;')`
+}
+
+// likeEscaper экранирует спецсимволы LIKE в пользовательском вводе
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// searchRepo — реализация SearchRepository для PostgreSQL
+type searchRepo struct {
+	db *sql.DB
+}
+
+// NewSearchRepo создаёт новый репозиторий поиска
+func NewSearchRepo(db *sql.DB) SearchRepository {
+	return &searchRepo{db: db}
+}
+
+// SearchPosts ищет посты по tsvector; запрос в синтаксисе websearch_to_tsquery
+// ("фраза в кавычках", -исключение, or). Ранг — ts_rank. Посты тех, с кем
+// у currentUserID блокировка, и недоступных ему закрытых аккаунтов не ищутся
+func (r *searchRepo) SearchPosts(req model.SearchRequest, currentUserID int) (*model.Page[*model.PostSearchResult], error) {
+	afterRank, afterID := rankAfterArgs(req.After)
+	rows, err := r.db.Query(
+		`WITH q AS (SELECT websearch_to_tsquery('russian', $1) AS query)
+		 SELECT `+postColumns("$2")+`,
+		        ts_headline('russian', `+escapedHTML("p.content")+`, q.query, `+headlineOptions+`),
+		        ts_rank(p.search_vector, q.query) AS rank
+		 FROM q, posts p`+postJoins("$2")+`
+		 WHERE p.search_vector @@ q.query AND `+notBlocked("p.user_id", "$2")+` AND `+visibleTo("u", "$2")+`
+		   AND ($3::real IS NULL OR (ts_rank(p.search_vector, q.query), p.id) < ($3::real, $4::int))
+		 ORDER BY rank DESC, p.id DESC
+		 LIMIT $5`, req.Query, currentUserID, afterRank, afterID, req.Limit+1,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*model.PostSearchResult{}
+	for rows.Next() {
+		res := &model.PostSearchResult{}
+		post, err := scanPost(rows, &res.Snippet, &res.Rank)
+		if err != nil {
+			return nil, err
+		}
+		res.Post = post
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rankPageOf(results, req.Limit, func(i int) model.RankCursor {
+		return model.RankCursor{Rank: results[i].Rank, ID: results[i].ID}
+	}), nil
+}
+
+// SearchUsers ищет пользователей по триграммам имени и bio; вхождение подстроки
+// в имя поднимает пользователя выше. Заблокированные аккаунты и те, с кем у
+// currentUserID блокировка, не ищутся
+func (r *searchRepo) SearchUsers(req model.SearchRequest, currentUserID int) (*model.Page[*model.UserSearchResult], error) {
+	afterRank, afterID := rankAfterArgs(req.After)
+	rows, err := r.db.Query(
+		`SELECT id, username, avatar_url, bio, snippet, rank FROM (
+			SELECT u.id, u.username, u.avatar_url, COALESCE(u.bio, '') AS bio,
+			       ts_headline('simple', `+escapedHTML("COALESCE(u.bio, '')")+`, plainto_tsquery('simple', $1), `+headlineOptions+`) AS snippet,
+			       GREATEST(
+			           similarity(u.username, $1),
+			           word_similarity($1, COALESCE(u.bio, '')),
+			           CASE WHEN u.username ILIKE $2 THEN 0.5 ELSE 0 END
+			       )::real AS rank
+			FROM users u
+			WHERE u.suspended_at IS NULL AND `+notBlocked("u.id", "$6")+`
+			  AND (u.username ILIKE $2 OR u.username % $1 OR $1 <% u.bio)
+		 ) s
+		 WHERE ($3::real IS NULL OR (rank, id) < ($3::real, $4::int))
+		 ORDER BY rank DESC, id DESC
+		 LIMIT $5`, req.Query, "%"+likeEscaper.Replace(req.Query)+"%", afterRank, afterID, req.Limit+1, currentUserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*model.UserSearchResult{}
+	for rows.Next() {
+		u := &model.UserSearchResult{}
+		if err := rows.Scan(&u.ID, &u.Username, &u.AvatarURL, &u.Bio, &u.Snippet, &u.Rank); err != nil {
+			return nil, err
+		}
+		results = append(results, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rankPageOf(results, req.Limit, func(i int) model.RankCursor {
+		return model.RankCursor{Rank: results[i].Rank, ID: results[i].ID}
+	}), nil
+}
+
+// rankAfterArgs раскладывает курсор поиска в параметры запроса; без курсора оба NULL
+func rankAfterArgs(after *model.RankCursor) (any, any) {
+	if after == nil {
+		return nil, nil
+	}
+	return after.Rank, after.ID
+}
+
+// rankPageOf — как pageOf, но с курсором по рангу
+func rankPageOf[T any](items []T, limit int, key func(i int) model.RankCursor) *model.Page[T] {
+	page := &model.Page[T]{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = key(limit - 1).Encode()
+	}
+	return page
+}
@@ -16,10 +16,12 @@ func NewTokenRepo(db *sql.DB) TokenRepository {
 	return &tokenRepo{db: db}
 }
 
-func (r *tokenRepo) Create(userID int, tokenHash string, expiresAt any) error {
+func (r *tokenRepo) Create(token *model.RefreshToken) error {
 	_, err := r.db.Exec(
-		`INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
-		userID, tokenHash, expiresAt,
+		`INSERT INTO refresh_tokens (user_id, family_id, token_hash, user_agent, ip_address, session_started_at, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		token.UserID, token.FamilyID, token.TokenHash, token.UserAgent, token.IPAddress,
+		token.SessionStartedAt, token.ExpiresAt,
 	)
 	return err
 }
@@ -27,21 +29,77 @@ func (r *tokenRepo) Create(userID int, tokenHash string, expiresAt any) error {
 func (r *tokenRepo) GetByHash(tokenHash string) (*model.RefreshToken, error) {
 	token := &model.RefreshToken{}
 	err := r.db.QueryRow(
-		`SELECT id, user_id, token_hash, expires_at, created_at
+		`SELECT id, user_id, family_id, token_hash, user_agent, ip_address, session_started_at,
+			expires_at, rotated_at, created_at
 		 FROM refresh_tokens WHERE token_hash = $1`, tokenHash,
-	).Scan(&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &token.CreatedAt)
+	).Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.UserAgent, &token.IPAddress,
+		&token.SessionStartedAt, &token.ExpiresAt, &token.RotatedAt, &token.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return token, nil
 }
 
+// GetSessions возвращает активные сессии: по одному живому токену на семейство
+func (r *tokenRepo) GetSessions(userID int) ([]*model.Session, error) {
+	rows, err := r.db.Query(
+		`SELECT family_id, user_agent, ip_address, session_started_at, created_at, expires_at
+		 FROM refresh_tokens
+		 WHERE user_id = $1 AND rotated_at IS NULL AND expires_at > NOW()
+		 ORDER BY created_at DESC`, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*model.Session
+	for rows.Next() {
+		s := &model.Session{}
+		err := rows.Scan(&s.ID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	if sessions == nil {
+		sessions = []*model.Session{}
+	}
+	return sessions, rows.Err()
+}
+
+// MarkRotated помечает токен обменянным. Возвращает false, если его уже обменяли —
+// так два параллельных refresh с одним токеном не получат две живые ветки
+func (r *tokenRepo) MarkRotated(tokenHash string) (bool, error) {
+	res, err := r.db.Exec(
+		`UPDATE refresh_tokens SET rotated_at = NOW()
+		 WHERE token_hash = $1 AND rotated_at IS NULL`, tokenHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
 func (r *tokenRepo) DeleteByHash(tokenHash string) error {
 	_, err := r.db.Exec(`DELETE FROM refresh_tokens WHERE token_hash = $1`, tokenHash)
 	return err
 }
 
+func (r *tokenRepo) DeleteByFamily(familyID string) error {
+	_, err := r.db.Exec(`DELETE FROM refresh_tokens WHERE family_id = $1`, familyID)
+	return err
+}
+
 func (r *tokenRepo) DeleteByUserID(userID int) error {
 	_, err := r.db.Exec(`DELETE FROM refresh_tokens WHERE user_id = $1`, userID)
 	return err
 }
+
+func (r *tokenRepo) DeleteByUserIDExcept(userID int, familyID string) error {
+	_, err := r.db.Exec(
+		`DELETE FROM refresh_tokens WHERE user_id = $1 AND family_id <> $2`, userID, familyID,
+	)
+	return err
+}
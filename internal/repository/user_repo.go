@@ -2,10 +2,15 @@ package repository
 
 import (
 	"database/sql"
+	"time"
 
 	"social-network/internal/model"
 )
 
+// userColumns — колонки users в порядке, ожидаемом scanUser
+const userColumns = `id, username, email, password_hash, bio, avatar_url, email_verified_at,
+	role, suspended_at, messages_following_only, is_private, created_at, updated_at`
+
 // userRepo — реализация UserRepository для PostgreSQL
 type userRepo struct {
 	db *sql.DB
@@ -17,53 +22,30 @@ func NewUserRepo(db *sql.DB) UserRepository {
 }
 
 func (r *userRepo) Create(username, email, passwordHash string) (*model.User, error) {
-	user := &model.User{}
-	err := r.db.QueryRow(
+	return scanUser(r.db.QueryRow(
 		`INSERT INTO users (username, email, password_hash)
 		 VALUES ($1, $2, $3)
-		 RETURNING id, username, email, bio, avatar_url, created_at, updated_at`,
+		 RETURNING `+userColumns,
 		username, email, passwordHash,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.Bio, &user.AvatarURL, &user.CreatedAt, &user.UpdatedAt)
-	if err != nil {
-		return nil, err
-	}
-	return user, nil
+	))
 }
 
 func (r *userRepo) GetByID(id int) (*model.User, error) {
-	user := &model.User{}
-	err := r.db.QueryRow(
-		`SELECT id, username, email, password_hash, bio, avatar_url, created_at, updated_at
-		 FROM users WHERE id = $1`, id,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Bio, &user.AvatarURL, &user.CreatedAt, &user.UpdatedAt)
-	if err != nil {
-		return nil, err
-	}
-	return user, nil
+	return scanUser(r.db.QueryRow(
+		`SELECT `+userColumns+` FROM users WHERE id = $1`, id,
+	))
 }
 
 func (r *userRepo) GetByEmail(email string) (*model.User, error) {
-	user := &model.User{}
-	err := r.db.QueryRow(
-		`SELECT id, username, email, password_hash, bio, avatar_url, created_at, updated_at
-		 FROM users WHERE email = $1`, email,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Bio, &user.AvatarURL, &user.CreatedAt, &user.UpdatedAt)
-	if err != nil {
-		return nil, err
-	}
-	return user, nil
+	return scanUser(r.db.QueryRow(
+		`SELECT `+userColumns+` FROM users WHERE email = $1`, email,
+	))
 }
 
 func (r *userRepo) GetByUsername(username string) (*model.User, error) {
-	user := &model.User{}
-	err := r.db.QueryRow(
-		`SELECT id, username, email, password_hash, bio, avatar_url, created_at, updated_at
-		 FROM users WHERE username = $1`, username,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Bio, &user.AvatarURL, &user.CreatedAt, &user.UpdatedAt)
-	if err != nil {
-		return nil, err
-	}
-	return user, nil
+	return scanUser(r.db.QueryRow(
+		`SELECT `+userColumns+` FROM users WHERE username = $1`, username,
+	))
 }
 
 func (r *userRepo) UpdateBio(id int, bio string) error {
@@ -73,6 +55,20 @@ func (r *userRepo) UpdateBio(id int, bio string) error {
 	return err
 }
 
+func (r *userRepo) SetMessagesFollowingOnly(id int, enabled bool) error {
+	_, err := r.db.Exec(
+		`UPDATE users SET messages_following_only = $1, updated_at = NOW() WHERE id = $2`, enabled, id,
+	)
+	return err
+}
+
+func (r *userRepo) SetPrivate(id int, private bool) error {
+	_, err := r.db.Exec(
+		`UPDATE users SET is_private = $1, updated_at = NOW() WHERE id = $2`, private, id,
+	)
+	return err
+}
+
 func (r *userRepo) UpdateAvatar(id int, avatarURL string) error {
 	_, err := r.db.Exec(
 		`UPDATE users SET avatar_url = $1, updated_at = NOW() WHERE id = $2`, avatarURL, id,
@@ -80,21 +76,68 @@ func (r *userRepo) UpdateAvatar(id int, avatarURL string) error {
 	return err
 }
 
+func (r *userRepo) UpdatePassword(id int, passwordHash string) error {
+	_, err := r.db.Exec(
+		`UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2`, passwordHash, id,
+	)
+	return err
+}
+
+func (r *userRepo) MarkEmailVerified(id int) error {
+	_, err := r.db.Exec(
+		`UPDATE users SET email_verified_at = NOW(), updated_at = NOW()
+		 WHERE id = $1 AND email_verified_at IS NULL`, id,
+	)
+	return err
+}
+
+func (r *userRepo) SetRole(id int, role string) error {
+	_, err := r.db.Exec(`UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2`, role, id)
+	return err
+}
+
+// SetSuspended блокирует аккаунт с момента at или снимает блокировку при at == nil
+func (r *userRepo) SetSuspended(id int, at *time.Time) error {
+	_, err := r.db.Exec(`UPDATE users SET suspended_at = $1, updated_at = NOW() WHERE id = $2`, at, id)
+	return err
+}
+
+// GetProfile возвращает профиль глазами currentUserID; при блокировке между ними — sql.ErrNoRows
 func (r *userRepo) GetProfile(id, currentUserID int) (*model.UserProfile, error) {
 	profile := &model.UserProfile{}
 	err := r.db.QueryRow(
-		`SELECT u.id, u.username, u.email, u.bio, u.avatar_url, u.created_at, u.updated_at,
-			(SELECT COUNT(*) FROM follows WHERE following_id = u.id) as followers_count,
-			(SELECT COUNT(*) FROM follows WHERE follower_id = u.id) as following_count,
-			EXISTS(SELECT 1 FROM follows WHERE follower_id = $2 AND following_id = u.id) as is_following
-		 FROM users u WHERE u.id = $1`, id, currentUserID,
+		`SELECT u.id, u.username, u.email, u.bio, u.avatar_url, u.email_verified_at, u.role, u.suspended_at,
+			u.messages_following_only, u.is_private, u.created_at, u.updated_at,
+			u.followers_count, u.following_count, u.posts_count,
+			EXISTS(SELECT 1 FROM follows WHERE follower_id = $2 AND following_id = u.id) as is_following,
+			NOT `+notMuted("u.id", "$2")+` as is_muted,
+			EXISTS(SELECT 1 FROM follow_requests WHERE follower_id = $2 AND following_id = u.id) as is_requested
+		 FROM users u WHERE u.id = $1 AND `+notBlocked("u.id", "$2"), id, currentUserID,
 	).Scan(
 		&profile.ID, &profile.Username, &profile.Email, &profile.Bio,
-		&profile.AvatarURL, &profile.CreatedAt, &profile.UpdatedAt,
-		&profile.FollowersCount, &profile.FollowingCount, &profile.IsFollowing,
+		&profile.AvatarURL, &profile.EmailVerifiedAt, &profile.Role, &profile.SuspendedAt,
+		&profile.MessagesFollowingOnly, &profile.IsPrivate, &profile.CreatedAt, &profile.UpdatedAt,
+		&profile.FollowersCount, &profile.FollowingCount, &profile.PostsCount, &profile.IsFollowing, &profile.IsMuted,
+		&profile.IsRequested,
 	)
 	if err != nil {
 		return nil, err
 	}
 	return profile, nil
 }
+
+// rowScanner — общий интерфейс *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanUser сканирует строку с колонками userColumns
+func scanUser(row rowScanner) (*model.User, error) {
+	user := &model.User{}
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Bio, &user.AvatarURL,
+		&user.EmailVerifiedAt, &user.Role, &user.SuspendedAt, &user.MessagesFollowingOnly, &user.IsPrivate, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
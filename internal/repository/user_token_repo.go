@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"social-network/internal/model"
+)
+
+// userTokenRepo — реализация UserTokenRepository для PostgreSQL
+type userTokenRepo struct {
+	db *sql.DB
+}
+
+// NewUserTokenRepo создаёт новый репозиторий одноразовых токенов
+func NewUserTokenRepo(db *sql.DB) UserTokenRepository {
+	return &userTokenRepo{db: db}
+}
+
+func (r *userTokenRepo) Create(userID int, purpose, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.Exec(
+		`INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at) VALUES ($1, $2, $3, $4)`,
+		userID, purpose, tokenHash, expiresAt,
+	)
+	return err
+}
+
+// Consume атомарно помечает токен использованным. Возвращает sql.ErrNoRows,
+// если токена нет, он уже использован или истёк
+func (r *userTokenRepo) Consume(purpose, tokenHash string) (*model.UserToken, error) {
+	token := &model.UserToken{}
+	err := r.db.QueryRow(
+		`UPDATE user_tokens SET used_at = NOW()
+		 WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		 RETURNING id, user_id, purpose, token_hash, expires_at, used_at, created_at`,
+		tokenHash, purpose,
+	).Scan(&token.ID, &token.UserID, &token.Purpose, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *userTokenRepo) DeleteByUserID(userID int, purpose string) error {
+	_, err := r.db.Exec(`DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2`, userID, purpose)
+	return err
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"social-network/internal/model"
+)
+
+// AccessTokenPrefix отличает персональные токены от JWT в заголовке Authorization
+const AccessTokenPrefix = "snp_"
+
+// maxAccessTokenTTL — наибольший срок действия персонального токена
+const maxAccessTokenTTL = 365 * 24 * time.Hour
+
+// accessTokenTouchInterval — как часто обновлять last_used_at при активном использовании
+const accessTokenTouchInterval = time.Minute
+
+var (
+	ErrInvalidScope        = errors.New("неизвестная область доступа")
+	ErrAccessTokenNotFound = errors.New("токен не найден")
+)
+
+// CreateAccessToken выпускает персональный токен. ttl == 0 — бессрочный токен
+func (s *AuthService) CreateAccessToken(userID int, name string, scopes []string, ttl time.Duration) (*model.CreatedAccessToken, error) {
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(model.Scopes, scope) {
+			return nil, ErrInvalidScope
+		}
+	}
+	scopes = slices.Compact(slices.Sorted(slices.Values(scopes)))
+
+	raw := AccessTokenPrefix + generateSecret()
+	token := &model.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(raw),
+		Scopes:    scopes,
+	}
+	if ttl > 0 {
+		expiresAt := s.now().Add(min(ttl, maxAccessTokenTTL)).UTC()
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.accessTokenRepo.Create(token); err != nil {
+		return nil, err
+	}
+	return &model.CreatedAccessToken{PersonalAccessToken: token, Token: raw}, nil
+}
+
+// ListAccessTokens возвращает персональные токены пользователя (без секретов)
+func (s *AuthService) ListAccessTokens(userID int) ([]*model.PersonalAccessToken, error) {
+	return s.accessTokenRepo.GetByUserID(userID)
+}
+
+// RevokeAccessToken удаляет персональный токен пользователя
+func (s *AuthService) RevokeAccessToken(userID, tokenID int) error {
+	deleted, err := s.accessTokenRepo.Delete(tokenID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// parseAccessToken проверяет персональный токен и возвращает его области доступа
+func (s *AuthService) parseAccessToken(raw string) (*TokenClaims, error) {
+	token, err := s.accessTokenRepo.GetByHash(hashToken(raw))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	now := s.now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	// Не пишем в БД на каждый запрос бота — достаточно точности до минуты
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= accessTokenTouchInterval {
+		if err := s.accessTokenRepo.TouchLastUsed(token.ID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	claims := &TokenClaims{UserID: token.UserID, Scopes: token.Scopes}
+	if token.ExpiresAt != nil {
+		claims.ExpiresAt = *token.ExpiresAt
+	}
+	return claims, nil
+}
+
+// isAccessToken сообщает, похожа ли строка на персональный токен
+func isAccessToken(raw string) bool {
+	return strings.HasPrefix(raw, AccessTokenPrefix)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"social-network/internal/model"
+	"social-network/internal/repository"
+)
+
+var (
+	ErrInsufficientRole = errors.New("недостаточно прав для действий над этим пользователем")
+	ErrInvalidRole      = errors.New("неизвестная роль")
+)
+
+// AdminService — действия модераторов и администраторов над аккаунтами
+type AdminService struct {
+	userRepo  repository.UserRepository
+	tokenRepo repository.TokenRepository
+	stream    *StreamService
+	now       func() time.Time
+}
+
+// NewAdminService создаёт сервис администрирования; now == nil означает time.Now
+func NewAdminService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, stream *StreamService, now func() time.Time) *AdminService {
+	if now == nil {
+		now = time.Now
+	}
+	return &AdminService{userRepo: userRepo, tokenRepo: tokenRepo, stream: stream, now: now}
+}
+
+// GetUser возвращает пользователя по ID со служебными полями
+func (s *AdminService) GetUser(id int) (*model.User, error) {
+	return lookupUser(s.userRepo.GetByID(id))
+}
+
+// FindUser ищет пользователя по email или username
+func (s *AdminService) FindUser(email, username string) (*model.User, error) {
+	if email != "" {
+		return lookupUser(s.userRepo.GetByEmail(email))
+	}
+	return lookupUser(s.userRepo.GetByUsername(username))
+}
+
+// Suspend блокирует аккаунт, завершает все его сессии и открытые потоки событий.
+// Выданные access-токены и персональные токены перестают работать сразу
+func (s *AdminService) Suspend(actorID int, actorRole string, targetID int) (*model.User, error) {
+	target, err := s.moderatable(actorID, actorRole, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if target.SuspendedAt != nil {
+		return target, nil
+	}
+
+	now := s.now()
+	if err := s.userRepo.SetSuspended(target.ID, &now); err != nil {
+		return nil, err
+	}
+	if err := s.tokenRepo.DeleteByUserID(target.ID); err != nil {
+		return nil, err
+	}
+	s.stream.AccountSuspended(target.ID)
+	return s.GetUser(target.ID)
+}
+
+// Unsuspend снимает блокировку аккаунта
+func (s *AdminService) Unsuspend(actorID int, actorRole string, targetID int) (*model.User, error) {
+	target, err := s.moderatable(actorID, actorRole, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetSuspended(target.ID, nil); err != nil {
+		return nil, err
+	}
+	return s.GetUser(target.ID)
+}
+
+// SetRole меняет роль пользователя. Новая роль попадает в токены при следующем обновлении
+func (s *AdminService) SetRole(actorID int, actorRole string, targetID int, role string) (*model.User, error) {
+	if !model.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	target, err := s.moderatable(actorID, actorRole, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetRole(target.ID, role); err != nil {
+		return nil, err
+	}
+	return s.GetUser(target.ID)
+}
+
+// moderatable возвращает пользователя, если актор может над ним действовать:
+// не над собой и только над теми, чья роль ниже его собственной
+func (s *AdminService) moderatable(actorID int, actorRole string, targetID int) (*model.User, error) {
+	target, err := s.GetUser(targetID)
+	if err != nil {
+		return nil, err
+	}
+	if target.ID == actorID || model.RoleAtLeast(target.Role, actorRole) {
+		return nil, ErrInsufficientRole
+	}
+	return target, nil
+}
+
+// lookupUser переводит sql.ErrNoRows в ErrUserNotFound
+func lookupUser(user *model.User, err error) (*model.User, error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
@@ -7,11 +7,15 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 
+	"social-network/internal/keys"
+	"social-network/internal/mailer"
 	"social-network/internal/model"
 	"social-network/internal/repository"
 )
@@ -21,26 +25,114 @@ var (
 	ErrUserExists         = errors.New("пользователь уже существует")
 	ErrInvalidToken       = errors.New("невалидный токен")
 	ErrTokenExpired       = errors.New("токен истёк")
+	ErrTokenReused        = errors.New("refresh-токен уже был использован")
+	ErrSessionNotFound    = errors.New("сессия не найдена")
+	ErrAccountSuspended   = errors.New("аккаунт заблокирован")
 )
 
+// TokenClaims — данные, извлечённые из access-токена
+type TokenClaims struct {
+	UserID    int
+	SessionID string // ID семейства refresh-токенов, в котором выдан токен
+
+	// Role — роль пользователя на момент выдачи токена; у персональных токенов пустая,
+	// поэтому им недоступны маршруты, требующие роли
+	Role string
+
+	// Scopes — области доступа персонального токена; nil у токенов сессии с полным доступом
+	Scopes []string
+
+	// ExpiresAt — когда токен перестанет действовать; нулевое у бессрочных персональных токенов
+	ExpiresAt time.Time
+}
+
+// IsAccessToken сообщает, что запрос выполнен персональным токеном, а не из сессии
+func (c *TokenClaims) IsAccessToken() bool {
+	return c.Scopes != nil
+}
+
+// HasScope проверяет, разрешена ли токену область доступа
+func (c *TokenClaims) HasScope(scope string) bool {
+	return !c.IsAccessToken() || slices.Contains(c.Scopes, scope)
+}
+
+// AuthConfig — настройки сервиса авторизации
+type AuthConfig struct {
+	JWTSecret string
+	AppURL    string // Базовый URL фронтенда для ссылок в письмах
+
+	// Keys — ключи подписи JWT; nil означает HS256 с общим JWTSecret
+	Keys *keys.Manager
+
+	// LoginThrottle — защита входа от перебора; нулевое значение означает DefaultLoginThrottle
+	LoginThrottle LoginThrottleConfig
+
+	// RequireVerifiedEmail запрещает публиковать посты и комментарии до подтверждения email
+	RequireVerifiedEmail bool
+
+	// Now — источник времени; nil означает time.Now. В тестах подменяется фейковыми часами
+	Now func() time.Time
+}
+
 // AuthService — сервис авторизации
 type AuthService struct {
-	userRepo  repository.UserRepository
-	tokenRepo repository.TokenRepository
-	jwtSecret []byte
+	userRepo         repository.UserRepository
+	tokenRepo        repository.TokenRepository
+	userTokenRepo    repository.UserTokenRepository
+	mfaRepo          repository.MFARepository
+	accessTokenRepo  repository.AccessTokenRepository
+	loginAttemptRepo repository.LoginAttemptRepository
+	mailer           mailer.Mailer
+	keys             *keys.Manager
+	appURL           string
+	now              func() time.Time
+	throttle         LoginThrottleConfig
+
+	requireVerifiedEmail bool
 }
 
 // NewAuthService создаёт сервис авторизации
-func NewAuthService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, jwtSecret string) *AuthService {
+func NewAuthService(
+	userRepo repository.UserRepository,
+	tokenRepo repository.TokenRepository,
+	userTokenRepo repository.UserTokenRepository,
+	mfaRepo repository.MFARepository,
+	accessTokenRepo repository.AccessTokenRepository,
+	loginAttemptRepo repository.LoginAttemptRepository,
+	mailer mailer.Mailer,
+	cfg AuthConfig,
+) *AuthService {
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+	signingKeys := cfg.Keys
+	if signingKeys == nil {
+		signingKeys = keys.NewHMACManager(cfg.JWTSecret)
+	}
+	throttle := cfg.LoginThrottle
+	if throttle == (LoginThrottleConfig{}) {
+		throttle = DefaultLoginThrottle()
+	}
 	return &AuthService{
-		userRepo:  userRepo,
-		tokenRepo: tokenRepo,
-		jwtSecret: []byte(jwtSecret),
+		userRepo:         userRepo,
+		tokenRepo:        tokenRepo,
+		userTokenRepo:    userTokenRepo,
+		mfaRepo:          mfaRepo,
+		accessTokenRepo:  accessTokenRepo,
+		loginAttemptRepo: loginAttemptRepo,
+		mailer:           mailer,
+		keys:             signingKeys,
+		appURL:           cfg.AppURL,
+		now:              now,
+		throttle:         throttle,
+
+		requireVerifiedEmail: cfg.RequireVerifiedEmail,
 	}
 }
 
 // Register регистрирует нового пользователя
-func (s *AuthService) Register(username, email, password string) (*model.User, *model.TokenPair, error) {
+func (s *AuthService) Register(username, email, password string, client model.ClientInfo) (*model.User, *model.TokenPair, error) {
 	// Проверяем, не занят ли email
 	_, err := s.userRepo.GetByEmail(email)
 	if err == nil {
@@ -71,8 +163,13 @@ func (s *AuthService) Register(username, email, password string) (*model.User, *
 		return nil, nil, err
 	}
 
-	// Генерируем токены
-	tokens, err := s.generateTokens(user.ID)
+	// Письмо подтверждения: сбой почты не должен ломать регистрацию
+	if err := s.sendVerification(user); err != nil {
+		log.Printf("Не удалось отправить письмо подтверждения user_id=%d: %v", user.ID, err)
+	}
+
+	// Генерируем токены — регистрация начинает новую сессию
+	tokens, err := s.startSession(user, client)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -80,31 +177,75 @@ func (s *AuthService) Register(username, email, password string) (*model.User, *
 	return user, tokens, nil
 }
 
-// Login аутентифицирует пользователя по email и паролю
-func (s *AuthService) Login(email, password string) (*model.User, *model.TokenPair, error) {
+// Login аутентифицирует пользователя по email и паролю.
+// Если у пользователя включена 2FA, вместо пары токенов возвращается MFA-челлендж.
+// После серии неудач возвращает *LoginThrottledError
+func (s *AuthService) Login(email, password string, client model.ClientInfo) (*model.LoginResult, error) {
+	keys := newLoginKeys(email, client.IPAddress)
+	if err := s.checkLoginAllowed(keys); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil, ErrInvalidCredentials
+			// Несуществующие email тоже считаем, чтобы по ответам нельзя было перебирать аккаунты
+			return nil, s.loginFailed(keys)
 		}
-		return nil, nil, err
+		return nil, err
 	}
 
 	// Сравниваем пароль с хешем
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return nil, nil, ErrInvalidCredentials
+		return nil, s.loginFailed(keys)
 	}
 
-	tokens, err := s.generateTokens(user.ID)
+	if err := s.resetLoginFailures(user.Email); err != nil {
+		return nil, err
+	}
+	return s.completeLogin(user, client)
+}
+
+// loginFailed учитывает неудачную попытку и возвращает ErrInvalidCredentials
+func (s *AuthService) loginFailed(keys loginKeys) error {
+	if err := s.recordLoginFailure(keys); err != nil {
+		return err
+	}
+	return ErrInvalidCredentials
+}
+
+// completeLogin завершает вход после проверки первого фактора
+func (s *AuthService) completeLogin(user *model.User, client model.ClientInfo) (*model.LoginResult, error) {
+	if user.SuspendedAt != nil {
+		return nil, ErrAccountSuspended
+	}
+
+	enabled, err := s.totpEnabled(user.ID)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	if enabled {
+		mfaToken, err := s.generateMFAToken(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &model.LoginResult{MFARequired: true, MFAToken: mfaToken}, nil
 	}
 
-	return user, tokens, nil
+	// Каждый вход начинает новую сессию — семейство refresh-токенов
+	tokens, err := s.startSession(user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.LoginResult{User: user, Tokens: tokens}, nil
 }
 
-// Refresh обновляет access-токен по refresh-токену
-func (s *AuthService) Refresh(refreshToken string) (*model.TokenPair, error) {
+// Refresh обновляет access-токен по refresh-токену.
+// Предъявленный токен помечается обменянным, новый выдаётся в том же семействе.
+// Повторное предъявление уже обменянного токена означает, что он утёк:
+// отзываем всё семейство, чтобы ветка злоумышленника тоже перестала работать.
+func (s *AuthService) Refresh(refreshToken string, client model.ClientInfo) (*model.TokenPair, error) {
 	// Хешируем токен для поиска в БД
 	hash := hashToken(refreshToken)
 
@@ -116,60 +257,186 @@ func (s *AuthService) Refresh(refreshToken string) (*model.TokenPair, error) {
 		return nil, err
 	}
 
+	if stored.RotatedAt != nil {
+		return nil, s.revokeFamily(stored)
+	}
+
 	// Проверяем срок действия
-	if time.Now().After(stored.ExpiresAt) {
+	if s.now().After(stored.ExpiresAt) {
 		s.tokenRepo.DeleteByHash(hash)
 		return nil, ErrTokenExpired
 	}
 
-	// Удаляем старый refresh-токен
-	s.tokenRepo.DeleteByHash(hash)
+	// Помечаем токен обменянным; если кто-то успел раньше — это тоже повтор
+	rotated, err := s.tokenRepo.MarkRotated(hash)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, s.revokeFamily(stored)
+	}
+
+	// Роль и блокировку перечитываем: изменения вступают в силу при следующем обновлении
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.SuspendedAt != nil {
+		return nil, ErrAccountSuspended
+	}
+
+	// Генерируем новую пару токенов в том же семействе
+	return s.generateTokens(user, stored.FamilyID, stored.SessionStartedAt, client)
+}
 
-	// Генерируем новую пару токенов
-	return s.generateTokens(stored.UserID)
+// revokeFamily отзывает все токены семейства после повторного использования
+func (s *AuthService) revokeFamily(stored *model.RefreshToken) error {
+	log.Printf("Повторное использование refresh-токена: user_id=%d, семейство %s отозвано",
+		stored.UserID, stored.FamilyID)
+	if err := s.tokenRepo.DeleteByFamily(stored.FamilyID); err != nil {
+		return err
+	}
+	return ErrTokenReused
 }
 
-// Logout инвалидирует refresh-токен
+// Logout завершает сессию: удаляет всё семейство предъявленного refresh-токена
 func (s *AuthService) Logout(refreshToken string) error {
 	hash := hashToken(refreshToken)
-	return s.tokenRepo.DeleteByHash(hash)
+	stored, err := s.tokenRepo.GetByHash(hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	return s.tokenRepo.DeleteByFamily(stored.FamilyID)
 }
 
-// ParseToken парсит и валидирует access JWT-токен, возвращает userID
-func (s *AuthService) ParseToken(tokenString string) (int, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
+// ListSessions возвращает активные сессии пользователя
+func (s *AuthService) ListSessions(userID int, currentSessionID string) ([]*model.Session, error) {
+	sessions, err := s.tokenRepo.GetSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.IsCurrent = session.ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession завершает одну сессию пользователя
+func (s *AuthService) RevokeSession(userID int, sessionID string) error {
+	sessions, err := s.tokenRepo.GetSessions(userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID == sessionID {
+			return s.tokenRepo.DeleteByFamily(sessionID)
 		}
-		return s.jwtSecret, nil
-	})
+	}
+	return ErrSessionNotFound
+}
+
+// RevokeOtherSessions завершает все сессии пользователя, кроме текущей
+func (s *AuthService) RevokeOtherSessions(userID int, currentSessionID string) error {
+	return s.tokenRepo.DeleteByUserIDExcept(userID, currentSessionID)
+}
+
+// EnsureActive возвращает ErrAccountSuspended, если аккаунт заблокирован, и ErrInvalidToken,
+// если его больше нет. Access JWT не отзываются, поэтому блокировка проверяется на каждый запрос
+func (s *AuthService) EnsureActive(userID int) error {
+	user, err := s.userRepo.GetByID(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidToken
+	}
 	if err != nil {
-		return 0, ErrInvalidToken
+		return err
 	}
+	if user.SuspendedAt != nil {
+		return ErrAccountSuspended
+	}
+	return nil
+}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
-		return 0, ErrInvalidToken
+// ParseToken парсит и валидирует access JWT-токен или персональный токен доступа
+func (s *AuthService) ParseToken(tokenString string) (*TokenClaims, error) {
+	if isAccessToken(tokenString) {
+		return s.parseAccessToken(tokenString)
+	}
+
+	claims, err := s.parseJWT(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// refresh- и MFA-токены подписаны тем же ключом, но доступа не дают
+	if tokenType, _ := claims["type"].(string); tokenType != "" {
+		return nil, ErrInvalidToken
 	}
 
 	userIDFloat, ok := claims["user_id"].(float64)
 	if !ok {
-		return 0, ErrInvalidToken
+		return nil, ErrInvalidToken
+	}
+
+	// Токены, выданные до появления сессий и ролей, не содержат sid и role
+	sessionID, _ := claims["sid"].(string)
+	role, _ := claims["role"].(string)
+	if role == "" {
+		role = model.RoleUser
 	}
 
-	return int(userIDFloat), nil
+	result := &TokenClaims{UserID: int(userIDFloat), SessionID: sessionID, Role: role}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		result.ExpiresAt = exp.Time
+	}
+	return result, nil
 }
 
-// generateTokens генерирует пару access + refresh токенов
-func (s *AuthService) generateTokens(userID int) (*model.TokenPair, error) {
+// parseJWT проверяет подпись и срок действия JWT и возвращает его claims
+func (s *AuthService) parseJWT(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, s.keys.Keyfunc,
+		jwt.WithValidMethods(s.keys.Methods()),
+		jwt.WithTimeFunc(s.now),
+	)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// JWKS возвращает открытые ключи для проверки access-токенов другими сервисами
+func (s *AuthService) JWKS() keys.JWKS {
+	return s.keys.JWKS()
+}
+
+// startSession начинает новую сессию и выдаёт первую пару токенов в ней
+func (s *AuthService) startSession(user *model.User, client model.ClientInfo) (*model.TokenPair, error) {
+	if user.SuspendedAt != nil {
+		return nil, ErrAccountSuspended
+	}
+	return s.generateTokens(user, generateJTI(), s.now(), client)
+}
+
+// generateTokens генерирует пару access + refresh токенов в семействе familyID
+func (s *AuthService) generateTokens(user *model.User, familyID string, startedAt time.Time, client model.ClientInfo) (*model.TokenPair, error) {
+	userID := user.ID
+
 	// Access-токен — 15 минут
 	accessClaims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(15 * time.Minute).Unix(),
-		"iat":     time.Now().Unix(),
+		"sid":     familyID,
+		"role":    user.Role,
+		"exp":     s.now().Add(15 * time.Minute).Unix(),
+		"iat":     s.now().Unix(),
 	}
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessString, err := accessToken.SignedString(s.jwtSecret)
+	accessString, err := s.keys.Sign(accessClaims)
 	if err != nil {
 		return nil, err
 	}
@@ -177,21 +444,27 @@ func (s *AuthService) generateTokens(userID int) (*model.TokenPair, error) {
 	// Refresh-токен — 7 дней (с уникальным jti для уникальности)
 	refreshClaims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(7 * 24 * time.Hour).Unix(),
-		"iat":     time.Now().Unix(),
+		"exp":     s.now().Add(7 * 24 * time.Hour).Unix(),
+		"iat":     s.now().Unix(),
 		"type":    "refresh",
 		"jti":     generateJTI(),
 	}
-	refreshJWT := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshString, err := refreshJWT.SignedString(s.jwtSecret)
+	refreshString, err := s.keys.Sign(refreshClaims)
 	if err != nil {
 		return nil, err
 	}
 
 	// Сохраняем хеш refresh-токена в БД
-	hash := hashToken(refreshString)
-	expiresAt := time.Now().Add(7 * 24 * time.Hour)
-	if err := s.tokenRepo.Create(userID, hash, expiresAt); err != nil {
+	err = s.tokenRepo.Create(&model.RefreshToken{
+		UserID:           userID,
+		FamilyID:         familyID,
+		TokenHash:        hashToken(refreshString),
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		SessionStartedAt: startedAt,
+		ExpiresAt:        s.now().Add(7 * 24 * time.Hour),
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -208,6 +481,13 @@ func generateJTI() string {
 	return fmt.Sprintf("%x", b)
 }
 
+// generateSecret генерирует случайный токен для ссылок из писем
+func generateSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 // hashToken хеширует токен через SHA256
 func hashToken(token string) string {
 	hash := sha256.Sum256([]byte(token))
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+
+	"social-network/internal/model"
+	"social-network/internal/repository"
+)
+
+var (
+	ErrSelfBlock = errors.New("нельзя заблокировать себя")
+	ErrSelfMute  = errors.New("нельзя скрыть себя")
+)
+
+// BlockService — сервис блокировок и скрытых пользователей. Блокировка взаимна:
+// стороны не видят посты, комментарии и профили друг друга, не могут подписаться,
+// лайкнуть, прокомментировать или написать. Скрытие убирает посты пользователя
+// из лент скрывшего, и сам пользователь об этом не знает
+type BlockService struct {
+	blockRepo repository.BlockRepository
+	muteRepo  repository.MuteRepository
+	userRepo  repository.UserRepository
+	stream    *StreamService
+}
+
+// NewBlockService создаёт сервис блокировок
+func NewBlockService(
+	blockRepo repository.BlockRepository,
+	muteRepo repository.MuteRepository,
+	userRepo repository.UserRepository,
+	stream *StreamService,
+) *BlockService {
+	return &BlockService{blockRepo: blockRepo, muteRepo: muteRepo, userRepo: userRepo, stream: stream}
+}
+
+// Block блокирует пользователя и отменяет подписки в обе стороны; потоки обеих
+// сторон перестают получать посты друг друга
+func (s *BlockService) Block(userID, blockedID int) error {
+	if userID == blockedID {
+		return ErrSelfBlock
+	}
+	if err := s.requireUser(blockedID); err != nil {
+		return err
+	}
+	if err := s.blockRepo.Block(userID, blockedID); err != nil {
+		return err
+	}
+	s.stream.FollowingChanged(userID, blockedID, false)
+	s.stream.FollowingChanged(blockedID, userID, false)
+	return nil
+}
+
+// Unblock снимает блокировку; прежние подписки не восстанавливаются
+func (s *BlockService) Unblock(userID, blockedID int) error {
+	return s.blockRepo.Unblock(userID, blockedID)
+}
+
+// GetBlocked возвращает заблокированных пользователем, последних первыми
+func (s *BlockService) GetBlocked(userID int, page model.PageRequest) (*model.Page[*model.User], error) {
+	return s.blockRepo.GetBlocked(userID, normalizePage(page))
+}
+
+// Mute скрывает посты пользователя из лент
+func (s *BlockService) Mute(userID, mutedID int) error {
+	if userID == mutedID {
+		return ErrSelfMute
+	}
+	if err := s.requireUser(mutedID); err != nil {
+		return err
+	}
+	if err := s.muteRepo.Mute(userID, mutedID); err != nil {
+		return err
+	}
+	s.stream.MutingChanged(userID, mutedID, true)
+	return nil
+}
+
+// Unmute возвращает посты пользователя в ленты
+func (s *BlockService) Unmute(userID, mutedID int) error {
+	if err := s.muteRepo.Unmute(userID, mutedID); err != nil {
+		return err
+	}
+	s.stream.MutingChanged(userID, mutedID, false)
+	return nil
+}
+
+// GetMuted возвращает скрытых пользователем, последних первыми
+func (s *BlockService) GetMuted(userID int, page model.PageRequest) (*model.Page[*model.User], error) {
+	return s.muteRepo.GetMuted(userID, normalizePage(page))
+}
+
+// requireUser возвращает ErrUserNotFound, если пользователя нет
+func (s *BlockService) requireUser(id int) error {
+	_, err := s.userRepo.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	return err
+}
@@ -1,26 +1,269 @@
 package service
 
 import (
+	"database/sql"
+	"errors"
+
 	"social-network/internal/model"
 	"social-network/internal/repository"
 )
 
+var (
+	ErrCommentNotFound = errors.New("комментарий не найден")
+	ErrCommentTooDeep  = errors.New("слишком глубокая вложенность ответов")
+	ErrNotCommentOwner = errors.New("вы не являетесь автором комментария")
+)
+
+// CommentPreviewLimit — сколько комментариев отдаётся вместе с постом
+const CommentPreviewLimit = 20
+
+// MaxCommentDepth — максимальная глубина ответа; 0 — комментарий к посту
+const MaxCommentDepth = 5
+
 // CommentService — сервис работы с комментариями
 type CommentService struct {
 	commentRepo repository.CommentRepository
+	postRepo    repository.PostRepository
+	userRepo    repository.UserRepository
+	blockRepo   repository.BlockRepository
+	followRepo  repository.FollowRepository
+
+	notifications *NotificationService
+	stream        *StreamService
 }
 
 // NewCommentService создаёт сервис комментариев
-func NewCommentService(commentRepo repository.CommentRepository) *CommentService {
-	return &CommentService{commentRepo: commentRepo}
+func NewCommentService(
+	commentRepo repository.CommentRepository,
+	postRepo repository.PostRepository,
+	userRepo repository.UserRepository,
+	blockRepo repository.BlockRepository,
+	followRepo repository.FollowRepository,
+	notifications *NotificationService,
+	stream *StreamService,
+) *CommentService {
+	return &CommentService{commentRepo: commentRepo, postRepo: postRepo, userRepo: userRepo, blockRepo: blockRepo, followRepo: followRepo, notifications: notifications, stream: stream}
+}
+
+// Create создаёт новый комментарий. parentID — комментарий того же поста, на который отвечают.
+// Автор поста, автор родительского комментария и упомянутые получают уведомления.
+// Комментировать пост и отвечать тем, с кем блокировка, нельзя — для пользователя их нет
+func (s *CommentService) Create(postID, userID int, parentID *int, content string) (*model.Comment, error) {
+	post, err := getPost(s.postRepo, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var parent *model.Comment
+	if parentID != nil {
+		parent, err = s.commentOfPost(postID, *parentID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.DeletedAt != nil {
+			return nil, ErrCommentNotFound
+		}
+		if parent.Depth >= MaxCommentDepth {
+			return nil, ErrCommentTooDeep
+		}
+	}
+
+	mentions, err := resolveMentions(s.userRepo, s.blockRepo, s.followRepo, userID, post.UserID, content)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := s.commentRepo.Create(postID, userID, parentID, content, mentions)
+	if err != nil {
+		return nil, err
+	}
+
+	notified := s.notifications.Commented(comment, post.UserID, parent)
+	s.notifications.Mentioned(userID, postID, &comment.ID, mentions, notified...)
+	s.stream.CountersChanged(postID)
+	return comment, nil
+}
+
+// Update меняет текст комментария (только автор комментария)
+func (s *CommentService) Update(postID, commentID, userID int, content string) (*model.Comment, error) {
+	comment, err := s.commentOfPost(postID, commentID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if comment.DeletedAt != nil {
+		return nil, ErrCommentNotFound
+	}
+	if comment.UserID != userID {
+		return nil, ErrNotCommentOwner
+	}
+
+	if content != comment.Content {
+		post, err := findPost(s.postRepo, postID)
+		if err != nil {
+			return nil, err
+		}
+		mentions, err := resolveMentions(s.userRepo, s.blockRepo, s.followRepo, userID, post.UserID, content)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.commentRepo.Update(commentID, content, mentions); err != nil {
+			return nil, err
+		}
+
+		// Тем, кому пришло уведомление о самом комментарии, об упоминании не сообщаем
+		skip, err := s.notifiedOfComment(comment)
+		if err != nil {
+			return nil, err
+		}
+		s.notifications.Mentioned(userID, postID, &commentID, mentions, skip...)
+	}
+	return s.commentRepo.GetByID(commentID, userID)
+}
+
+// Delete удаляет комментарий, оставляя «надгробие». Удалить может автор комментария
+// или автор поста
+func (s *CommentService) Delete(postID, commentID, userID int) error {
+	comment, err := s.commentOfPost(postID, commentID, 0)
+	if err != nil {
+		return err
+	}
+	if comment.DeletedAt != nil {
+		return ErrCommentNotFound
+	}
+	if comment.UserID != userID {
+		post, err := findPost(s.postRepo, postID)
+		if err != nil {
+			return err
+		}
+		if post.UserID != userID {
+			return ErrNotCommentOwner
+		}
+	}
+	if err := s.commentRepo.SoftDelete(commentID); err != nil {
+		return err
+	}
+	s.notifications.CommentRemoved(commentID)
+	s.stream.CountersChanged(postID)
+	return nil
+}
+
+// SetHidden скрывает комментарий от остальных читателей или возвращает его (только автор поста)
+func (s *CommentService) SetHidden(postID, commentID, userID int, hidden bool) error {
+	comment, err := s.commentOfPost(postID, commentID, 0)
+	if err != nil {
+		return err
+	}
+	if comment.DeletedAt != nil {
+		return ErrCommentNotFound
+	}
+	post, err := findPost(s.postRepo, postID)
+	if err != nil {
+		return err
+	}
+	if post.UserID != userID {
+		return ErrNotPostOwner
+	}
+	return s.commentRepo.SetHidden(commentID, hidden)
+}
+
+// GetByPostID возвращает страницу комментариев верхнего уровня к посту
+func (s *CommentService) GetByPostID(postID, viewerID int, page model.PageRequest) (*model.Page[*model.Comment], error) {
+	authorID, err := s.postAuthor(postID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.commentRepo.GetByPostID(postID, viewerID, normalizePage(page))
+	if err != nil {
+		return nil, err
+	}
+	maskHidden(comments.Items, authorID, viewerID)
+	return comments, nil
+}
+
+// GetReplies возвращает страницу прямых ответов на комментарий
+func (s *CommentService) GetReplies(commentID, viewerID int, page model.PageRequest) (*model.Page[*model.Comment], error) {
+	parent, err := s.getComment(commentID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	authorID, err := s.postAuthor(parent.PostID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	replies, err := s.commentRepo.GetReplies(commentID, viewerID, normalizePage(page))
+	if err != nil {
+		return nil, err
+	}
+	maskHidden(replies.Items, authorID, viewerID)
+	return replies, nil
+}
+
+// GetPreview возвращает первую страницу комментариев к посту автора postAuthorID
+func (s *CommentService) GetPreview(postID, postAuthorID, viewerID int) (*model.Page[*model.Comment], error) {
+	comments, err := s.commentRepo.GetByPostID(postID, viewerID, model.PageRequest{Limit: CommentPreviewLimit})
+	if err != nil {
+		return nil, err
+	}
+	maskHidden(comments.Items, postAuthorID, viewerID)
+	return comments, nil
+}
+
+// maskHidden убирает текст скрытых комментариев для всех, кроме их автора и автора поста
+func maskHidden(comments []*model.Comment, postAuthorID, viewerID int) {
+	for _, c := range comments {
+		if c.HiddenAt != nil && viewerID != c.UserID && viewerID != postAuthorID {
+			c.Content = ""
+			c.Mentions = nil
+		}
+	}
+}
+
+// commentOfPost возвращает комментарий, только если он относится к посту postID
+func (s *CommentService) commentOfPost(postID, commentID, viewerID int) (*model.Comment, error) {
+	comment, err := s.getComment(commentID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.PostID != postID {
+		return nil, ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+// postAuthor возвращает ID автора поста или ErrPostNotFound, если viewerID пост не видит
+func (s *CommentService) postAuthor(postID, viewerID int) (int, error) {
+	post, err := s.postRepo.GetByID(postID, viewerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrPostNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return post.UserID, nil
 }
 
-// Create создаёт новый комментарий
-func (s *CommentService) Create(postID, userID int, content string) (*model.Comment, error) {
-	return s.commentRepo.Create(postID, userID, content)
+// getComment возвращает комментарий глазами viewerID или ErrCommentNotFound
+func (s *CommentService) getComment(id, viewerID int) (*model.Comment, error) {
+	comment, err := s.commentRepo.GetByID(id, viewerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCommentNotFound
+	}
+	return comment, err
 }
 
-// GetByPostID возвращает комментарии к посту
-func (s *CommentService) GetByPostID(postID int) ([]*model.Comment, error) {
-	return s.commentRepo.GetByPostID(postID)
+// notifiedOfComment возвращает автора поста и автора родительского комментария —
+// тех, кому Commented сообщил о комментарии
+func (s *CommentService) notifiedOfComment(comment *model.Comment) ([]int, error) {
+	post, err := findPost(s.postRepo, comment.PostID)
+	if err != nil {
+		return nil, err
+	}
+	notified := []int{post.UserID}
+	if comment.ParentID != nil {
+		parent, err := s.commentRepo.GetByID(*comment.ParentID, 0)
+		if err != nil {
+			return nil, err
+		}
+		notified = append(notified, parent.UserID)
+	}
+	return notified, nil
 }
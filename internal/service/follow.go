@@ -1,43 +1,138 @@
 package service
 
 import (
+	"database/sql"
 	"errors"
 
 	"social-network/internal/model"
 	"social-network/internal/repository"
 )
 
-var ErrSelfFollow = errors.New("нельзя подписаться на себя")
+var (
+	ErrSelfFollow            = errors.New("нельзя подписаться на себя")
+	ErrFollowRequestNotFound = errors.New("запрос на подписку не найден")
+)
 
-// FollowService — сервис работы с подписками
+// FollowService — сервис работы с подписками и запросами на подписку к закрытым аккаунтам
 type FollowService struct {
-	followRepo repository.FollowRepository
+	followRepo    repository.FollowRepository
+	blockRepo     repository.BlockRepository
+	userRepo      repository.UserRepository
+	notifications *NotificationService
+	stream        *StreamService
 }
 
 // NewFollowService создаёт сервис подписок
-func NewFollowService(followRepo repository.FollowRepository) *FollowService {
-	return &FollowService{followRepo: followRepo}
+func NewFollowService(
+	followRepo repository.FollowRepository,
+	blockRepo repository.BlockRepository,
+	userRepo repository.UserRepository,
+	notifications *NotificationService,
+	stream *StreamService,
+) *FollowService {
+	return &FollowService{followRepo: followRepo, blockRepo: blockRepo, userRepo: userRepo, notifications: notifications, stream: stream}
 }
 
-// Follow подписывает followerID на followingID
-func (s *FollowService) Follow(followerID, followingID int) error {
+// Follow подписывает followerID на followingID и уведомляет его. На закрытый аккаунт
+// вместо подписки отправляется запрос — тогда возвращается true. При блокировке
+// между ними пользователь для подписчика не существует
+func (s *FollowService) Follow(followerID, followingID int) (bool, error) {
 	if followerID == followingID {
-		return ErrSelfFollow
+		return false, ErrSelfFollow
+	}
+	blocked, err := s.blockRepo.IsBlocked(followerID, followingID)
+	if err != nil {
+		return false, err
+	}
+	if blocked {
+		return false, ErrUserNotFound
+	}
+
+	requested, err := s.followRepo.Follow(followerID, followingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrUserNotFound
+	}
+	if err != nil {
+		return false, err
 	}
-	return s.followRepo.Follow(followerID, followingID)
+	if requested {
+		s.notifications.FollowRequested(followerID, followingID)
+		return true, nil
+	}
+	s.notifications.Followed(followerID, followingID)
+	s.stream.FollowingChanged(followerID, followingID, true)
+	return false, nil
 }
 
-// Unfollow отписывает followerID от followingID
+// Unfollow отписывает followerID от followingID или отзывает его запрос и убирает уведомление
 func (s *FollowService) Unfollow(followerID, followingID int) error {
-	return s.followRepo.Unfollow(followerID, followingID)
+	if err := s.followRepo.Unfollow(followerID, followingID); err != nil {
+		return err
+	}
+	s.notifications.Unfollowed(followerID, followingID)
+	s.notifications.FollowRequestClosed(followerID, followingID)
+	s.stream.FollowingChanged(followerID, followingID, false)
+	return nil
+}
+
+// GetRequests возвращает ожидающие запросы на подписку к пользователю, новые первыми
+func (s *FollowService) GetRequests(userID int, page model.PageRequest) (*model.Page[*model.User], error) {
+	return s.followRepo.GetRequests(userID, normalizePage(page))
+}
+
+// ApproveRequest одобряет запрос followerID: он становится подписчиком, и его потоки
+// начинают получать посты пользователя
+func (s *FollowService) ApproveRequest(userID, followerID int) error {
+	approved, err := s.followRepo.ApproveRequest(followerID, userID)
+	if err != nil {
+		return err
+	}
+	if !approved {
+		return ErrFollowRequestNotFound
+	}
+	s.notifications.FollowRequestClosed(followerID, userID)
+	s.stream.FollowingChanged(followerID, userID, true)
+	return nil
+}
+
+// DenyRequest отклоняет запрос followerID; отклонённый может отправить новый
+func (s *FollowService) DenyRequest(userID, followerID int) error {
+	deleted, err := s.followRepo.DeleteRequest(followerID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrFollowRequestNotFound
+	}
+	s.notifications.FollowRequestClosed(followerID, userID)
+	return nil
+}
+
+// SetPrivate закрывает или открывает аккаунт. При открытии все ожидающие запросы одобряются
+func (s *FollowService) SetPrivate(userID int, private bool) error {
+	if err := s.userRepo.SetPrivate(userID, private); err != nil {
+		return err
+	}
+	if private {
+		return nil
+	}
+	approved, err := s.followRepo.ApproveAllRequests(userID)
+	if err != nil {
+		return err
+	}
+	for _, followerID := range approved {
+		s.notifications.FollowRequestClosed(followerID, userID)
+		s.stream.FollowingChanged(followerID, userID, true)
+	}
+	return nil
 }
 
-// GetFollowers возвращает подписчиков пользователя
-func (s *FollowService) GetFollowers(userID int) ([]*model.User, error) {
-	return s.followRepo.GetFollowers(userID)
+// GetFollowers возвращает подписчиков пользователя, новых первыми, глазами viewerID
+func (s *FollowService) GetFollowers(userID, viewerID int, page model.PageRequest) (*model.Page[*model.User], error) {
+	return s.followRepo.GetFollowers(userID, viewerID, normalizePage(page))
 }
 
-// GetFollowing возвращает подписки пользователя
-func (s *FollowService) GetFollowing(userID int) ([]*model.User, error) {
-	return s.followRepo.GetFollowing(userID)
+// GetFollowing возвращает подписки пользователя, новые первыми, глазами viewerID
+func (s *FollowService) GetFollowing(userID, viewerID int, page model.PageRequest) (*model.Page[*model.User], error) {
+	return s.followRepo.GetFollowing(userID, viewerID, normalizePage(page))
 }
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxHashtagLength — длиннее считаем не тегом, а мусором
+const maxHashtagLength = 100
+
+// textToken — слово после маркера (# или @) и его место в тексте в рунах, вместе с маркером
+type textToken struct {
+	Text   string
+	Offset int
+	Length int
+}
+
+// findTokens находит слова из букв, цифр и _ сразу после marker. Перед маркером —
+// начало строки или не словесный символ, поэтому адреса вида a@b.c и якоря a#b не считаются
+func findTokens(content string, marker rune) []textToken {
+	var tokens []textToken
+
+	prev := ' '
+	runeIdx := 0
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRuneInString(content[i:])
+		if r != marker || isWordRune(prev) {
+			prev = r
+			i += size
+			runeIdx++
+			continue
+		}
+
+		start := runeIdx
+		end := i + size
+		runeIdx++
+		prev = r
+		for end < len(content) {
+			c, n := utf8.DecodeRuneInString(content[end:])
+			if !isWordRune(c) {
+				break
+			}
+			prev = c
+			end += n
+			runeIdx++
+		}
+
+		if word := content[i+size : end]; word != "" {
+			tokens = append(tokens, textToken{Text: word, Offset: start, Length: runeIdx - start})
+		}
+		i = end
+	}
+	return tokens
+}
+
+// extractHashtags находит #теги в тексте: хотя бы одна буква, не длиннее maxHashtagLength.
+// Теги приводятся к нижнему регистру и возвращаются без повторов в порядке появления
+func extractHashtags(content string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	for _, t := range findTokens(content, '#') {
+		tag := strings.ToLower(t.Text)
+		if !strings.ContainsFunc(tag, unicode.IsLetter) || utf8.RuneCountInString(tag) > maxHashtagLength || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// normalizeHashtag приводит тег из URL к виду, в котором он хранится
+func normalizeHashtag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
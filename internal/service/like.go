@@ -4,20 +4,43 @@ import "social-network/internal/repository"
 
 // LikeService — сервис работы с лайками
 type LikeService struct {
-	likeRepo repository.LikeRepository
+	likeRepo      repository.LikeRepository
+	postRepo      repository.PostRepository
+	notifications *NotificationService
+	stream        *StreamService
 }
 
 // NewLikeService создаёт сервис лайков
-func NewLikeService(likeRepo repository.LikeRepository) *LikeService {
-	return &LikeService{likeRepo: likeRepo}
+func NewLikeService(likeRepo repository.LikeRepository, postRepo repository.PostRepository, notifications *NotificationService, stream *StreamService) *LikeService {
+	return &LikeService{likeRepo: likeRepo, postRepo: postRepo, notifications: notifications, stream: stream}
 }
 
-// Like ставит лайк на пост
+// Like ставит лайк на пост, уведомляет автора и рассылает новые счётчики.
+// Пост автора, с которым блокировка, для пользователя не существует
 func (s *LikeService) Like(userID, postID int) error {
-	return s.likeRepo.Like(userID, postID)
+	post, err := getPost(s.postRepo, postID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.likeRepo.Like(userID, postID); err != nil {
+		return err
+	}
+	s.notifications.Liked(userID, post)
+	s.stream.CountersChanged(postID)
+	return nil
 }
 
-// Unlike убирает лайк с поста
+// Unlike убирает лайк с поста вместе с уведомлением о нём; свой лайк можно снять
+// и после блокировки или потери доступа к закрытому аккаунту
 func (s *LikeService) Unlike(userID, postID int) error {
-	return s.likeRepo.Unlike(userID, postID)
+	post, err := findPost(s.postRepo, postID)
+	if err != nil {
+		return err
+	}
+	if err := s.likeRepo.Unlike(userID, postID); err != nil {
+		return err
+	}
+	s.notifications.Unliked(userID, post)
+	s.stream.CountersChanged(postID)
+	return nil
 }
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LoginThrottleConfig — политика защиты входа от перебора паролей
+type LoginThrottleConfig struct {
+	// FreeAttempts — сколько неудач подряд по аккаунту проходят без задержки
+	FreeAttempts int
+	// IPFreeAttempts — то же для одного IP; выше, чем для аккаунта, из-за NAT и офисов
+	IPFreeAttempts int
+	// BaseDelay удваивается с каждой следующей неудачей, но не превышает MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutThreshold — после стольких неудач аккаунт блокируется на LockoutDuration.
+	// Снять блокировку досрочно можно сбросом пароля
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// Window — неудачи старше этого срока забываются
+	Window time.Duration
+}
+
+// DefaultLoginThrottle — политика по умолчанию
+func DefaultLoginThrottle() LoginThrottleConfig {
+	return LoginThrottleConfig{
+		FreeAttempts:     3,
+		IPFreeAttempts:   20,
+		BaseDelay:        time.Second,
+		MaxDelay:         5 * time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  15 * time.Minute,
+		Window:           time.Hour,
+	}
+}
+
+// LoginThrottledError — вход временно запрещён из-за серии неудачных попыток
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool // true — аккаунт заблокирован, false — нужно подождать из-за частых попыток
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("аккаунт временно заблокирован, повторите через %s", e.RetryAfter)
+	}
+	return fmt.Sprintf("слишком много попыток входа, повторите через %s", e.RetryAfter)
+}
+
+// loginKeys — ключи счётчиков попыток для одного запроса входа
+type loginKeys struct {
+	account string
+	ip      string
+}
+
+// newLoginKeys строит ключи счётчиков по email и адресу клиента
+func newLoginKeys(email, ip string) loginKeys {
+	return loginKeys{
+		account: "account:" + strings.ToLower(strings.TrimSpace(email)),
+		ip:      "ip:" + ip,
+	}
+}
+
+// checkLoginAllowed проверяет, можно ли сейчас пытаться войти.
+// Проверка идёт до сверки пароля, поэтому заблокированный аккаунт не выдаёт, верен ли пароль
+func (s *AuthService) checkLoginAllowed(keys loginKeys) error {
+	if err := s.checkAttempts(keys.account, s.throttle.FreeAttempts); err != nil {
+		return err
+	}
+	if keys.ip == "ip:" {
+		return nil
+	}
+	return s.checkAttempts(keys.ip, s.throttle.IPFreeAttempts)
+}
+
+// checkAttempts проверяет блокировку и задержку для одного ключа
+func (s *AuthService) checkAttempts(key string, freeAttempts int) error {
+	attempt, err := s.loginAttemptRepo.Get(key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	now := s.now()
+	if attempt.LockedUntil != nil && now.Before(*attempt.LockedUntil) {
+		return &LoginThrottledError{RetryAfter: attempt.LockedUntil.Sub(now), Locked: true}
+	}
+	if now.Sub(attempt.LastFailureAt) > s.throttle.Window {
+		return nil
+	}
+
+	retryAt := attempt.LastFailureAt.Add(s.backoff(attempt.Failures, freeAttempts))
+	if now.Before(retryAt) {
+		return &LoginThrottledError{RetryAfter: retryAt.Sub(now)}
+	}
+	return nil
+}
+
+// backoff возвращает паузу после failures неудач: 0 для первых freeAttempts,
+// затем BaseDelay, 2×BaseDelay, 4×BaseDelay... но не больше MaxDelay
+func (s *AuthService) backoff(failures, freeAttempts int) time.Duration {
+	extra := failures - freeAttempts
+	if extra <= 0 {
+		return 0
+	}
+	delay := s.throttle.BaseDelay
+	for i := 1; i < extra && delay < s.throttle.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.throttle.MaxDelay)
+}
+
+// recordLoginFailure учитывает неудачную попытку и при необходимости блокирует аккаунт
+func (s *AuthService) recordLoginFailure(keys loginKeys) error {
+	now := s.now()
+	windowStart := now.Add(-s.throttle.Window)
+
+	attempt, err := s.loginAttemptRepo.RegisterFailure(keys.account, now, windowStart)
+	if err != nil {
+		return err
+	}
+	// После истечения блокировки первая же неудача блокирует аккаунт снова
+	lockExpired := attempt.LockedUntil == nil || !now.Before(*attempt.LockedUntil)
+	if attempt.Failures >= s.throttle.LockoutThreshold && lockExpired {
+		if err := s.loginAttemptRepo.Lock(keys.account, now.Add(s.throttle.LockoutDuration)); err != nil {
+			return err
+		}
+	}
+
+	if keys.ip == "ip:" {
+		return nil
+	}
+	_, err = s.loginAttemptRepo.RegisterFailure(keys.ip, now, windowStart)
+	return err
+}
+
+// resetLoginFailures снимает счётчик и блокировку аккаунта.
+// Счётчик IP не сбрасывается: иначе перебор чужих паролей можно разбавлять входами в свой аккаунт
+func (s *AuthService) resetLoginFailures(email string) error {
+	return s.loginAttemptRepo.Reset(newLoginKeys(email, "").account)
+}
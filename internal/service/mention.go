@@ -0,0 +1,69 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+
+	"social-network/internal/model"
+	"social-network/internal/repository"
+)
+
+// maxMentionedUsers — сколько разных @username в одном тексте проверяется; остальные остаются текстом
+const maxMentionedUsers = 20
+
+// resolveMentions находит @username в тексте автора authorID к посту пользователя ownerID
+// и оставляет только существующих пользователей без блокировки с автором, которые видят
+// посты ownerID, — остальные остаются текстом. Каждое вхождение — отдельная сущность
+// со своим смещением
+func resolveMentions(
+	userRepo repository.UserRepository,
+	blockRepo repository.BlockRepository,
+	followRepo repository.FollowRepository,
+	authorID, ownerID int,
+	content string,
+) ([]model.Mention, error) {
+	var mentions []model.Mention
+	users := map[string]*model.User{}
+	for _, t := range findTokens(content, '@') {
+		user, checked := users[t.Text]
+		if !checked {
+			if len(users) >= maxMentionedUsers {
+				continue
+			}
+			u, err := userRepo.GetByUsername(t.Text)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return nil, err
+			}
+			if u != nil && u.ID != authorID {
+				blocked, err := blockRepo.IsBlocked(authorID, u.ID)
+				if err != nil {
+					return nil, err
+				}
+				if blocked {
+					u = nil
+				}
+			}
+			if u != nil {
+				visible, err := followRepo.CanView(u.ID, ownerID)
+				if err != nil {
+					return nil, err
+				}
+				if !visible {
+					u = nil
+				}
+			}
+			users[t.Text] = u
+			user = u
+		}
+		if user == nil {
+			continue
+		}
+		mentions = append(mentions, model.Mention{
+			UserID:   user.ID,
+			Username: user.Username,
+			Offset:   t.Offset,
+			Length:   t.Length,
+		})
+	}
+	return mentions, nil
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"social-network/internal/model"
+	"social-network/internal/repository"
+)
+
+var (
+	ErrConversationNotFound = errors.New("диалог не найден")
+	ErrSelfConversation     = errors.New("нельзя написать самому себе")
+	ErrMessagesRestricted   = errors.New("пользователь принимает сообщения только от тех, на кого подписан")
+	ErrEmptyMessage         = errors.New("сообщение не может быть пустым")
+	ErrMessageTooLong       = errors.New("слишком длинное сообщение")
+	ErrGroupTooSmall        = errors.New("в группе должно быть хотя бы два собеседника")
+	ErrGroupTooLarge        = errors.New("слишком много участников группы")
+	ErrGroupTitleTooLong    = errors.New("слишком длинное название группы")
+	ErrLeaveDirect          = errors.New("из личного диалога нельзя выйти, его можно архивировать")
+	ErrGroupMembersBlocked  = errors.New("эти пользователи не могут быть в одной группе")
+)
+
+const (
+	// MaxMessageLength — ограничение длины сообщения в символах
+	MaxMessageLength = 4000
+	// MaxGroupSize — сколько участников может быть в группе вместе с создателем
+	MaxGroupSize = 50
+	// maxGroupTitleLength — ограничение длины названия группы в символах
+	maxGroupTitleLength = 100
+)
+
+// MessageService — сервис личных сообщений
+type MessageService struct {
+	conversationRepo repository.ConversationRepository
+	userRepo         repository.UserRepository
+	followRepo       repository.FollowRepository
+	blockRepo        repository.BlockRepository
+
+	stream *StreamService
+}
+
+// NewMessageService создаёт сервис сообщений
+func NewMessageService(
+	conversationRepo repository.ConversationRepository,
+	userRepo repository.UserRepository,
+	followRepo repository.FollowRepository,
+	blockRepo repository.BlockRepository,
+	stream *StreamService,
+) *MessageService {
+	return &MessageService{
+		conversationRepo: conversationRepo,
+		userRepo:         userRepo,
+		followRepo:       followRepo,
+		blockRepo:        blockRepo,
+		stream:           stream,
+	}
+}
+
+// StartDirect возвращает личный диалог с пользователем otherID, создавая его при первом обращении
+func (s *MessageService) StartDirect(userID, otherID int) (*model.Conversation, error) {
+	if userID == otherID {
+		return nil, ErrSelfConversation
+	}
+	if err := s.canMessage(userID, otherID); err != nil {
+		return nil, err
+	}
+	id, err := s.conversationRepo.CreateDirect(userID, otherID)
+	if err != nil {
+		return nil, err
+	}
+	return s.conversationRepo.GetByID(id, userID)
+}
+
+// CreateGroup создаёт группу с участниками memberIDs; писать каждому из них
+// должно быть можно и лично, а между самими участниками не должно быть блокировок
+func (s *MessageService) CreateGroup(userID int, title string, memberIDs []int) (*model.Conversation, error) {
+	title = strings.TrimSpace(title)
+	if utf8.RuneCountInString(title) > maxGroupTitleLength {
+		return nil, ErrGroupTitleTooLong
+	}
+
+	seen := map[int]bool{userID: true}
+	var members []int
+	for _, id := range memberIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		members = append(members, id)
+	}
+	if len(members) < 2 {
+		return nil, ErrGroupTooSmall
+	}
+	if len(members)+1 > MaxGroupSize {
+		return nil, ErrGroupTooLarge
+	}
+	for _, id := range members {
+		if err := s.canMessage(userID, id); err != nil {
+			return nil, err
+		}
+	}
+	blocked, err := s.blockRepo.AnyBlocked(members)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrGroupMembersBlocked
+	}
+
+	id, err := s.conversationRepo.CreateGroup(userID, title, members)
+	if err != nil {
+		return nil, err
+	}
+	return s.conversationRepo.GetByID(id, userID)
+}
+
+// List возвращает диалоги пользователя: обычные или архив
+func (s *MessageService) List(userID int, archived bool, page model.PageRequest) (*model.Page[*model.Conversation], error) {
+	return s.conversationRepo.GetByUserID(userID, archived, normalizePage(page))
+}
+
+// Get возвращает диалог, если пользователь в нём участвует
+func (s *MessageService) Get(userID, conversationID int) (*model.Conversation, error) {
+	conversation, err := s.conversationRepo.GetByID(conversationID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrConversationNotFound
+	}
+	return conversation, err
+}
+
+// Send отправляет сообщение в диалог и сообщает о нём участникам в реальном времени.
+// В личном диалоге ограничения собеседника проверяются при каждой отправке, в группе
+// о сообщении не узнают те, с кем у автора блокировка
+func (s *MessageService) Send(userID, conversationID int, content string) (*model.Message, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(content) > MaxMessageLength {
+		return nil, ErrMessageTooLong
+	}
+
+	conversation, err := s.Get(userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if !conversation.IsGroup {
+		for _, p := range conversation.Participants {
+			if p.UserID == userID {
+				continue
+			}
+			if err := s.canMessage(userID, p.UserID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	msg, err := s.conversationRepo.AddMessage(conversationID, userID, content)
+	if err != nil {
+		return nil, err
+	}
+	recipients, err := s.unblockedParticipants(userID, conversation)
+	if err != nil {
+		return nil, err
+	}
+	s.stream.MessageSent(msg, recipients)
+	return msg, nil
+}
+
+// Messages возвращает сообщения диалога, новые первыми
+func (s *MessageService) Messages(userID, conversationID int, page model.PageRequest) (*model.Page[*model.Message], error) {
+	if _, err := s.Get(userID, conversationID); err != nil {
+		return nil, err
+	}
+	return s.conversationRepo.GetMessages(conversationID, userID, normalizePage(page))
+}
+
+// MarkRead отмечает диалог прочитанным до messageID (0 — целиком) и сообщает
+// об этом остальным участникам. Возвращает итоговую отметку
+func (s *MessageService) MarkRead(userID, conversationID, messageID int) (int, error) {
+	conversation, err := s.Get(userID, conversationID)
+	if err != nil {
+		return 0, err
+	}
+	lastReadID, err := s.conversationRepo.MarkRead(conversationID, userID, messageID)
+	if err != nil {
+		return 0, err
+	}
+	s.stream.ConversationRead(conversationID, userID, lastReadID, participantIDs(conversation))
+	return lastReadID, nil
+}
+
+// SetMuted включает или выключает беззвучный режим диалога: заглушённые диалоги
+// не попадают в счётчик непрочитанных и не покидают архив при новых сообщениях
+func (s *MessageService) SetMuted(userID, conversationID int, muted bool) error {
+	if _, err := s.Get(userID, conversationID); err != nil {
+		return err
+	}
+	return s.conversationRepo.SetMuted(conversationID, userID, muted)
+}
+
+// SetArchived убирает диалог в архив или возвращает из него
+func (s *MessageService) SetArchived(userID, conversationID int, archived bool) error {
+	if _, err := s.Get(userID, conversationID); err != nil {
+		return err
+	}
+	return s.conversationRepo.SetArchived(conversationID, userID, archived)
+}
+
+// Leave выводит пользователя из группы
+func (s *MessageService) Leave(userID, conversationID int) error {
+	conversation, err := s.Get(userID, conversationID)
+	if err != nil {
+		return err
+	}
+	if !conversation.IsGroup {
+		return ErrLeaveDirect
+	}
+	return s.conversationRepo.Leave(conversationID, userID)
+}
+
+// UnreadCount возвращает число диалогов с непрочитанными сообщениями
+func (s *MessageService) UnreadCount(userID int) (int, error) {
+	return s.conversationRepo.CountUnread(userID)
+}
+
+// canMessage проверяет, что отправитель может писать получателю. При блокировке
+// между ними получатель для отправителя не существует
+func (s *MessageService) canMessage(senderID, recipientID int) error {
+	recipient, err := s.userRepo.GetByID(recipientID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && recipient.SuspendedAt != nil) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	blocked, err := s.blockRepo.IsBlocked(senderID, recipientID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrUserNotFound
+	}
+	if !recipient.MessagesFollowingOnly {
+		return nil
+	}
+	following, err := s.followRepo.IsFollowing(recipientID, senderID)
+	if err != nil {
+		return err
+	}
+	if !following {
+		return ErrMessagesRestricted
+	}
+	return nil
+}
+
+// unblockedParticipants возвращает участников диалога, у которых нет блокировки с userID
+func (s *MessageService) unblockedParticipants(userID int, c *model.Conversation) ([]int, error) {
+	var ids []int
+	for _, id := range participantIDs(c) {
+		if id != userID {
+			blocked, err := s.blockRepo.IsBlocked(userID, id)
+			if err != nil {
+				return nil, err
+			}
+			if blocked {
+				continue
+			}
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// participantIDs возвращает ID текущих участников диалога
+func participantIDs(c *model.Conversation) []int {
+	ids := make([]int, len(c.Participants))
+	for i, p := range c.Participants {
+		ids[i] = p.UserID
+	}
+	return ids
+}
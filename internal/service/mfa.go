@@ -0,0 +1,253 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"social-network/internal/model"
+	"social-network/internal/totp"
+)
+
+const (
+	// mfaTokenTTL — сколько живёт челлендж между вводом пароля и кода
+	mfaTokenTTL = 5 * time.Minute
+	// recoveryCodesCount — размер набора кодов восстановления
+	recoveryCodesCount = 10
+	// totpIssuer — название сервиса в приложении-аутентификаторе
+	totpIssuer = "GoNetwork"
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("двухфакторная аутентификация уже включена")
+	ErrMFANotEnrolled    = errors.New("подключение TOTP не начато")
+	ErrMFANotEnabled     = errors.New("двухфакторная аутентификация не включена")
+	ErrInvalidMFACode    = errors.New("неверный код")
+)
+
+// MFAStatus возвращает состояние 2FA пользователя
+func (s *AuthService) MFAStatus(userID int) (*model.MFAStatus, error) {
+	enabled, err := s.totpEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &model.MFAStatus{TOTPEnabled: enabled}
+	if enabled {
+		status.RecoveryCodesLeft, err = s.mfaRepo.CountRecoveryCodes(userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// EnrollTOTP начинает подключение TOTP: выдаёт секрет и otpauth:// ссылку.
+// 2FA включится только после подтверждения кодом из приложения
+func (s *AuthService) EnrollTOTP(userID int) (*model.TOTPEnrollment, error) {
+	enabled, err := s.totpEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.SaveTOTPSecret(userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &model.TOTPEnrollment{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP включает 2FA по первому коду и возвращает коды восстановления
+func (s *AuthService) ConfirmTOTP(userID int, code string) ([]string, error) {
+	settings, err := s.mfaRepo.GetTOTP(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	if settings.ConfirmedAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if err := s.checkTOTP(settings, code); err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.ConfirmTOTP(userID); err != nil {
+		return nil, err
+	}
+
+	return s.issueRecoveryCodes(userID)
+}
+
+// DisableTOTP отключает 2FA. Требует действующий код или код восстановления
+func (s *AuthService) DisableTOTP(userID int, code, recoveryCode string) error {
+	if err := s.verifySecondFactor(userID, code, recoveryCode); err != nil {
+		return err
+	}
+	return s.mfaRepo.DeleteTOTP(userID)
+}
+
+// RegenerateRecoveryCodes выдаёт новый набор кодов восстановления взамен старого
+func (s *AuthService) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	if err := s.verifySecondFactor(userID, code, ""); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(userID)
+}
+
+// LoginMFA — второй шаг входа: проверяет код по MFA-челленджу и выдаёт токены
+func (s *AuthService) LoginMFA(mfaToken, code, recoveryCode string, client model.ClientInfo) (*model.LoginResult, error) {
+	claims, err := s.parseJWT(mfaToken)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if tokenType, _ := claims["type"].(string); tokenType != "mfa" {
+		return nil, ErrInvalidToken
+	}
+	userIDFloat, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	userID := int(userIDFloat)
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Перебор кодов 2FA ограничивается теми же счётчиками, что и перебор паролей
+	keys := newLoginKeys(user.Email, client.IPAddress)
+	if err := s.checkLoginAllowed(keys); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifySecondFactor(userID, code, recoveryCode); err != nil {
+		if err == ErrInvalidMFACode {
+			if recordErr := s.recordLoginFailure(keys); recordErr != nil {
+				return nil, recordErr
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.resetLoginFailures(user.Email); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.startSession(user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginResult{User: user, Tokens: tokens}, nil
+}
+
+// totpEnabled проверяет, включена ли у пользователя 2FA
+func (s *AuthService) totpEnabled(userID int) (bool, error) {
+	settings, err := s.mfaRepo.GetTOTP(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return settings.ConfirmedAt != nil, nil
+}
+
+// verifySecondFactor проверяет TOTP-код или одноразовый код восстановления
+func (s *AuthService) verifySecondFactor(userID int, code, recoveryCode string) error {
+	settings, err := s.mfaRepo.GetTOTP(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMFANotEnabled
+		}
+		return err
+	}
+	if settings.ConfirmedAt == nil {
+		return ErrMFANotEnabled
+	}
+
+	if recoveryCode != "" {
+		used, err := s.mfaRepo.UseRecoveryCode(userID, hashToken(normalizeRecoveryCode(recoveryCode)))
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	return s.checkTOTP(settings, code)
+}
+
+// checkTOTP проверяет код и запрещает повторное использование того же шага
+func (s *AuthService) checkTOTP(settings *model.TOTPSettings, code string) error {
+	step, ok := totp.Validate(settings.Secret, code, s.now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	advanced, err := s.mfaRepo.AdvanceTOTPStep(settings.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// issueRecoveryCodes генерирует коды восстановления; в БД хранятся только хеши
+func (s *AuthService) issueRecoveryCodes(userID int) ([]string, error) {
+	codes := make([]string, recoveryCodesCount)
+	hashes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		raw := generateJTI()[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashToken(normalizeRecoveryCode(codes[i]))
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateMFAToken выпускает короткоживущий токен второго шага входа
+func (s *AuthService) generateMFAToken(userID int) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"type":    "mfa",
+		"exp":     s.now().Add(mfaTokenTTL).Unix(),
+		"iat":     s.now().Unix(),
+		"jti":     generateJTI(),
+	}
+	token, err := s.keys.Sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("ошибка подписи MFA-токена: %w", err)
+	}
+	return token, nil
+}
+
+// normalizeRecoveryCode приводит код к виду, в котором хранится хеш
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"social-network/internal/model"
+	"social-network/internal/repository"
+)
+
+var ErrNotificationNotFound = errors.New("уведомление не найдено")
+
+// notificationVerbs — текст действия для одного автора и для нескольких
+var notificationVerbs = map[string][2]string{
+	model.NotificationLike:    {"оценил(а) ваш пост", "оценили ваш пост"},
+	model.NotificationComment: {"прокомментировал(а) ваш пост", "прокомментировали ваш пост"},
+	model.NotificationReply:   {"ответил(а) на ваш комментарий", "ответили на ваш комментарий"},
+	model.NotificationFollow:  {"подписался(-ась) на вас", "подписались на вас"},
+	model.NotificationMention: {"упомянул(а) вас", "упомянули вас"},
+
+	model.NotificationFollowRequest: {"хочет подписаться на вас", "хотят подписаться на вас"},
+}
+
+// NotificationService — сервис уведомлений. Другие сервисы сообщают ему о действиях;
+// ошибки записи уведомлений только логируются и не ломают само действие
+type NotificationService struct {
+	notificationRepo repository.NotificationRepository
+	stream           *StreamService
+}
+
+// NewNotificationService создаёт сервис уведомлений
+func NewNotificationService(notificationRepo repository.NotificationRepository, stream *StreamService) *NotificationService {
+	return &NotificationService{notificationRepo: notificationRepo, stream: stream}
+}
+
+// Liked уведомляет автора поста о лайке
+func (s *NotificationService) Liked(actorID int, post *model.Post) {
+	s.notify(likeEvent(actorID, post))
+}
+
+// Unliked убирает уведомление о снятом лайке
+func (s *NotificationService) Unliked(actorID int, post *model.Post) {
+	s.retract(likeEvent(actorID, post))
+}
+
+// Followed уведомляет о новом подписчике
+func (s *NotificationService) Followed(followerID, followingID int) {
+	s.notify(followEvent(followerID, followingID))
+}
+
+// Unfollowed убирает уведомление об отменённой подписке
+func (s *NotificationService) Unfollowed(followerID, followingID int) {
+	s.retract(followEvent(followerID, followingID))
+}
+
+// FollowRequested уведомляет владельца закрытого аккаунта о запросе на подписку
+func (s *NotificationService) FollowRequested(followerID, followingID int) {
+	s.notify(followRequestEvent(followerID, followingID))
+}
+
+// FollowRequestClosed убирает уведомление о запросе, когда его одобрили, отклонили или отозвали
+func (s *NotificationService) FollowRequestClosed(followerID, followingID int) {
+	s.retract(followRequestEvent(followerID, followingID))
+}
+
+// Commented уведомляет автора поста о комментарии, а автора родительского комментария —
+// об ответе. parent — nil для комментария верхнего уровня. Возвращает ID тех, кому
+// уже сообщили о комментарии: упоминание им отдельно не приходит
+func (s *NotificationService) Commented(comment *model.Comment, postAuthorID int, parent *model.Comment) []int {
+	notified := []int{comment.UserID}
+	if parent != nil && parent.UserID != comment.UserID {
+		s.notify(&model.NotificationEvent{
+			UserID:    parent.UserID,
+			ActorID:   comment.UserID,
+			Type:      model.NotificationReply,
+			PostID:    &comment.PostID,
+			CommentID: &comment.ID,
+			GroupKey:  fmt.Sprintf("reply:%d", parent.ID),
+		})
+		notified = append(notified, parent.UserID)
+	}
+	if postAuthorID != comment.UserID && (parent == nil || parent.UserID != postAuthorID) {
+		s.notify(&model.NotificationEvent{
+			UserID:    postAuthorID,
+			ActorID:   comment.UserID,
+			Type:      model.NotificationComment,
+			PostID:    &comment.PostID,
+			CommentID: &comment.ID,
+			GroupKey:  fmt.Sprintf("comment:%d", comment.PostID),
+		})
+	}
+	return append(notified, postAuthorID)
+}
+
+// CommentRemoved убирает все уведомления об удалённом комментарии
+func (s *NotificationService) CommentRemoved(commentID int) {
+	if err := s.notificationRepo.DeleteByComment(commentID); err != nil {
+		log.Printf("Не удалось убрать уведомления комментария %d: %v", commentID, err)
+	}
+}
+
+// Mentioned приводит уведомления об упоминаниях в тексте поста (commentID == nil)
+// или комментария к текущему списку mentions. skip — кому об этом тексте уже сообщили
+func (s *NotificationService) Mentioned(actorID, postID int, commentID *int, mentions []model.Mention, skip ...int) {
+	skipped := map[int]bool{actorID: true}
+	for _, id := range skip {
+		skipped[id] = true
+	}
+
+	keep := []int64{} // Не nil: пустой массив, а не NULL в запросе
+	for _, m := range mentions {
+		if skipped[m.UserID] {
+			continue
+		}
+		skipped[m.UserID] = true
+		keep = append(keep, int64(m.UserID))
+
+		key := fmt.Sprintf("mention:post:%d", postID)
+		if commentID != nil {
+			key = fmt.Sprintf("mention:comment:%d", *commentID)
+		}
+		s.notify(&model.NotificationEvent{
+			UserID:    m.UserID,
+			ActorID:   actorID,
+			Type:      model.NotificationMention,
+			PostID:    &postID,
+			CommentID: commentID,
+			GroupKey:  key,
+		})
+	}
+
+	if err := s.notificationRepo.DeleteStaleMentions(postID, commentID, keep); err != nil {
+		log.Printf("Не удалось убрать устаревшие упоминания поста %d: %v", postID, err)
+	}
+}
+
+// List возвращает страницу групп уведомлений с готовым текстом
+func (s *NotificationService) List(userID int, page model.PageRequest) (*model.Page[*model.Notification], error) {
+	notifications, err := s.notificationRepo.GetByUserID(userID, normalizePage(page))
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range notifications.Items {
+		n.Summary = notificationSummary(n)
+	}
+	return notifications, nil
+}
+
+// UnreadCount возвращает число непрочитанных групп
+func (s *NotificationService) UnreadCount(userID int) (int, error) {
+	return s.notificationRepo.CountUnread(userID)
+}
+
+// MarkRead отмечает группу уведомлений прочитанной
+func (s *NotificationService) MarkRead(userID, groupID int) error {
+	found, err := s.notificationRepo.MarkRead(userID, groupID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// MarkAllRead отмечает прочитанными все уведомления
+func (s *NotificationService) MarkAllRead(userID int) error {
+	return s.notificationRepo.MarkAllRead(userID)
+}
+
+// notify сохраняет событие и сообщает о нём в поток получателя; о своих действиях не уведомляем
+func (s *NotificationService) notify(e *model.NotificationEvent) {
+	if e.UserID == e.ActorID {
+		return
+	}
+	if err := s.notificationRepo.Create(e); err != nil {
+		log.Printf("Не удалось создать уведомление %s для user_id=%d: %v", e.Type, e.UserID, err)
+		return
+	}
+	s.stream.Notified(e)
+}
+
+// retract удаляет событие отменённого действия
+func (s *NotificationService) retract(e *model.NotificationEvent) {
+	if err := s.notificationRepo.DeleteEvent(e); err != nil {
+		log.Printf("Не удалось убрать уведомление %s для user_id=%d: %v", e.Type, e.UserID, err)
+	}
+}
+
+func likeEvent(actorID int, post *model.Post) *model.NotificationEvent {
+	return &model.NotificationEvent{
+		UserID:   post.UserID,
+		ActorID:  actorID,
+		Type:     model.NotificationLike,
+		PostID:   &post.ID,
+		GroupKey: fmt.Sprintf("like:%d", post.ID),
+	}
+}
+
+func followEvent(followerID, followingID int) *model.NotificationEvent {
+	return &model.NotificationEvent{
+		UserID:   followingID,
+		ActorID:  followerID,
+		Type:     model.NotificationFollow,
+		GroupKey: "follow",
+	}
+}
+
+func followRequestEvent(followerID, followingID int) *model.NotificationEvent {
+	return &model.NotificationEvent{
+		UserID:   followingID,
+		ActorID:  followerID,
+		Type:     model.NotificationFollowRequest,
+		GroupKey: "follow_request",
+	}
+}
+
+// notificationSummary собирает текст вида «alice и ещё 5 оценили ваш пост»
+func notificationSummary(n *model.Notification) string {
+	verbs := notificationVerbs[n.Type]
+	if n.ActorsCount <= 1 {
+		return n.Actor.Username + " " + verbs[0]
+	}
+	return fmt.Sprintf("%s и ещё %d %s", n.Actor.Username, n.ActorsCount-1, verbs[1])
+}
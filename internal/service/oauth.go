@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"social-network/internal/model"
+	"social-network/internal/oidc"
+	"social-network/internal/repository"
+)
+
+// oauthStateTTL — сколько живёт незавершённый вход через провайдера
+const oauthStateTTL = 10 * time.Minute
+
+var (
+	ErrUnknownProvider       = errors.New("неизвестный провайдер")
+	ErrProviderUnavailable   = errors.New("провайдер недоступен")
+	ErrOAuthState            = errors.New("state недействителен или истёк")
+	ErrOAuthExchange         = errors.New("провайдер не подтвердил вход")
+	ErrOAuthEmailMissing     = errors.New("провайдер не передал email")
+	ErrOAuthEmailTaken       = errors.New("аккаунт с таким email уже существует")
+	ErrIdentityLinked        = errors.New("внешний аккаунт уже привязан к другому пользователю")
+	ErrProviderAlreadyLinked = errors.New("провайдер уже привязан к аккаунту")
+	ErrIdentityNotFound      = errors.New("провайдер не привязан")
+	ErrLastLoginMethod       = errors.New("нельзя отвязать единственный способ входа")
+)
+
+// OAuthResult — итог callback: вход (Login) или привязка к текущему аккаунту (Linked)
+type OAuthResult struct {
+	Login  *model.LoginResult
+	Linked *model.UserIdentity
+}
+
+// OAuthService — вход и привязка аккаунтов через провайдеров OpenID Connect
+type OAuthService struct {
+	auth         *AuthService
+	userRepo     repository.UserRepository
+	identityRepo repository.IdentityRepository
+	stateRepo    repository.OAuthStateRepository
+	providers    map[string]*oidc.Provider
+}
+
+// NewOAuthService создаёт сервис внешнего входа. Сессии выдаёт authService —
+// так вход через провайдера подчиняется тем же правилам, включая 2FA
+func NewOAuthService(
+	authService *AuthService,
+	userRepo repository.UserRepository,
+	identityRepo repository.IdentityRepository,
+	stateRepo repository.OAuthStateRepository,
+	providers []*oidc.Provider,
+) *OAuthService {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+	return &OAuthService{
+		auth:         authService,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		stateRepo:    stateRepo,
+		providers:    byName,
+	}
+}
+
+// Start начинает вход через провайдера и возвращает адрес его страницы входа и state.
+// linkUserID != 0 — пользователь привязывает провайдера к своему аккаунту
+func (s *OAuthService) Start(ctx context.Context, providerName string, linkUserID int) (authURL, state string, err error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	now := s.auth.now()
+	if err := s.stateRepo.DeleteExpired(now); err != nil {
+		return "", "", err
+	}
+
+	state = oidc.GenerateState()
+	nonce := oidc.GenerateState()
+	verifier := oidc.GenerateVerifier()
+
+	pending := &model.OAuthState{
+		StateHash:    hashToken(state),
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    now.Add(oauthStateTTL),
+	}
+	if linkUserID != 0 {
+		pending.UserID = &linkUserID
+	}
+
+	authURL, err = provider.AuthCodeURL(ctx, state, nonce, oidc.S256Challenge(verifier))
+	if err != nil {
+		log.Printf("Провайдер %s: %v", providerName, err)
+		return "", "", ErrProviderUnavailable
+	}
+	if err := s.stateRepo.Create(pending); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// Callback завершает вход: проверяет state, обменивает код и входит или привязывает аккаунт
+func (s *OAuthService) Callback(ctx context.Context, providerName, code, state string, client model.ClientInfo) (*OAuthResult, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	// State одноразовый: удаляется сразу, даже если дальше что-то пойдёт не так
+	pending, err := s.stateRepo.Consume(hashToken(state))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOAuthState
+		}
+		return nil, err
+	}
+	if pending.Provider != providerName || s.auth.now().After(pending.ExpiresAt) {
+		return nil, ErrOAuthState
+	}
+
+	claims, err := provider.Exchange(ctx, code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		log.Printf("Провайдер %s: %v", providerName, err)
+		return nil, ErrOAuthExchange
+	}
+
+	if pending.UserID != nil {
+		identity, err := s.link(*pending.UserID, providerName, claims)
+		if err != nil {
+			return nil, err
+		}
+		return &OAuthResult{Linked: identity}, nil
+	}
+
+	result, err := s.login(providerName, claims, client)
+	if err != nil {
+		return nil, err
+	}
+	return &OAuthResult{Login: result}, nil
+}
+
+// login входит по привязанному аккаунту или регистрирует нового пользователя.
+// Существующий аккаунт с тем же email автоматически не привязывается: иначе владелец
+// аккаунта у провайдера получил бы доступ к чужому профилю. Привязка — только из профиля
+func (s *OAuthService) login(providerName string, claims *oidc.Claims, client model.ClientInfo) (*model.LoginResult, error) {
+	identity, err := s.identityRepo.GetBySubject(providerName, claims.Subject)
+	if err == nil {
+		user, err := s.userRepo.GetByID(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return s.auth.completeLogin(user, client)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if claims.Email == "" {
+		return nil, ErrOAuthEmailMissing
+	}
+	if _, err := s.userRepo.GetByEmail(claims.Email); err == nil {
+		return nil, ErrOAuthEmailTaken
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	username, err := s.freeUsername(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// Пустой хеш не совпадает ни с одним паролем; задать пароль можно через сброс
+	user, err := s.userRepo.Create(username, claims.Email, "")
+	if err != nil {
+		return nil, err
+	}
+	if claims.EmailVerified {
+		if err := s.userRepo.MarkEmailVerified(user.ID); err != nil {
+			return nil, err
+		}
+		user, err = s.userRepo.GetByID(user.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.identityRepo.Create(&model.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.auth.completeLogin(user, client)
+}
+
+// link привязывает внешний аккаунт к пользователю
+func (s *OAuthService) link(userID int, providerName string, claims *oidc.Claims) (*model.UserIdentity, error) {
+	existing, err := s.identityRepo.GetBySubject(providerName, claims.Subject)
+	if err == nil {
+		if existing.UserID != userID {
+			return nil, ErrIdentityLinked
+		}
+		return existing, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	identities, err := s.identityRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range identities {
+		if identity.Provider == providerName {
+			return nil, ErrProviderAlreadyLinked
+		}
+	}
+
+	identity := &model.UserIdentity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	if err := s.identityRepo.Create(identity); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// ListIdentities возвращает привязанные внешние аккаунты пользователя
+func (s *OAuthService) ListIdentities(userID int) ([]*model.UserIdentity, error) {
+	return s.identityRepo.GetByUserID(userID)
+}
+
+// Unlink отвязывает провайдера, если у пользователя останется способ войти
+func (s *OAuthService) Unlink(userID int, providerName string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	identities, err := s.identityRepo.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash == "" && len(identities) == 1 && identities[0].Provider == providerName {
+		return ErrLastLoginMethod
+	}
+
+	deleted, err := s.identityRepo.Delete(userID, providerName)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrIdentityNotFound
+	}
+	return nil
+}
+
+// freeUsername подбирает свободный username из данных провайдера
+func (s *OAuthService) freeUsername(claims *oidc.Claims) (string, error) {
+	base := sanitizeUsername(claims.PreferredUsername)
+	if base == "" {
+		base = sanitizeUsername(strings.SplitN(claims.Email, "@", 2)[0])
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for range 10 {
+		_, err := s.userRepo.GetByUsername(candidate)
+		if errors.Is(err, sql.ErrNoRows) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		n, _ := rand.Int(rand.Reader, big.NewInt(10000))
+		candidate = fmt.Sprintf("%s_%04d", base, n.Int64())
+	}
+	return "", fmt.Errorf("не удалось подобрать username для %q", base)
+}
+
+// sanitizeUsername оставляет в имени латиницу, цифры и подчёркивания
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		}
+		if b.Len() == 40 {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"social-network/internal/mailer"
+	"social-network/internal/model"
+)
+
+// passwordResetTTL — время жизни ссылки сброса пароля
+const passwordResetTTL = time.Hour
+
+var ErrWrongPassword = errors.New("неверный текущий пароль")
+
+// ChangePassword меняет пароль по текущему и завершает все остальные сессии
+func (s *AuthService) ChangePassword(userID int, currentSessionID, currentPassword, newPassword string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+		return ErrWrongPassword
+	}
+
+	if err := s.setPassword(userID, newPassword); err != nil {
+		return err
+	}
+
+	return s.tokenRepo.DeleteByUserIDExcept(userID, currentSessionID)
+}
+
+// RequestPasswordReset отправляет письмо со ссылкой сброса пароля.
+// Для неизвестного email молча ничего не делает, чтобы не раскрывать, кто зарегистрирован
+func (s *AuthService) RequestPasswordReset(email string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	// Действует только последняя ссылка
+	if err := s.userTokenRepo.DeleteByUserID(user.ID, model.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+
+	token := generateSecret()
+	expiresAt := s.now().Add(passwordResetTTL)
+	if err := s.userTokenRepo.Create(user.ID, model.TokenPurposePasswordReset, hashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf(
+			"Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s/reset-password?token=%s\n\n"+
+				"Ссылка действует 1 час. Если вы не запрашивали сброс, просто проигнорируйте это письмо.",
+			user.Username, s.appURL, token,
+		),
+	})
+}
+
+// ResetPassword задаёт новый пароль по токену из письма, завершает все сессии
+// и снимает блокировку входа
+func (s *AuthService) ResetPassword(token, newPassword string) error {
+	stored, err := s.userTokenRepo.Consume(model.TokenPurposePasswordReset, hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	if err := s.setPassword(stored.UserID, newPassword); err != nil {
+		return err
+	}
+
+	// Сброс пароля — штатный способ снять блокировку входа после перебора
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		return err
+	}
+	if err := s.resetLoginFailures(user.Email); err != nil {
+		return err
+	}
+
+	return s.tokenRepo.DeleteByUserID(stored.UserID)
+}
+
+// setPassword хеширует и сохраняет новый пароль, аннулируя неиспользованные ссылки сброса
+func (s *AuthService) setPassword(userID int, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(userID, string(hash)); err != nil {
+		return err
+	}
+	return s.userTokenRepo.DeleteByUserID(userID, model.TokenPurposePasswordReset)
+}
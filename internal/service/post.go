@@ -1,6 +1,7 @@
 package service
 
 import (
+	"database/sql"
 	"errors"
 
 	"social-network/internal/model"
@@ -8,28 +9,157 @@ import (
 )
 
 var (
-	ErrPostNotFound = errors.New("пост не найден")
-	ErrNotPostOwner = errors.New("вы не являетесь автором поста")
+	ErrPostNotFound  = errors.New("пост не найден")
+	ErrQuoteNotFound = errors.New("цитируемый пост не найден")
+	ErrNotPostOwner  = errors.New("вы не являетесь автором поста")
+	ErrEmptyPost     = errors.New("текст или изображение обязательны")
+	ErrEmptyHashtag  = errors.New("тег не может быть пустым")
+)
+
+const (
+	// trendingWindowHours — за сколько последних часов считаются популярные теги
+	trendingWindowHours = 24
+	// defaultTrendingLimit — сколько тегов отдавать, если limit не задан
+	defaultTrendingLimit = 10
 )
 
 // PostService — сервис работы с постами
 type PostService struct {
-	postRepo repository.PostRepository
+	postRepo    repository.PostRepository
+	repostRepo  repository.RepostRepository
+	hashtagRepo repository.HashtagRepository
+	userRepo    repository.UserRepository
+	blockRepo   repository.BlockRepository
+	followRepo  repository.FollowRepository
+
+	notifications *NotificationService
+	stream        *StreamService
 }
 
 // NewPostService создаёт сервис постов
-func NewPostService(postRepo repository.PostRepository) *PostService {
-	return &PostService{postRepo: postRepo}
+func NewPostService(
+	postRepo repository.PostRepository,
+	repostRepo repository.RepostRepository,
+	hashtagRepo repository.HashtagRepository,
+	userRepo repository.UserRepository,
+	blockRepo repository.BlockRepository,
+	followRepo repository.FollowRepository,
+	notifications *NotificationService,
+	stream *StreamService,
+) *PostService {
+	return &PostService{
+		postRepo:      postRepo,
+		repostRepo:    repostRepo,
+		hashtagRepo:   hashtagRepo,
+		userRepo:      userRepo,
+		blockRepo:     blockRepo,
+		followRepo:    followRepo,
+		notifications: notifications,
+		stream:        stream,
+	}
+}
+
+// Create создаёт новый пост. quoteOf — ID цитируемого поста, nil для обычного поста
+func (s *PostService) Create(userID int, content, imageURL string, quoteOf *int) (*model.Post, error) {
+	if quoteOf != nil {
+		if err := requirePost(s.postRepo, *quoteOf, userID); err != nil {
+			if err == ErrPostNotFound {
+				return nil, ErrQuoteNotFound
+			}
+			return nil, err
+		}
+	}
+	mentions, err := resolveMentions(s.userRepo, s.blockRepo, s.followRepo, userID, userID, content)
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.postRepo.Create(&model.PostDraft{
+		UserID:   userID,
+		Content:  content,
+		ImageURL: imageURL,
+		QuoteOf:  quoteOf,
+		Hashtags: extractHashtags(content),
+		Mentions: mentions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.notifications.Mentioned(userID, post.ID, nil, mentions)
+	s.stream.PostCreated(post)
+	return post, nil
 }
 
-// Create создаёт новый пост
-func (s *PostService) Create(userID int, content, imageURL string) (*model.Post, error) {
-	return s.postRepo.Create(userID, content, imageURL)
+// Repost делится постом с подписчиками; повторный репост ничего не меняет
+func (s *PostService) Repost(userID, postID int) error {
+	if err := requirePost(s.postRepo, postID, userID); err != nil {
+		return err
+	}
+	if err := s.repostRepo.Repost(userID, postID); err != nil {
+		return err
+	}
+	s.stream.CountersChanged(postID)
+	return nil
+}
+
+// Unrepost отменяет репост; свой репост можно снять и после блокировки автора
+func (s *PostService) Unrepost(userID, postID int) error {
+	if _, err := findPost(s.postRepo, postID); err != nil {
+		return err
+	}
+	if err := s.repostRepo.Unrepost(userID, postID); err != nil {
+		return err
+	}
+	s.stream.CountersChanged(postID)
+	return nil
 }
 
 // GetByID возвращает пост по ID
 func (s *PostService) GetByID(id, currentUserID int) (*model.Post, error) {
-	return s.postRepo.GetByID(id, currentUserID)
+	post, err := s.postRepo.GetByID(id, currentUserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPostNotFound
+	}
+	return post, err
+}
+
+// Update меняет текст поста (только автор). Прежняя версия сохраняется в истории,
+// теги и упоминания пересобираются по новому тексту
+func (s *PostService) Update(postID, userID int, content string) (*model.Post, error) {
+	post, err := s.postRepo.GetByID(postID, userID)
+	if err != nil {
+		return nil, ErrPostNotFound
+	}
+	if post.UserID != userID {
+		return nil, ErrNotPostOwner
+	}
+	if content == "" && post.ImageURL == "" {
+		return nil, ErrEmptyPost
+	}
+
+	// Без изменений — не плодим одинаковые версии
+	if content == post.Content {
+		return post, nil
+	}
+
+	mentions, err := resolveMentions(s.userRepo, s.blockRepo, s.followRepo, userID, userID, content)
+	if err != nil {
+		return nil, err
+	}
+	draft := &model.PostDraft{UserID: userID, Content: content, Hashtags: extractHashtags(content), Mentions: mentions}
+	if err := s.postRepo.Update(postID, draft); err != nil {
+		return nil, err
+	}
+	s.notifications.Mentioned(userID, postID, nil, mentions)
+	return s.postRepo.GetByID(postID, userID)
+}
+
+// GetRevisions возвращает прежние версии поста, от новых к старым. Историю поста,
+// который viewerID не видит, не отдаём
+func (s *PostService) GetRevisions(postID, viewerID int) ([]*model.PostRevision, error) {
+	if err := requirePost(s.postRepo, postID, viewerID); err != nil {
+		return nil, err
+	}
+	return s.postRepo.GetRevisions(postID)
 }
 
 // Delete удаляет пост (только автор)
@@ -44,26 +174,85 @@ func (s *PostService) Delete(postID, userID int) error {
 	return s.postRepo.Delete(postID)
 }
 
-// GetFeed возвращает глобальную ленту
-func (s *PostService) GetFeed(currentUserID, limit, offset int) ([]*model.Post, error) {
-	if limit <= 0 || limit > 50 {
-		limit = 50
+// ForceDelete удаляет пост без проверки авторства — для модераторов
+func (s *PostService) ForceDelete(postID int) error {
+	if _, err := findPost(s.postRepo, postID); err != nil {
+		return ErrPostNotFound
 	}
-	return s.postRepo.GetFeed(currentUserID, limit, offset)
+	return s.postRepo.Delete(postID)
 }
 
-// GetFollowingFeed возвращает ленту подписок
-func (s *PostService) GetFollowingFeed(userID, limit, offset int) ([]*model.Post, error) {
-	if limit <= 0 || limit > 50 {
-		limit = 50
+// requirePost возвращает ErrPostNotFound, если поста нет или viewerID его не видит
+func requirePost(postRepo repository.PostRepository, postID, viewerID int) error {
+	_, err := getPost(postRepo, postID, viewerID)
+	return err
+}
+
+// getPost возвращает пост глазами viewerID или ErrPostNotFound — в том числе при
+// блокировке между ним и автором и закрытом аккаунте автора. viewerID 0 — гость
+func getPost(postRepo repository.PostRepository, postID, viewerID int) (*model.Post, error) {
+	post, err := postRepo.GetByID(postID, viewerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPostNotFound
+	}
+	return post, err
+}
+
+// findPost возвращает пост без проверок видимости или ErrPostNotFound — для действий,
+// которые не должны зависеть от блокировок и приватности
+func findPost(postRepo repository.PostRepository, postID int) (*model.Post, error) {
+	post, err := postRepo.GetByIDUnfiltered(postID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPostNotFound
+	}
+	return post, err
+}
+
+// maxPageLimit — размер страницы по умолчанию и максимальный
+const maxPageLimit = 50
+
+// normalizePage подставляет размер страницы по умолчанию и ограничивает слишком большой
+func normalizePage(page model.PageRequest) model.PageRequest {
+	if page.Limit <= 0 || page.Limit > maxPageLimit {
+		page.Limit = maxPageLimit
 	}
-	return s.postRepo.GetFollowingFeed(userID, limit, offset)
+	if page.After != nil || page.Offset < 0 {
+		page.Offset = 0
+	}
+	return page
+}
+
+// GetFeed возвращает глобальную ленту
+func (s *PostService) GetFeed(currentUserID int, page model.PageRequest) (*model.Page[*model.Post], error) {
+	return s.postRepo.GetFeed(currentUserID, normalizePage(page))
+}
+
+// GetFollowingFeed возвращает ленту подписок
+func (s *PostService) GetFollowingFeed(userID int, page model.PageRequest) (*model.Page[*model.Post], error) {
+	return s.postRepo.GetFollowingFeed(userID, normalizePage(page))
 }
 
 // GetByUserID возвращает посты конкретного пользователя
-func (s *PostService) GetByUserID(userID, currentUserID, limit, offset int) ([]*model.Post, error) {
-	if limit <= 0 || limit > 50 {
-		limit = 50
+func (s *PostService) GetByUserID(userID, currentUserID int, page model.PageRequest) (*model.Page[*model.Post], error) {
+	return s.postRepo.GetByUserID(userID, currentUserID, normalizePage(page))
+}
+
+// GetByHashtag возвращает посты с тегом; регистр и ведущий # не важны
+func (s *PostService) GetByHashtag(tag string, currentUserID int, page model.PageRequest) (*model.Page[*model.Post], error) {
+	tag = normalizeHashtag(tag)
+	if tag == "" {
+		return nil, ErrEmptyHashtag
+	}
+	return s.postRepo.GetByHashtag(tag, currentUserID, normalizePage(page))
+}
+
+// Trending возвращает популярные теги за последние сутки
+func (s *PostService) Trending(limit int) ([]*model.TrendingTag, error) {
+	if limit <= 0 {
+		limit = defaultTrendingLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
 	}
-	return s.postRepo.GetByUserID(userID, currentUserID, limit, offset)
+	return s.hashtagRepo.Trending(trendingWindowHours, limit)
 }
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"social-network/internal/model"
+	"social-network/internal/repository"
+)
+
+var (
+	ErrEmptySearchQuery   = errors.New("поисковый запрос не может быть пустым")
+	ErrSearchQueryTooLong = errors.New("слишком длинный поисковый запрос")
+	ErrUnknownSearchType  = errors.New("неизвестный тип поиска: ожидается posts или users")
+)
+
+// maxSearchQueryLength — ограничение длины запроса в символах
+const maxSearchQueryLength = 200
+
+// SearchService — сервис поиска постов и пользователей
+type SearchService struct {
+	searchRepo repository.SearchRepository
+}
+
+// NewSearchService создаёт сервис поиска
+func NewSearchService(searchRepo repository.SearchRepository) *SearchService {
+	return &SearchService{searchRepo: searchRepo}
+}
+
+// SearchPosts ищет посты по тексту
+func (s *SearchService) SearchPosts(req model.SearchRequest, currentUserID int) (*model.Page[*model.PostSearchResult], error) {
+	req, err := normalizeSearch(req)
+	if err != nil {
+		return nil, err
+	}
+	return s.searchRepo.SearchPosts(req, currentUserID)
+}
+
+// SearchUsers ищет пользователей по имени и bio
+func (s *SearchService) SearchUsers(req model.SearchRequest, currentUserID int) (*model.Page[*model.UserSearchResult], error) {
+	req, err := normalizeSearch(req)
+	if err != nil {
+		return nil, err
+	}
+	return s.searchRepo.SearchUsers(req, currentUserID)
+}
+
+// normalizeSearch проверяет запрос и подставляет размер страницы
+func normalizeSearch(req model.SearchRequest) (model.SearchRequest, error) {
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		return req, ErrEmptySearchQuery
+	}
+	if utf8.RuneCountInString(req.Query) > maxSearchQueryLength {
+		return req, ErrSearchQueryTooLong
+	}
+	if req.Limit <= 0 || req.Limit > maxPageLimit {
+		req.Limit = maxPageLimit
+	}
+	return req, nil
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+
+	"social-network/internal/model"
+	"social-network/internal/realtime"
+	"social-network/internal/repository"
+)
+
+var ErrTooManyStreamPosts = errors.New("слишком много постов для отслеживания")
+
+// MaxStreamPosts — сколько постов можно отслеживать в одном потоке
+const MaxStreamPosts = 100
+
+// StreamService — события в реальном времени. Другие сервисы сообщают ему о действиях,
+// а клиенты потока подписываются на свои темы: личную, авторов из подписок и
+// открытые у них посты
+type StreamService struct {
+	hub        realtime.Hub
+	postRepo   repository.PostRepository
+	followRepo repository.FollowRepository
+	muteRepo   repository.MuteRepository
+}
+
+// NewStreamService создаёт сервис событий
+func NewStreamService(
+	hub realtime.Hub,
+	postRepo repository.PostRepository,
+	followRepo repository.FollowRepository,
+	muteRepo repository.MuteRepository,
+) *StreamService {
+	return &StreamService{hub: hub, postRepo: postRepo, followRepo: followRepo, muteRepo: muteRepo}
+}
+
+// Subscribe подписывает пользователя на его события, новые посты его подписок (кроме
+// скрытых им авторов) и счётчики тех постов из postIDs, которые он видит. Подписку нужно закрыть
+func (s *StreamService) Subscribe(userID int, postIDs []int) (*realtime.Subscription, error) {
+	if len(postIDs) > MaxStreamPosts {
+		return nil, ErrTooManyStreamPosts
+	}
+
+	// Сначала личная тема: подписки и скрытия, оформленные во время запроса,
+	// придут событиями following и muting
+	topics := []string{realtime.UserTopic(userID)}
+	for _, id := range postIDs {
+		// Недоступные посты пропускаем молча: на экране клиента могли остаться устаревшие
+		_, err := s.postRepo.GetByID(id, userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		topics = append(topics, realtime.PostTopic(id))
+	}
+	sub := s.hub.Subscribe(topics...)
+
+	following, err := s.followRepo.GetFollowingIDs(userID)
+	if err != nil {
+		sub.Close()
+		return nil, err
+	}
+	muted, err := s.muteRepo.GetMutedIDs(userID)
+	if err != nil {
+		sub.Close()
+		return nil, err
+	}
+	var authors []string
+	for _, id := range following {
+		if !slices.Contains(muted, id) {
+			authors = append(authors, realtime.AuthorTopic(id))
+		}
+	}
+	sub.Add(authors...)
+	return sub, nil
+}
+
+// Track поддерживает подписку userID на авторов в актуальном состоянии по событиям
+// following и muting: посты автора приходят, только если на него подписаны и он не скрыт
+func (s *StreamService) Track(userID int, sub *realtime.Subscription, event realtime.Event) {
+	switch event.Type {
+	case realtime.EventFollowing:
+		var change model.FollowingChange
+		if err := json.Unmarshal(event.Data, &change); err != nil {
+			return
+		}
+		if !change.Following {
+			sub.Remove(realtime.AuthorTopic(change.UserID))
+			return
+		}
+		muted, err := s.muteRepo.IsMuted(userID, change.UserID)
+		if err != nil {
+			log.Printf("Не удалось проверить скрытие %d пользователем %d: %v", change.UserID, userID, err)
+			return
+		}
+		if !muted {
+			sub.Add(realtime.AuthorTopic(change.UserID))
+		}
+	case realtime.EventMuting:
+		var change model.MutingChange
+		if err := json.Unmarshal(event.Data, &change); err != nil {
+			return
+		}
+		if change.Muted {
+			sub.Remove(realtime.AuthorTopic(change.UserID))
+			return
+		}
+		following, err := s.followRepo.IsFollowing(userID, change.UserID)
+		if err != nil {
+			log.Printf("Не удалось проверить подписку %d на %d: %v", userID, change.UserID, err)
+			return
+		}
+		if following {
+			sub.Add(realtime.AuthorTopic(change.UserID))
+		}
+	}
+}
+
+// Notified сообщает получателю о новом уведомлении
+func (s *StreamService) Notified(e *model.NotificationEvent) {
+	s.hub.Publish(realtime.UserTopic(e.UserID), realtime.NewEvent(realtime.EventNotification, model.NotificationNotice{
+		Type:      e.Type,
+		ActorID:   e.ActorID,
+		PostID:    e.PostID,
+		CommentID: e.CommentID,
+	}))
+}
+
+// PostCreated сообщает подписчикам автора о новом посте
+func (s *StreamService) PostCreated(post *model.Post) {
+	s.hub.Publish(realtime.AuthorTopic(post.UserID), realtime.NewEvent(realtime.EventPostCreated, model.PostNotice{
+		PostID:   post.ID,
+		UserID:   post.UserID,
+		Username: post.Username,
+	}))
+}
+
+// CountersChanged рассылает текущие счётчики поста
+func (s *StreamService) CountersChanged(postID int) {
+	post, err := s.postRepo.GetByIDUnfiltered(postID)
+	if err != nil {
+		log.Printf("Не удалось получить счётчики поста %d: %v", postID, err)
+		return
+	}
+	s.hub.Publish(realtime.PostTopic(postID), realtime.NewEvent(realtime.EventPostCounters, model.PostCounters{
+		PostID:        post.ID,
+		LikesCount:    post.LikesCount,
+		CommentsCount: post.CommentsCount,
+		RepostsCount:  post.RepostsCount,
+	}))
+}
+
+// FollowingChanged сообщает подписчику об изменении его подписок, чтобы его потоки
+// начали или перестали получать посты автора
+func (s *StreamService) FollowingChanged(followerID, followingID int, following bool) {
+	s.hub.Publish(realtime.UserTopic(followerID), realtime.NewEvent(realtime.EventFollowing, model.FollowingChange{
+		UserID:    followingID,
+		Following: following,
+	}))
+}
+
+// MutingChanged сообщает пользователю, что он скрыл автора или вернул его в ленты,
+// чтобы его потоки перестали или снова начали получать посты автора
+func (s *StreamService) MutingChanged(muterID, mutedID int, muted bool) {
+	s.hub.Publish(realtime.UserTopic(muterID), realtime.NewEvent(realtime.EventMuting, model.MutingChange{
+		UserID: mutedID,
+		Muted:  muted,
+	}))
+}
+
+// AccountSuspended сообщает потокам заблокированного пользователя, что их пора закрыть
+func (s *StreamService) AccountSuspended(userID int) {
+	s.hub.Publish(realtime.UserTopic(userID), realtime.NewEvent(realtime.EventSuspended, nil))
+}
+
+// MessageSent сообщает участникам диалога о новом сообщении
+func (s *StreamService) MessageSent(msg *model.Message, participantIDs []int) {
+	event := realtime.NewEvent(realtime.EventMessage, model.MessageNotice{
+		ConversationID: msg.ConversationID,
+		MessageID:      msg.ID,
+		UserID:         msg.UserID,
+	})
+	for _, id := range participantIDs {
+		s.hub.Publish(realtime.UserTopic(id), event)
+	}
+}
+
+// ConversationRead сообщает остальным участникам диалога, до какого сообщения его прочитал userID
+func (s *StreamService) ConversationRead(conversationID, userID, lastReadID int, participantIDs []int) {
+	event := realtime.NewEvent(realtime.EventMessageRead, model.ReadNotice{
+		ConversationID: conversationID,
+		UserID:         userID,
+		MessageID:      lastReadID,
+	})
+	for _, id := range participantIDs {
+		if id != userID {
+			s.hub.Publish(realtime.UserTopic(id), event)
+		}
+	}
+}
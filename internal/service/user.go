@@ -20,12 +20,13 @@ var (
 
 // UserService — сервис работы с профилями
 type UserService struct {
-	userRepo repository.UserRepository
+	userRepo    repository.UserRepository
+	mentionRepo repository.MentionRepository
 }
 
 // NewUserService создаёт сервис пользователей
-func NewUserService(userRepo repository.UserRepository) *UserService {
-	return &UserService{userRepo: userRepo}
+func NewUserService(userRepo repository.UserRepository, mentionRepo repository.MentionRepository) *UserService {
+	return &UserService{userRepo: userRepo, mentionRepo: mentionRepo}
 }
 
 // GetProfile возвращает публичный профиль пользователя
@@ -38,11 +39,21 @@ func (s *UserService) GetByID(id int) (*model.User, error) {
 	return s.userRepo.GetByID(id)
 }
 
+// GetMentions возвращает посты и комментарии, где упомянули пользователя
+func (s *UserService) GetMentions(userID int, page model.PageRequest) (*model.Page[*model.MentionNotice], error) {
+	return s.mentionRepo.GetByUserID(userID, normalizePage(page))
+}
+
 // UpdateBio обновляет bio пользователя
 func (s *UserService) UpdateBio(id int, bio string) error {
 	return s.userRepo.UpdateBio(id, bio)
 }
 
+// SetMessagesFollowingOnly разрешает писать пользователю только тем, на кого он подписан
+func (s *UserService) SetMessagesFollowingOnly(id int, enabled bool) error {
+	return s.userRepo.SetMessagesFollowingOnly(id, enabled)
+}
+
 // UploadAvatar сохраняет аватарку и обновляет URL в БД
 func (s *UserService) UploadAvatar(userID int, file multipart.File, header *multipart.FileHeader) (string, error) {
 	// Проверяем MIME-тип
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"social-network/internal/mailer"
+	"social-network/internal/model"
+)
+
+// emailVerificationTTL — время жизни ссылки подтверждения email
+const emailVerificationTTL = 24 * time.Hour
+
+var (
+	ErrEmailNotVerified     = errors.New("email не подтверждён")
+	ErrEmailAlreadyVerified = errors.New("email уже подтверждён")
+)
+
+// VerifyEmail подтверждает email по токену из письма
+func (s *AuthService) VerifyEmail(token string) error {
+	stored, err := s.userTokenRepo.Consume(model.TokenPurposeEmailVerification, hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	if err := s.userRepo.MarkEmailVerified(stored.UserID); err != nil {
+		return err
+	}
+	return s.userTokenRepo.DeleteByUserID(stored.UserID, model.TokenPurposeEmailVerification)
+}
+
+// ResendVerification повторно отправляет письмо подтверждения
+func (s *AuthService) ResendVerification(userID int) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+	return s.sendVerification(user)
+}
+
+// EnsureVerified проверяет, может ли пользователь публиковать контент.
+// Если политика REQUIRE_EMAIL_VERIFICATION выключена, ограничений нет
+func (s *AuthService) EnsureVerified(userID int) error {
+	if !s.requireVerifiedEmail {
+		return nil
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// sendVerification выпускает новую ссылку подтверждения и отправляет её на email
+func (s *AuthService) sendVerification(user *model.User) error {
+	// Действует только последняя ссылка
+	if err := s.userTokenRepo.DeleteByUserID(user.ID, model.TokenPurposeEmailVerification); err != nil {
+		return err
+	}
+
+	token := generateSecret()
+	expiresAt := s.now().Add(emailVerificationTTL)
+	if err := s.userTokenRepo.Create(user.ID, model.TokenPurposeEmailVerification, hashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Подтверждение email",
+		Body: fmt.Sprintf(
+			"Здравствуйте, %s!\n\nЧтобы подтвердить email, перейдите по ссылке:\n%s/verify-email?token=%s\n\n"+
+				"Ссылка действует 24 часа.",
+			user.Username, s.appURL, token,
+		),
+	})
+}
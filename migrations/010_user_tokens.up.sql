@@ -0,0 +1,12 @@
+-- Одноразовые токены для действий по ссылке из письма (сброс пароля и т.п.)
+CREATE TABLE user_tokens (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose    VARCHAR(50) NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at    TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_tokens_user_id ON user_tokens(user_id, purpose);
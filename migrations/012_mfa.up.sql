@@ -0,0 +1,18 @@
+-- TOTP: секрет появляется при начале подключения, confirmed_at — после ввода первого кода
+CREATE TABLE user_totp (
+    user_id      INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret       TEXT NOT NULL,
+    confirmed_at TIMESTAMP,
+    last_step    BIGINT NOT NULL DEFAULT 0,
+    created_at   TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE mfa_recovery_codes (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash  TEXT NOT NULL,
+    used_at    TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
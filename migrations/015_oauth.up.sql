@@ -0,0 +1,22 @@
+-- Внешние аккаунты (OpenID Connect), привязанные к пользователям
+CREATE TABLE user_identities (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider   VARCHAR(50) NOT NULL,
+    subject    TEXT NOT NULL,
+    email      VARCHAR(255) DEFAULT '',
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (provider, subject),
+    UNIQUE (user_id, provider)
+);
+
+-- Незавершённые входы через провайдера: state, PKCE code_verifier и nonce
+CREATE TABLE oauth_states (
+    state_hash    TEXT PRIMARY KEY,
+    provider      VARCHAR(50) NOT NULL,
+    code_verifier TEXT NOT NULL,
+    nonce         TEXT NOT NULL,
+    user_id       INTEGER REFERENCES users(id) ON DELETE CASCADE, -- Задан при привязке к существующему аккаунту
+    expires_at    TIMESTAMP NOT NULL,
+    created_at    TIMESTAMP DEFAULT NOW()
+);
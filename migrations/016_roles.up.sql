@@ -0,0 +1,4 @@
+-- Роли пользователей и блокировка аккаунтов модераторами
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'moderator', 'admin'));
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
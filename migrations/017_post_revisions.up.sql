@@ -0,0 +1,13 @@
+-- Редактирование постов: отметка о правке и прежние версии
+ALTER TABLE posts ADD COLUMN edited_at TIMESTAMP;
+
+CREATE TABLE post_revisions (
+    id          SERIAL PRIMARY KEY,
+    post_id     INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    content     TEXT NOT NULL,
+    image_url   TEXT DEFAULT '',
+    created_at  TIMESTAMP NOT NULL, -- Когда эта версия была опубликована
+    replaced_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_post_revisions_post_id ON post_revisions(post_id, replaced_at DESC);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_follows_follower_created_at;
+DROP INDEX IF EXISTS idx_follows_following_created_at;
+DROP INDEX IF EXISTS idx_comments_post_created_at_id;
+DROP INDEX IF EXISTS idx_posts_user_created_at_id;
+DROP INDEX IF EXISTS idx_posts_created_at_id;
+CREATE INDEX idx_posts_created_at ON posts(created_at DESC);
@@ -0,0 +1,7 @@
+-- Индексы под курсорную пагинацию по (created_at, id)
+DROP INDEX IF EXISTS idx_posts_created_at;
+CREATE INDEX idx_posts_created_at_id ON posts(created_at DESC, id DESC);
+CREATE INDEX idx_posts_user_created_at_id ON posts(user_id, created_at DESC, id DESC);
+CREATE INDEX idx_comments_post_created_at_id ON comments(post_id, created_at, id);
+CREATE INDEX idx_follows_following_created_at ON follows(following_id, created_at DESC);
+CREATE INDEX idx_follows_follower_created_at ON follows(follower_id, created_at DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_comments_parent_created_at_id;
+ALTER TABLE comments DROP COLUMN IF EXISTS depth;
+ALTER TABLE comments DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,5 @@
+-- Ответы на комментарии: родитель и глубина вложенности
+ALTER TABLE comments ADD COLUMN parent_id INTEGER REFERENCES comments(id) ON DELETE CASCADE;
+ALTER TABLE comments ADD COLUMN depth INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_comments_parent_created_at_id ON comments(parent_id, created_at, id);
@@ -0,0 +1,5 @@
+-- Правка и удаление комментариев. Удалённый комментарий остаётся «надгробием»,
+-- чтобы не рвать ветку ответов; скрытый автором поста виден только участникам
+ALTER TABLE comments ADD COLUMN edited_at TIMESTAMP;
+ALTER TABLE comments ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE comments ADD COLUMN hidden_at TIMESTAMP;
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN IF EXISTS posts_count;
+ALTER TABLE users DROP COLUMN IF EXISTS following_count;
+ALTER TABLE users DROP COLUMN IF EXISTS followers_count;
+ALTER TABLE posts DROP COLUMN IF EXISTS comments_count;
+ALTER TABLE posts DROP COLUMN IF EXISTS likes_count;
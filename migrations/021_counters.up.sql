@@ -0,0 +1,17 @@
+-- Денормализованные счётчики вместо COUNT(*) на каждый запрос.
+-- Поддерживаются репозиториями в тех же транзакциях, что и изменения;
+-- пересчитать их можно командой go run ./cmd/recount
+ALTER TABLE posts ADD COLUMN likes_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN comments_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN followers_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN following_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN posts_count INTEGER NOT NULL DEFAULT 0;
+
+UPDATE posts p SET
+    likes_count = (SELECT COUNT(*) FROM likes WHERE post_id = p.id),
+    comments_count = (SELECT COUNT(*) FROM comments WHERE post_id = p.id AND deleted_at IS NULL);
+
+UPDATE users u SET
+    followers_count = (SELECT COUNT(*) FROM follows WHERE following_id = u.id),
+    following_count = (SELECT COUNT(*) FROM follows WHERE follower_id = u.id),
+    posts_count = (SELECT COUNT(*) FROM posts WHERE user_id = u.id);
@@ -0,0 +1,4 @@
+ALTER TABLE posts DROP COLUMN IF EXISTS is_quote;
+ALTER TABLE posts DROP COLUMN IF EXISTS quote_of;
+ALTER TABLE posts DROP COLUMN IF EXISTS reposts_count;
+DROP TABLE IF EXISTS reposts;
@@ -0,0 +1,16 @@
+-- Репосты и цитаты. Репост — отдельная запись, а не пост: удаление оригинала
+-- убирает и его репосты. Цитата — обычный пост со ссылкой на оригинал; если оригинал
+-- удалён, ссылка обнуляется, а is_quote остаётся, чтобы показать «пост удалён»
+CREATE TABLE reposts (
+    user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id    INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX idx_reposts_user_created_at ON reposts(user_id, created_at DESC);
+CREATE INDEX idx_reposts_post_id ON reposts(post_id);
+
+ALTER TABLE posts ADD COLUMN reposts_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN quote_of INTEGER REFERENCES posts(id) ON DELETE SET NULL;
+ALTER TABLE posts ADD COLUMN is_quote BOOLEAN NOT NULL DEFAULT FALSE;
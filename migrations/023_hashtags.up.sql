@@ -0,0 +1,17 @@
+-- Хештеги постов. created_at копирует время поста: по нему листается страница тега
+-- и считается окно трендов без JOIN с posts
+CREATE TABLE post_hashtags (
+    post_id    INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag        VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (post_id, tag)
+);
+
+CREATE INDEX idx_post_hashtags_tag_created_at ON post_hashtags(tag, created_at DESC, post_id DESC);
+CREATE INDEX idx_post_hashtags_created_at ON post_hashtags(created_at);
+
+-- Теги уже опубликованных постов
+INSERT INTO post_hashtags (post_id, tag, created_at)
+SELECT DISTINCT p.id, lower(m[2]), p.created_at
+FROM posts p, regexp_matches(p.content, '(^|[^[:alnum:]_])#([[:alnum:]_]{1,100})', 'g') AS m
+WHERE m[2] ~ '[[:alpha:]]';
@@ -0,0 +1,15 @@
+-- Упоминания @username в постах и комментариях. Смещения — в символах текста.
+-- У упоминания в комментарии post_id — пост этого комментария
+CREATE TABLE mentions (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id      INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id   INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+    start_offset INTEGER NOT NULL,
+    length       INTEGER NOT NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_mentions_post ON mentions(post_id) WHERE comment_id IS NULL;
+CREATE INDEX idx_mentions_comment ON mentions(comment_id) WHERE comment_id IS NOT NULL;
+CREATE INDEX idx_mentions_user_created_at ON mentions(user_id, created_at DESC, id DESC);
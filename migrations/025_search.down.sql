@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_bio_trgm;
+DROP INDEX IF EXISTS idx_users_username_trgm;
+DROP INDEX IF EXISTS idx_posts_search_vector;
+ALTER TABLE posts DROP COLUMN IF EXISTS search_vector;
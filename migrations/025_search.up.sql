@@ -0,0 +1,12 @@
+-- Полнотекстовый поиск по постам: вектор пересчитывается самой базой при записи.
+-- Конфигурация russian стеммит и кириллицу, и латиницу
+ALTER TABLE posts ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('russian', content)) STORED;
+
+CREATE INDEX idx_posts_search_vector ON posts USING GIN (search_vector);
+
+-- Нечёткий поиск пользователей по имени и bio
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_users_username_trgm ON users USING GIN (username gin_trgm_ops);
+CREATE INDEX idx_users_bio_trgm ON users USING GIN (bio gin_trgm_ops);
@@ -0,0 +1,23 @@
+-- Уведомления: одна строка — одно событие для получателя user_id от actor_id.
+-- Непрочитанные события с одинаковым group_key собираются в группу: group_id указывает
+-- на первое событие группы, у самого первого он NULL
+CREATE TABLE notifications (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_id   INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type       VARCHAR(20) NOT NULL,
+    post_id    INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+    group_key  VARCHAR(100) NOT NULL,
+    group_id   INTEGER,
+    read_at    TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Повторный лайк или подписка не плодят одинаковых событий
+CREATE UNIQUE INDEX idx_notifications_event
+    ON notifications(user_id, actor_id, type, COALESCE(post_id, 0), COALESCE(comment_id, 0));
+
+CREATE INDEX idx_notifications_user_group ON notifications(user_id, (COALESCE(group_id, id)));
+CREATE INDEX idx_notifications_unread ON notifications(user_id, group_key) WHERE read_at IS NULL;
+CREATE INDEX idx_notifications_comment ON notifications(comment_id) WHERE comment_id IS NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN IF EXISTS messages_following_only;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS conversation_participants;
+DROP TABLE IF EXISTS conversations;
@@ -0,0 +1,37 @@
+-- Личные сообщения. Личный диалог (is_group = FALSE) между двумя пользователями
+-- единственный: direct_key — «меньший_ID:больший_ID»
+CREATE TABLE conversations (
+    id              SERIAL PRIMARY KEY,
+    is_group        BOOLEAN NOT NULL DEFAULT FALSE,
+    title           VARCHAR(100) NOT NULL DEFAULT '',
+    direct_key      VARCHAR(30) UNIQUE,
+    last_message_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Участники со своими настройками диалога. last_read_id — последнее прочитанное
+-- сообщение; left_at — участник вышел из группы и больше её не видит
+CREATE TABLE conversation_participants (
+    conversation_id INTEGER NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id         INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_read_id    INTEGER NOT NULL DEFAULT 0,
+    muted           BOOLEAN NOT NULL DEFAULT FALSE,
+    archived        BOOLEAN NOT NULL DEFAULT FALSE,
+    joined_at       TIMESTAMP NOT NULL DEFAULT NOW(),
+    left_at         TIMESTAMP,
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE TABLE messages (
+    id              SERIAL PRIMARY KEY,
+    conversation_id INTEGER NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id         INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content         TEXT NOT NULL,
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_conversation_participants_user ON conversation_participants(user_id) WHERE left_at IS NULL;
+CREATE INDEX idx_messages_conversation ON messages(conversation_id, created_at DESC, id DESC);
+
+-- Принимать сообщения только от тех, на кого подписан сам пользователь
+ALTER TABLE users ADD COLUMN messages_following_only BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,20 @@
+-- Блокировки: заблокированный и заблокировавший не видят друг друга и не могут
+-- взаимодействовать. Обратный индекс — для проверки «в любую сторону»
+CREATE TABLE user_blocks (
+    blocker_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    blocked_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX idx_user_blocks_blocked ON user_blocks(blocked_id, blocker_id);
+
+-- Скрытые пользователи: их посты не попадают в ленты скрывшего, сами они об этом не знают
+CREATE TABLE user_mutes (
+    muter_id   INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    muted_id   INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (muter_id, muted_id),
+    CHECK (muter_id <> muted_id)
+);
@@ -0,0 +1,13 @@
+-- Закрытый аккаунт: посты и списки подписок видны только одобренным подписчикам
+ALTER TABLE users ADD COLUMN is_private BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Запросы на подписку к закрытым аккаунтам, ожидающие решения владельца
+CREATE TABLE follow_requests (
+    follower_id  INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    following_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (follower_id, following_id),
+    CHECK (follower_id <> following_id)
+);
+
+CREATE INDEX idx_follow_requests_following ON follow_requests(following_id, created_at DESC);
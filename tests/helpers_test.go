@@ -2,16 +2,23 @@ package tests
 
 import (
 	"bytes"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"regexp"
+	"sync"
 	"testing"
+	"time"
 
 	"social-network/internal/config"
 	"social-network/internal/database"
 	"social-network/internal/handler"
+	"social-network/internal/mailer"
+	"social-network/internal/oidc"
+	"social-network/internal/realtime"
 	"social-network/internal/repository"
 	"social-network/internal/service"
 )
@@ -19,6 +26,9 @@ import (
 // testApp — тестовое приложение
 type testApp struct {
 	handler http.Handler
+	db      *sql.DB
+	mailer  *mailer.MemoryMailer
+	oidc    *mockOIDC // Провайдер «mock» для входа через OpenID Connect
 }
 
 // tokenPair — пара токенов из ответа
@@ -33,8 +43,22 @@ type authResponse struct {
 	Tokens tokenPair      `json:"tokens"`
 }
 
-// setupTestApp создаёт тестовое приложение с чистой БД
-func setupTestApp(t *testing.T) *testApp {
+// pageResponse — конверт постраничных списков
+type pageResponse struct {
+	Items      []map[string]any `json:"items"`
+	NextCursor string           `json:"next_cursor"`
+}
+
+// decodePage разбирает ответ списка в конверте {items, next_cursor}
+func decodePage(w *httptest.ResponseRecorder) pageResponse {
+	var page pageResponse
+	json.NewDecoder(w.Body).Decode(&page)
+	return page
+}
+
+// setupTestApp создаёт тестовое приложение с чистой БД.
+// opts позволяют поменять настройки авторизации для отдельного теста
+func setupTestApp(t *testing.T, opts ...func(*service.AuthConfig)) *testApp {
 	t.Helper()
 
 	// Используем тестовую БД
@@ -55,7 +79,7 @@ func setupTestApp(t *testing.T) *testApp {
 	}
 
 	// Чистим все таблицы перед тестами
-	tables := []string{"refresh_tokens", "likes", "follows", "comments", "posts", "users", "schema_migrations"}
+	tables := []string{"oauth_states", "user_identities", "login_attempts", "personal_access_tokens", "mfa_recovery_codes", "user_totp", "user_tokens", "refresh_tokens", "follow_requests", "user_mutes", "user_blocks", "messages", "conversation_participants", "conversations", "notifications", "mentions", "post_hashtags", "reposts", "likes", "follows", "comments", "post_revisions", "posts", "users", "schema_migrations"}
 	for _, table := range tables {
 		db.Exec("DROP TABLE IF EXISTS " + table + " CASCADE")
 	}
@@ -74,22 +98,64 @@ func setupTestApp(t *testing.T) *testApp {
 	commentRepo := repository.NewCommentRepo(db)
 	followRepo := repository.NewFollowRepo(db)
 	likeRepo := repository.NewLikeRepo(db)
+	repostRepo := repository.NewRepostRepo(db)
+	hashtagRepo := repository.NewHashtagRepo(db)
+	mentionRepo := repository.NewMentionRepo(db)
+	searchRepo := repository.NewSearchRepo(db)
+	notificationRepo := repository.NewNotificationRepo(db)
+	conversationRepo := repository.NewConversationRepo(db)
+	blockRepo := repository.NewBlockRepo(db)
+	muteRepo := repository.NewMuteRepo(db)
 	tokenRepo := repository.NewTokenRepo(db)
+	userTokenRepo := repository.NewUserTokenRepo(db)
+	mfaRepo := repository.NewMFARepo(db)
+	accessTokenRepo := repository.NewAccessTokenRepo(db)
+	identityRepo := repository.NewIdentityRepo(db)
+	oauthStateRepo := repository.NewOAuthStateRepo(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepo(db)
+	hub := realtime.NewMemoryHub()
+
+	mail := mailer.NewMemoryMailer()
+
+	authCfg := service.AuthConfig{
+		JWTSecret: cfg.JWTSecret,
+		AppURL:    "http://localhost",
+	}
+	for _, opt := range opts {
+		opt(&authCfg)
+	}
+
+	authService := service.NewAuthService(userRepo, tokenRepo, userTokenRepo, mfaRepo, accessTokenRepo, loginAttemptRepo, mail, authCfg)
+	streamService := service.NewStreamService(hub, postRepo, followRepo, muteRepo)
+	notificationService := service.NewNotificationService(notificationRepo, streamService)
+	userService := service.NewUserService(userRepo, mentionRepo)
+	postService := service.NewPostService(postRepo, repostRepo, hashtagRepo, userRepo, blockRepo, followRepo, notificationService, streamService)
+	commentService := service.NewCommentService(commentRepo, postRepo, userRepo, blockRepo, followRepo, notificationService, streamService)
+	followService := service.NewFollowService(followRepo, blockRepo, userRepo, notificationService, streamService)
+	likeService := service.NewLikeService(likeRepo, postRepo, notificationService, streamService)
+	adminService := service.NewAdminService(userRepo, tokenRepo, streamService, authCfg.Now)
+	searchService := service.NewSearchService(searchRepo)
+	messageService := service.NewMessageService(conversationRepo, userRepo, followRepo, blockRepo, streamService)
+	blockService := service.NewBlockService(blockRepo, muteRepo, userRepo, streamService)
 
-	authService := service.NewAuthService(userRepo, tokenRepo, cfg.JWTSecret)
-	userService := service.NewUserService(userRepo)
-	postService := service.NewPostService(postRepo)
-	commentService := service.NewCommentService(commentRepo)
-	followService := service.NewFollowService(followRepo)
-	likeService := service.NewLikeService(likeRepo)
+	mockProvider := newMockOIDC(t)
+	oauthService := service.NewOAuthService(authService, userRepo, identityRepo, oauthStateRepo, []*oidc.Provider{
+		oidc.NewProvider(oidc.Config{
+			Name:         "mock",
+			Issuer:       mockProvider.issuer(),
+			ClientID:     mockOIDCClientID,
+			ClientSecret: mockOIDCClientSecret,
+			RedirectURL:  "http://localhost/v1/auth/oauth/mock/callback",
+		}, mockProvider.server.Client()),
+	})
 
-	h := handler.NewHandler(authService, userService, postService, commentService, followService, likeService)
+	h := handler.NewHandler(authService, userService, postService, commentService, followService, likeService, oauthService, adminService, searchService, notificationService, streamService, messageService, blockService, false)
 
 	t.Cleanup(func() {
 		db.Close()
 	})
 
-	return &testApp{handler: h.Routes()}
+	return &testApp{handler: h.Routes(), db: db, mailer: mail, oidc: mockProvider}
 }
 
 // registerUser регистрирует пользователя и возвращает authResponse
@@ -149,11 +215,55 @@ func (app *testApp) authRequest(method, path, token string, body any) *httptest.
 	return w
 }
 
+// lastMailToken извлекает токен из ссылки в последнем письме на адрес to
+func (app *testApp) lastMailToken(t *testing.T, to string) string {
+	t.Helper()
+	msg, ok := app.mailer.Last(to)
+	if !ok {
+		t.Fatalf("Письмо на %s не отправлено", to)
+	}
+	match := regexp.MustCompile(`token=([0-9a-f]+)`).FindStringSubmatch(msg.Body)
+	if match == nil {
+		t.Fatalf("В письме нет токена: %s", msg.Body)
+	}
+	return match[1]
+}
+
+// setRole назначает роль напрямую в БД — так в проде назначают первого администратора
+func (app *testApp) setRole(t *testing.T, userID any, role string) {
+	t.Helper()
+	if _, err := app.db.Exec(`UPDATE users SET role = $1 WHERE id = $2`, role, userID); err != nil {
+		t.Fatalf("Не удалось назначить роль: %v", err)
+	}
+}
+
 // request выполняет публичный HTTP-запрос
 func (app *testApp) request(method, path string, body any) *httptest.ResponseRecorder {
 	return app.authRequest(method, path, "", body)
 }
 
+// fakeClock — управляемые часы для тестов, зависящих от времени
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Now()}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
 func getTestEnv(key, fallback string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
@@ -1,10 +1,25 @@
 package tests
 
 import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"social-network/internal/keys"
+	"social-network/internal/repository"
+	"social-network/internal/service"
+	"social-network/internal/totp"
 )
 
 // ==================== АВТОРИЗАЦИЯ ====================
@@ -93,135 +108,2805 @@ func TestRefresh(t *testing.T) {
 		t.Errorf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
 	}
 
-	var tokens tokenPair
-	json.NewDecoder(w.Body).Decode(&tokens)
-	if tokens.AccessToken == "" {
-		t.Error("Новый access_token пустой")
+	var tokens tokenPair
+	json.NewDecoder(w.Body).Decode(&tokens)
+	if tokens.AccessToken == "" {
+		t.Error("Новый access_token пустой")
+	}
+}
+
+func TestLogout(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.authRequest("POST", "/v1/auth/logout", resp.Tokens.AccessToken, map[string]string{
+		"refresh_token": resp.Tokens.RefreshToken,
+	})
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидали 200, получили %d", w.Code)
+	}
+
+	// Повторный refresh должен провалиться
+	w = app.request("POST", "/v1/auth/refresh", map[string]string{
+		"refresh_token": resp.Tokens.RefreshToken,
+	})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Ожидали 401 после логаута, получили %d", w.Code)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	// Обмениваем исходный токен на новую пару
+	w := app.request("POST", "/v1/auth/refresh", map[string]string{
+		"refresh_token": resp.Tokens.RefreshToken,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	var rotated tokenPair
+	json.NewDecoder(w.Body).Decode(&rotated)
+
+	// Повторно предъявляем уже обменянный токен
+	w = app.request("POST", "/v1/auth/refresh", map[string]string{
+		"refresh_token": resp.Tokens.RefreshToken,
+	})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Повтор: ожидали 401, получили %d", w.Code)
+	}
+
+	// Вся ветка семейства отозвана — новый токен тоже не работает
+	w = app.request("POST", "/v1/auth/refresh", map[string]string{
+		"refresh_token": rotated.RefreshToken,
+	})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("После отзыва семейства ожидали 401, получили %d", w.Code)
+	}
+}
+
+func TestRefreshReuseKeepsOtherFamilies(t *testing.T) {
+	app := setupTestApp(t)
+	first := app.registerUser(t, "testuser", "test@test.com", "password123")
+	second := app.loginUser(t, "test@test.com", "password123")
+
+	app.request("POST", "/v1/auth/refresh", map[string]string{"refresh_token": first.Tokens.RefreshToken})
+	app.request("POST", "/v1/auth/refresh", map[string]string{"refresh_token": first.Tokens.RefreshToken})
+
+	// Второй вход — отдельное семейство, его отзыв не задевает
+	w := app.request("POST", "/v1/auth/refresh", map[string]string{
+		"refresh_token": second.Tokens.RefreshToken,
+	})
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// ==================== СЕССИИ ====================
+
+func TestListSessions(t *testing.T) {
+	app := setupTestApp(t)
+	first := app.registerUser(t, "testuser", "test@test.com", "password123")
+	app.loginUser(t, "test@test.com", "password123")
+
+	w := app.authRequest("GET", "/v1/auth/sessions", first.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	var sessions []map[string]any
+	json.NewDecoder(w.Body).Decode(&sessions)
+	if len(sessions) != 2 {
+		t.Fatalf("Ожидали 2 сессии, получили %d", len(sessions))
+	}
+
+	current := 0
+	for _, s := range sessions {
+		if s["is_current"] == true {
+			current++
+		}
+	}
+	if current != 1 {
+		t.Errorf("Ожидали ровно одну текущую сессию, получили %d", current)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	app := setupTestApp(t)
+	first := app.registerUser(t, "testuser", "test@test.com", "password123")
+	second := app.loginUser(t, "test@test.com", "password123")
+
+	// Находим ID второй (не текущей) сессии
+	w := app.authRequest("GET", "/v1/auth/sessions", first.Tokens.AccessToken, nil)
+	var sessions []map[string]any
+	json.NewDecoder(w.Body).Decode(&sessions)
+	var otherID string
+	for _, s := range sessions {
+		if s["is_current"] != true {
+			otherID = s["id"].(string)
+		}
+	}
+
+	w = app.authRequest("DELETE", "/v1/auth/sessions/"+otherID, first.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	w = app.request("POST", "/v1/auth/refresh", map[string]string{"refresh_token": second.Tokens.RefreshToken})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Refresh завершённой сессии: ожидали 401, получили %d", w.Code)
+	}
+
+	// Чужую или несуществующую сессию завершить нельзя
+	w = app.authRequest("DELETE", "/v1/auth/sessions/unknown", first.Tokens.AccessToken, nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Ожидали 404, получили %d", w.Code)
+	}
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	app := setupTestApp(t)
+	first := app.registerUser(t, "testuser", "test@test.com", "password123")
+	second := app.loginUser(t, "test@test.com", "password123")
+
+	w := app.authRequest("DELETE", "/v1/auth/sessions", first.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	w = app.request("POST", "/v1/auth/refresh", map[string]string{"refresh_token": second.Tokens.RefreshToken})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Другая сессия: ожидали 401, получили %d", w.Code)
+	}
+
+	w = app.request("POST", "/v1/auth/refresh", map[string]string{"refresh_token": first.Tokens.RefreshToken})
+	if w.Code != http.StatusOK {
+		t.Errorf("Текущая сессия: ожидали 200, получили %d", w.Code)
+	}
+}
+
+// ==================== ПАРОЛИ ====================
+
+func TestChangePassword(t *testing.T) {
+	app := setupTestApp(t)
+	first := app.registerUser(t, "testuser", "test@test.com", "password123")
+	second := app.loginUser(t, "test@test.com", "password123")
+
+	w := app.authRequest("PUT", "/v1/users/me/password", first.Tokens.AccessToken, map[string]string{
+		"current_password": "password123", "new_password": "newpassword456",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	// Остальные сессии завершены
+	w = app.request("POST", "/v1/auth/refresh", map[string]string{"refresh_token": second.Tokens.RefreshToken})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Другая сессия: ожидали 401, получили %d", w.Code)
+	}
+
+	app.loginUser(t, "test@test.com", "newpassword456")
+}
+
+func TestChangePasswordWrongCurrent(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.authRequest("PUT", "/v1/users/me/password", resp.Tokens.AccessToken, map[string]string{
+		"current_password": "wrongpassword", "new_password": "newpassword456",
+	})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Ожидали 403, получили %d", w.Code)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.request("POST", "/v1/auth/password/forgot", map[string]string{"email": "test@test.com"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	token := app.lastMailToken(t, "test@test.com")
+
+	w = app.request("POST", "/v1/auth/password/reset", map[string]string{
+		"token": token, "new_password": "newpassword456",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	// Токен одноразовый
+	w = app.request("POST", "/v1/auth/password/reset", map[string]string{
+		"token": token, "new_password": "anotherpassword",
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Повторный сброс: ожидали 400, получили %d", w.Code)
+	}
+
+	// Все сессии завершены, вход — только с новым паролем
+	w = app.request("POST", "/v1/auth/refresh", map[string]string{"refresh_token": resp.Tokens.RefreshToken})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Старая сессия: ожидали 401, получили %d", w.Code)
+	}
+	app.loginUser(t, "test@test.com", "newpassword456")
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	app := setupTestApp(t)
+
+	w := app.request("POST", "/v1/auth/password/forgot", map[string]string{"email": "noone@test.com"})
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидали 200, получили %d", w.Code)
+	}
+	if _, ok := app.mailer.Last("noone@test.com"); ok {
+		t.Error("Письмо на неизвестный адрес не должно отправляться")
+	}
+}
+
+// ==================== ПОДТВЕРЖДЕНИЕ EMAIL ====================
+
+func TestVerifyEmail(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	if resp.User["email_verified_at"] != nil {
+		t.Error("Новый пользователь не должен быть подтверждён")
+	}
+
+	token := app.lastMailToken(t, "test@test.com")
+	w := app.request("POST", "/v1/auth/verify-email", map[string]string{"token": token})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	w = app.authRequest("GET", "/v1/users/me", resp.Tokens.AccessToken, nil)
+	var user map[string]any
+	json.NewDecoder(w.Body).Decode(&user)
+	if user["email_verified_at"] == nil {
+		t.Error("email_verified_at пустой после подтверждения")
+	}
+
+	// Повторное подтверждение тем же токеном
+	w = app.request("POST", "/v1/auth/verify-email", map[string]string{"token": token})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидали 400, получили %d", w.Code)
+	}
+}
+
+func TestResendVerification(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	first := app.lastMailToken(t, "test@test.com")
+
+	w := app.authRequest("POST", "/v1/auth/verify-email/resend", resp.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	// Старая ссылка больше не действует
+	w = app.request("POST", "/v1/auth/verify-email", map[string]string{"token": first})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Старый токен: ожидали 400, получили %d", w.Code)
+	}
+
+	w = app.request("POST", "/v1/auth/verify-email", map[string]string{
+		"token": app.lastMailToken(t, "test@test.com"),
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d", w.Code)
+	}
+
+	w = app.authRequest("POST", "/v1/auth/verify-email/resend", resp.Tokens.AccessToken, nil)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Уже подтверждён: ожидали 409, получили %d", w.Code)
+	}
+}
+
+func TestUnverifiedCannotPost(t *testing.T) {
+	app := setupTestApp(t, func(cfg *service.AuthConfig) {
+		cfg.RequireVerifiedEmail = true
+	})
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
+		"content": "Спам",
+	})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Ожидали 403, получили %d", w.Code)
+	}
+
+	app.request("POST", "/v1/auth/verify-email", map[string]string{
+		"token": app.lastMailToken(t, "test@test.com"),
+	})
+
+	w = app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
+		"content": "Теперь можно",
+	})
+	if w.Code != http.StatusCreated {
+		t.Errorf("После подтверждения ожидали 201, получили %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// ==================== ДВУХФАКТОРНАЯ АУТЕНТИФИКАЦИЯ ====================
+
+// enableTOTP подключает TOTP пользователю и возвращает секрет и коды восстановления
+func enableTOTP(t *testing.T, app *testApp, clock *fakeClock, accessToken string) (string, []string) {
+	t.Helper()
+	w := app.authRequest("POST", "/v1/users/me/mfa/totp", accessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Подключение TOTP: ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	var enrollment map[string]string
+	json.NewDecoder(w.Body).Decode(&enrollment)
+	if !strings.HasPrefix(enrollment["otpauth_uri"], "otpauth://totp/") {
+		t.Errorf("otpauth_uri = %q", enrollment["otpauth_uri"])
+	}
+	secret := enrollment["secret"]
+
+	code, _ := totp.Code(secret, clock.Now())
+	w = app.authRequest("POST", "/v1/users/me/mfa/totp/confirm", accessToken, map[string]string{"code": code})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Подтверждение TOTP: ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	var confirm struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	json.NewDecoder(w.Body).Decode(&confirm)
+	return secret, confirm.RecoveryCodes
+}
+
+// loginMFAChallenge выполняет первый шаг входа и возвращает mfa_token
+func loginMFAChallenge(t *testing.T, app *testApp) string {
+	t.Helper()
+	w := app.request("POST", "/v1/auth/login", map[string]string{
+		"email": "test@test.com", "password": "password123",
+	})
+	var result map[string]any
+	json.NewDecoder(w.Body).Decode(&result)
+	if result["mfa_required"] != true || result["tokens"] != nil {
+		t.Fatalf("Ожидали MFA-челлендж вместо токенов: %v", result)
+	}
+	return result["mfa_token"].(string)
+}
+
+func TestTOTPLogin(t *testing.T) {
+	clock := newFakeClock()
+	app := setupTestApp(t, func(cfg *service.AuthConfig) { cfg.Now = clock.Now })
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	secret, recovery := enableTOTP(t, app, clock, resp.Tokens.AccessToken)
+	if len(recovery) != 10 {
+		t.Errorf("Ожидали 10 кодов восстановления, получили %d", len(recovery))
+	}
+
+	mfaToken := loginMFAChallenge(t, app)
+
+	// Код того же шага уже использован при подтверждении
+	code, _ := totp.Code(secret, clock.Now())
+	w := app.request("POST", "/v1/auth/login/mfa", map[string]string{"mfa_token": mfaToken, "code": code})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Повтор кода: ожидали 401, получили %d", w.Code)
+	}
+
+	clock.Advance(30 * time.Second)
+	code, _ = totp.Code(secret, clock.Now())
+	w = app.request("POST", "/v1/auth/login/mfa", map[string]string{"mfa_token": mfaToken, "code": code})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	var result authResponse
+	json.NewDecoder(w.Body).Decode(&result)
+	if result.Tokens.AccessToken == "" {
+		t.Error("access_token пустой после второго шага")
+	}
+}
+
+func TestTOTPRecoveryCode(t *testing.T) {
+	clock := newFakeClock()
+	app := setupTestApp(t, func(cfg *service.AuthConfig) { cfg.Now = clock.Now })
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	_, recovery := enableTOTP(t, app, clock, resp.Tokens.AccessToken)
+
+	mfaToken := loginMFAChallenge(t, app)
+	w := app.request("POST", "/v1/auth/login/mfa", map[string]string{
+		"mfa_token": mfaToken, "recovery_code": recovery[0],
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	// Код восстановления одноразовый
+	w = app.request("POST", "/v1/auth/login/mfa", map[string]string{
+		"mfa_token": mfaToken, "recovery_code": recovery[0],
+	})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Повтор кода восстановления: ожидали 401, получили %d", w.Code)
+	}
+}
+
+func TestMFAChallengeExpires(t *testing.T) {
+	clock := newFakeClock()
+	app := setupTestApp(t, func(cfg *service.AuthConfig) { cfg.Now = clock.Now })
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	secret, _ := enableTOTP(t, app, clock, resp.Tokens.AccessToken)
+
+	mfaToken := loginMFAChallenge(t, app)
+
+	clock.Advance(6 * time.Minute)
+	code, _ := totp.Code(secret, clock.Now())
+	w := app.request("POST", "/v1/auth/login/mfa", map[string]string{"mfa_token": mfaToken, "code": code})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Истёкший челлендж: ожидали 401, получили %d", w.Code)
+	}
+}
+
+func TestMFATokenIsNotAccessToken(t *testing.T) {
+	clock := newFakeClock()
+	app := setupTestApp(t, func(cfg *service.AuthConfig) { cfg.Now = clock.Now })
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	enableTOTP(t, app, clock, resp.Tokens.AccessToken)
+
+	mfaToken := loginMFAChallenge(t, app)
+	w := app.authRequest("GET", "/v1/users/me", mfaToken, nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Ожидали 401, получили %d", w.Code)
+	}
+}
+
+// ==================== КЛЮЧИ ПОДПИСИ JWT ====================
+
+// tokenKid возвращает kid из заголовка JWT без проверки подписи
+func tokenKid(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Не удалось разобрать токен: %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestJWKSVerifiesAccessToken(t *testing.T) {
+	signingKeys, err := keys.NewManager(t.TempDir(), keys.AlgEdDSA, time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := setupTestApp(t, func(cfg *service.AuthConfig) { cfg.Keys = signingKeys })
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.request("GET", "/.well-known/jwks.json", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d", w.Code)
+	}
+	var set keys.JWKS
+	json.NewDecoder(w.Body).Decode(&set)
+	if len(set.Keys) != 1 {
+		t.Fatalf("Ожидали 1 ключ, получили %d", len(set.Keys))
+	}
+
+	// Другой сервис проверяет токен только по опубликованному ключу
+	jwk := set.Keys[0]
+	if jwk.Kid != tokenKid(t, resp.Tokens.AccessToken) {
+		t.Errorf("kid токена не совпадает с kid из JWKS")
+	}
+	pub, _ := base64.RawURLEncoding.DecodeString(jwk.X)
+	_, err = jwt.Parse(resp.Tokens.AccessToken, func(*jwt.Token) (any, error) {
+		return ed25519.PublicKey(pub), nil
+	}, jwt.WithValidMethods([]string{"EdDSA"}))
+	if err != nil {
+		t.Errorf("Подпись не проверилась ключом из JWKS: %v", err)
+	}
+}
+
+func TestKeyRotationKeepsOldTokensValid(t *testing.T) {
+	signingKeys, err := keys.NewManager(t.TempDir(), keys.AlgRS256, time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := setupTestApp(t, func(cfg *service.AuthConfig) { cfg.Keys = signingKeys })
+	before := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	if err := signingKeys.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	after := app.loginUser(t, "test@test.com", "password123")
+
+	if tokenKid(t, before.Tokens.AccessToken) == tokenKid(t, after.Tokens.AccessToken) {
+		t.Error("После ротации токены должны подписываться новым ключом")
+	}
+
+	// Токен, подписанный прежним ключом, продолжает работать
+	w := app.authRequest("GET", "/v1/users/me", before.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("Старый токен: ожидали 200, получили %d", w.Code)
+	}
+
+	w = app.request("GET", "/.well-known/jwks.json", nil)
+	var set keys.JWKS
+	json.NewDecoder(w.Body).Decode(&set)
+	if len(set.Keys) != 2 {
+		t.Errorf("Ожидали 2 ключа в JWKS, получили %d", len(set.Keys))
+	}
+}
+
+func TestRotatedKeyPublishedBeforeSigning(t *testing.T) {
+	signingKeys, err := keys.NewManager(t.TempDir(), keys.AlgRS256, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := setupTestApp(t, func(cfg *service.AuthConfig) { cfg.Keys = signingKeys })
+	before := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	if err := signingKeys.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	after := app.loginUser(t, "test@test.com", "password123")
+
+	// Новый ключ уже в JWKS, но подписывать начнёт только после активации,
+	// чтобы другие сервисы успели обновить кэш JWKS
+	if tokenKid(t, before.Tokens.AccessToken) != tokenKid(t, after.Tokens.AccessToken) {
+		t.Error("До активации токены должны подписываться прежним ключом")
+	}
+	w := app.request("GET", "/.well-known/jwks.json", nil)
+	var set keys.JWKS
+	json.NewDecoder(w.Body).Decode(&set)
+	if len(set.Keys) != 2 {
+		t.Errorf("Ожидали 2 ключа в JWKS, получили %d", len(set.Keys))
+	}
+}
+
+func TestJWKSHidesSharedSecret(t *testing.T) {
+	app := setupTestApp(t)
+
+	w := app.request("GET", "/.well-known/jwks.json", nil)
+	var set keys.JWKS
+	json.NewDecoder(w.Body).Decode(&set)
+	if len(set.Keys) != 0 {
+		t.Errorf("В режиме HS256 JWKS должен быть пустым, получили %d ключей", len(set.Keys))
+	}
+}
+
+// ==================== ЗАЩИТА ОТ ПЕРЕБОРА ====================
+
+// attemptLogin выполняет попытку входа и возвращает ответ как есть
+func (app *testApp) attemptLogin(email, password string) *httptest.ResponseRecorder {
+	return app.request("POST", "/v1/auth/login", map[string]string{"email": email, "password": password})
+}
+
+// withThrottle подменяет политику защиты входа и часы
+func withThrottle(clock *fakeClock, throttle service.LoginThrottleConfig) func(*service.AuthConfig) {
+	return func(cfg *service.AuthConfig) {
+		cfg.Now = clock.Now
+		cfg.LoginThrottle = throttle
+	}
+}
+
+func TestLoginBackoff(t *testing.T) {
+	clock := newFakeClock()
+	app := setupTestApp(t, func(cfg *service.AuthConfig) { cfg.Now = clock.Now })
+	app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	// Первые неудачи проходят без задержки
+	for i := 0; i < 4; i++ {
+		if w := app.attemptLogin("test@test.com", "wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Попытка %d: ожидали 401, получили %d", i+1, w.Code)
+		}
+	}
+
+	// Дальше — пауза, даже с верным паролем
+	w := app.attemptLogin("test@test.com", "password123")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Ожидали 429, получили %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Retry-After = %q, ожидали 1", w.Header().Get("Retry-After"))
+	}
+
+	clock.Advance(time.Second)
+	if w := app.attemptLogin("test@test.com", "password123"); w.Code != http.StatusOK {
+		t.Errorf("После паузы: ожидали 200, получили %d", w.Code)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	clock := newFakeClock()
+	app := setupTestApp(t, withThrottle(clock, service.LoginThrottleConfig{
+		FreeAttempts: 100, IPFreeAttempts: 100, BaseDelay: time.Second, MaxDelay: time.Second,
+		LockoutThreshold: 3, LockoutDuration: 15 * time.Minute, Window: time.Hour,
+	}))
+	app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	for i := 0; i < 3; i++ {
+		app.attemptLogin("test@test.com", "wrong")
+	}
+
+	w := app.attemptLogin("test@test.com", "password123")
+	if w.Code != http.StatusLocked {
+		t.Fatalf("Ожидали 423, получили %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "900" {
+		t.Errorf("Retry-After = %q, ожидали 900", w.Header().Get("Retry-After"))
+	}
+
+	clock.Advance(15 * time.Minute)
+	if w := app.attemptLogin("test@test.com", "password123"); w.Code != http.StatusOK {
+		t.Errorf("После блокировки: ожидали 200, получили %d", w.Code)
+	}
+}
+
+func TestLockoutClearedByPasswordReset(t *testing.T) {
+	clock := newFakeClock()
+	app := setupTestApp(t, withThrottle(clock, service.LoginThrottleConfig{
+		FreeAttempts: 100, IPFreeAttempts: 100, BaseDelay: time.Second, MaxDelay: time.Second,
+		LockoutThreshold: 3, LockoutDuration: time.Hour, Window: time.Hour,
+	}))
+	app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	for i := 0; i < 3; i++ {
+		app.attemptLogin("test@test.com", "wrong")
+	}
+	if w := app.attemptLogin("test@test.com", "password123"); w.Code != http.StatusLocked {
+		t.Fatalf("Ожидали 423, получили %d", w.Code)
+	}
+
+	app.request("POST", "/v1/auth/password/forgot", map[string]string{"email": "test@test.com"})
+	w := app.request("POST", "/v1/auth/password/reset", map[string]string{
+		"token": app.lastMailToken(t, "test@test.com"), "new_password": "newpassword456",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Сброс пароля: ожидали 200, получили %d", w.Code)
+	}
+
+	if w := app.attemptLogin("test@test.com", "newpassword456"); w.Code != http.StatusOK {
+		t.Errorf("После сброса пароля: ожидали 200, получили %d", w.Code)
+	}
+}
+
+func TestLoginThrottledPerIP(t *testing.T) {
+	clock := newFakeClock()
+	app := setupTestApp(t, withThrottle(clock, service.LoginThrottleConfig{
+		FreeAttempts: 100, IPFreeAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour,
+		LockoutThreshold: 100, LockoutDuration: time.Hour, Window: time.Hour,
+	}))
+	app.registerUser(t, "victim", "victim@test.com", "password123")
+
+	// Перебор по разным аккаунтам с одного адреса
+	for i := 0; i < 4; i++ {
+		app.attemptLogin(fmt.Sprintf("user%d@test.com", i), "guess")
+	}
+
+	w := app.attemptLogin("victim@test.com", "password123")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Ожидали 429 по IP, получили %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "60" {
+		t.Errorf("Retry-After = %q, ожидали 60", w.Header().Get("Retry-After"))
+	}
+}
+
+// ==================== ВХОД ЧЕРЕЗ OPENID CONNECT ====================
+
+// oauthStateCookieFrom достаёт cookie со state из ответа start
+func oauthStateCookieFrom(w *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "oauth_state" {
+			return c
+		}
+	}
+	return nil
+}
+
+// completeOAuth проходит страницу провайдера и вызывает наш callback, как сделал бы браузер
+func (app *testApp) completeOAuth(t *testing.T, authURL string, stateCookie *http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+	client := *app.oidc.server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Запрос к провайдеру: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Провайдер ответил %d", resp.StatusCode)
+	}
+
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+	req := httptest.NewRequest("GET", callback.RequestURI(), nil)
+	if stateCookie != nil {
+		req.AddCookie(stateCookie)
+	}
+	w := httptest.NewRecorder()
+	app.handler.ServeHTTP(w, req)
+	return w
+}
+
+// oauthLogin входит через провайдера mock под указанным пользователем
+func (app *testApp) oauthLogin(t *testing.T, user mockOIDCUser) *httptest.ResponseRecorder {
+	t.Helper()
+	app.oidc.signInAs(user)
+
+	w := app.request("GET", "/v1/auth/oauth/mock/start", nil)
+	if w.Code != http.StatusFound {
+		t.Fatalf("start: ожидали 302, получили %d: %s", w.Code, w.Body.String())
+	}
+	return app.completeOAuth(t, w.Header().Get("Location"), oauthStateCookieFrom(w))
+}
+
+func TestOAuthSignupAndLogin(t *testing.T) {
+	app := setupTestApp(t)
+	user := mockOIDCUser{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true, Username: "Alice"}
+
+	w := app.oauthLogin(t, user)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	var first authResponse
+	json.NewDecoder(w.Body).Decode(&first)
+	if first.User["username"] != "alice" || first.User["email_verified_at"] == nil {
+		t.Errorf("Ожидали нового подтверждённого пользователя alice, получили %v", first.User)
+	}
+
+	me := app.authRequest("GET", "/v1/users/me", first.Tokens.AccessToken, nil)
+	if me.Code != http.StatusOK {
+		t.Errorf("Токен после входа через провайдера: ожидали 200, получили %d", me.Code)
+	}
+
+	// Повторный вход попадает в тот же аккаунт
+	w = app.oauthLogin(t, user)
+	var second authResponse
+	json.NewDecoder(w.Body).Decode(&second)
+	if second.User["id"] != first.User["id"] {
+		t.Errorf("Повторный вход создал другой аккаунт: %v != %v", second.User["id"], first.User["id"])
+	}
+}
+
+func TestOAuthDoesNotTakeOverExistingEmail(t *testing.T) {
+	app := setupTestApp(t)
+	app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.oauthLogin(t, mockOIDCUser{Subject: "sub-1", Email: "test@test.com", EmailVerified: true})
+	if w.Code != http.StatusConflict {
+		t.Errorf("Ожидали 409, получили %d", w.Code)
+	}
+}
+
+func TestOAuthLinkAccount(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	app.oidc.signInAs(mockOIDCUser{Subject: "sub-1", Email: "other@example.com"})
+
+	w := app.authRequest("POST", "/v1/users/me/identities/mock", resp.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	var start map[string]string
+	json.NewDecoder(w.Body).Decode(&start)
+
+	w = app.completeOAuth(t, start["authorization_url"], oauthStateCookieFrom(w))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Привязка: ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	// Теперь вход через провайдера открывает существующий аккаунт
+	w = app.oauthLogin(t, mockOIDCUser{Subject: "sub-1", Email: "other@example.com"})
+	var login authResponse
+	json.NewDecoder(w.Body).Decode(&login)
+	if login.User["id"] != resp.User["id"] {
+		t.Errorf("Ожидали вход в аккаунт %v, получили %v", resp.User["id"], login.User["id"])
+	}
+
+	w = app.authRequest("GET", "/v1/users/me/identities", resp.Tokens.AccessToken, nil)
+	var identities []map[string]any
+	json.NewDecoder(w.Body).Decode(&identities)
+	if len(identities) != 1 || identities[0]["provider"] != "mock" {
+		t.Errorf("Ожидали одну привязку mock, получили %v", identities)
+	}
+}
+
+func TestOAuthCallbackRequiresOwnState(t *testing.T) {
+	app := setupTestApp(t)
+	app.oidc.signInAs(mockOIDCUser{Subject: "sub-1", Email: "alice@example.com"})
+
+	// Ссылка на callback без cookie браузера, начавшего вход, не принимается
+	w := app.request("GET", "/v1/auth/oauth/mock/start", nil)
+	w = app.completeOAuth(t, w.Header().Get("Location"), nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Без cookie: ожидали 400, получили %d", w.Code)
+	}
+
+	// State одноразовый
+	start := app.request("GET", "/v1/auth/oauth/mock/start", nil)
+	cookie := oauthStateCookieFrom(start)
+	authURL := start.Header().Get("Location")
+	if w := app.completeOAuth(t, authURL, cookie); w.Code != http.StatusOK {
+		t.Fatalf("Первый callback: ожидали 200, получили %d", w.Code)
+	}
+	if w := app.completeOAuth(t, authURL, cookie); w.Code != http.StatusBadRequest {
+		t.Errorf("Повторный callback: ожидали 400, получили %d", w.Code)
+	}
+}
+
+func TestOAuthUnlinkLastLoginMethod(t *testing.T) {
+	app := setupTestApp(t)
+	w := app.oauthLogin(t, mockOIDCUser{Subject: "sub-1", Email: "alice@example.com"})
+	var login authResponse
+	json.NewDecoder(w.Body).Decode(&login)
+
+	w = app.authRequest("DELETE", "/v1/users/me/identities/mock", login.Tokens.AccessToken, nil)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Ожидали 409, получили %d", w.Code)
+	}
+}
+
+// ==================== ПЕРСОНАЛЬНЫЕ ТОКЕНЫ ====================
+
+// createAccessToken выпускает персональный токен с указанными областями доступа
+func (app *testApp) createAccessToken(t *testing.T, sessionToken string, scopes ...string) (int, string) {
+	t.Helper()
+	w := app.authRequest("POST", "/v1/users/me/tokens", sessionToken, map[string]any{
+		"name":   "bot",
+		"scopes": scopes,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Ожидали 201 при создании токена, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	var created struct {
+		ID    int    `json:"id"`
+		Token string `json:"token"`
+	}
+	json.NewDecoder(w.Body).Decode(&created)
+	return created.ID, created.Token
+}
+
+func TestAccessTokenLifecycle(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	id, pat := app.createAccessToken(t, resp.Tokens.AccessToken, "posts:write")
+
+	if !strings.HasPrefix(pat, "snp_") {
+		t.Errorf("Токен должен начинаться с snp_, получили %q", pat)
+	}
+
+	w := app.authRequest("POST", "/v1/posts", pat, map[string]string{"content": "Пост от бота"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Ожидали 201, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	// В списке токен виден без секрета, с отметкой использования
+	w = app.authRequest("GET", "/v1/users/me/tokens", resp.Tokens.AccessToken, nil)
+	var tokens []map[string]any
+	json.NewDecoder(w.Body).Decode(&tokens)
+	if len(tokens) != 1 {
+		t.Fatalf("Ожидали 1 токен, получили %d", len(tokens))
+	}
+	if _, ok := tokens[0]["token"]; ok {
+		t.Error("Секрет токена не должен возвращаться в списке")
+	}
+	if tokens[0]["last_used_at"] == nil {
+		t.Error("last_used_at должен обновиться после запроса")
+	}
+
+	w = app.authRequest("DELETE", fmt.Sprintf("/v1/users/me/tokens/%d", id), resp.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200 при отзыве, получили %d", w.Code)
+	}
+
+	w = app.authRequest("POST", "/v1/posts", pat, map[string]string{"content": "После отзыва"})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Отозванный токен: ожидали 401, получили %d", w.Code)
+	}
+}
+
+func TestAccessTokenScopesEnforced(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	_, pat := app.createAccessToken(t, resp.Tokens.AccessToken, "profile:read")
+
+	w := app.authRequest("GET", "/v1/users/me", pat, nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("profile:read: ожидали 200, получили %d", w.Code)
+	}
+
+	w = app.authRequest("POST", "/v1/posts", pat, map[string]string{"content": "Нельзя"})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Без posts:write: ожидали 403, получили %d", w.Code)
+	}
+
+	// Управление аккаунтом недоступно персональным токенам при любых scopes
+	w = app.authRequest("POST", "/v1/users/me/tokens", pat, map[string]any{
+		"name": "escalation", "scopes": []string{"posts:write"},
+	})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Создание токена токеном: ожидали 403, получили %d", w.Code)
+	}
+	w = app.authRequest("PUT", "/v1/users/me/password", pat, map[string]string{
+		"current_password": "password123", "new_password": "newpassword123",
+	})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Смена пароля токеном: ожидали 403, получили %d", w.Code)
+	}
+}
+
+func TestAccessTokenUnknownScope(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.authRequest("POST", "/v1/users/me/tokens", resp.Tokens.AccessToken, map[string]any{
+		"name": "bot", "scopes": []string{"admin:everything"},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидали 400, получили %d", w.Code)
+	}
+}
+
+func TestAccessTokenExpires(t *testing.T) {
+	clock := newFakeClock()
+	app := setupTestApp(t, func(cfg *service.AuthConfig) { cfg.Now = clock.Now })
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.authRequest("POST", "/v1/users/me/tokens", resp.Tokens.AccessToken, map[string]any{
+		"name": "bot", "scopes": []string{"profile:read"}, "expires_in_days": 1,
+	})
+	var created struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(w.Body).Decode(&created)
+
+	clock.Advance(25 * time.Hour)
+
+	w = app.authRequest("GET", "/v1/users/me", created.Token, nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Истёкший токен: ожидали 401, получили %d", w.Code)
+	}
+}
+
+// ==================== РОЛИ И АДМИНИСТРИРОВАНИЕ ====================
+
+// registerWithRole регистрирует пользователя, назначает роль и входит заново,
+// чтобы роль попала в access-токен
+func (app *testApp) registerWithRole(t *testing.T, username, role string) authResponse {
+	t.Helper()
+	email := username + "@test.com"
+	resp := app.registerUser(t, username, email, "password123")
+	app.setRole(t, resp.User["id"], role)
+	return app.loginUser(t, email, "password123")
+}
+
+func TestAdminRoutesRequireRole(t *testing.T) {
+	app := setupTestApp(t)
+	user := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.authRequest("GET", "/v1/admin/users?username=testuser", user.Tokens.AccessToken, nil)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Обычный пользователь: ожидали 403, получили %d", w.Code)
+	}
+
+	mod := app.registerWithRole(t, "moderator", "moderator")
+	w = app.authRequest("GET", "/v1/admin/users?username=testuser", mod.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Модератор: ожидали 200, получили %d", w.Code)
+	}
+	var found map[string]any
+	json.NewDecoder(w.Body).Decode(&found)
+	if found["role"] != "user" {
+		t.Errorf("role = %v", found["role"])
+	}
+
+	// Смена ролей — только администратору
+	w = app.authRequest("PUT", fmt.Sprintf("/v1/admin/users/%v/role", user.User["id"]), mod.Tokens.AccessToken,
+		map[string]string{"role": "moderator"})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Смена роли модератором: ожидали 403, получили %d", w.Code)
+	}
+}
+
+func TestSuspendUser(t *testing.T) {
+	app := setupTestApp(t)
+	target := app.registerUser(t, "target", "target@test.com", "password123")
+	mod := app.registerWithRole(t, "moderator", "moderator")
+	suspendPath := fmt.Sprintf("/v1/admin/users/%v/suspend", target.User["id"])
+	events := app.openStream(t, target.Tokens.AccessToken, "")
+	postID := app.newPost(t, target.Tokens.AccessToken, "Последний пост")
+	app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/like", postID), target.Tokens.AccessToken, nil)
+
+	w := app.authRequest("POST", suspendPath, mod.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	// Выданный до блокировки access-токен и открытый поток перестают работать сразу
+	waitEvent(t, events, "suspended")
+	if w := app.authRequest("GET", "/v1/users/me", target.Tokens.AccessToken, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Access-токен заблокированного: ожидали 403, получили %d", w.Code)
+	}
+	// На публичных маршрутах токен заблокированного не действует — он видит их как гость
+	var post map[string]any
+	json.NewDecoder(app.authRequest("GET", fmt.Sprintf("/v1/posts/%d", postID), target.Tokens.AccessToken, nil).Body).Decode(&post)
+	if post["is_liked"] != false {
+		t.Errorf("Публичный маршрут узнал заблокированного: is_liked = %v", post["is_liked"])
+	}
+
+	if w := app.attemptLogin("target@test.com", "password123"); w.Code != http.StatusForbidden {
+		t.Errorf("Вход заблокированного: ожидали 403, получили %d", w.Code)
+	}
+	w = app.request("POST", "/v1/auth/refresh", map[string]string{"refresh_token": target.Tokens.RefreshToken})
+	if w.Code == http.StatusOK {
+		t.Error("Заблокированный пользователь не должен обновлять токены")
+	}
+
+	w = app.authRequest("DELETE", suspendPath, mod.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Разблокировка: ожидали 200, получили %d", w.Code)
+	}
+	if w := app.attemptLogin("target@test.com", "password123"); w.Code != http.StatusOK {
+		t.Errorf("После разблокировки: ожидали 200, получили %d", w.Code)
+	}
+}
+
+func TestModeratorCannotSuspendAdmin(t *testing.T) {
+	app := setupTestApp(t)
+	admin := app.registerWithRole(t, "admin", "admin")
+	mod := app.registerWithRole(t, "moderator", "moderator")
+
+	w := app.authRequest("POST", fmt.Sprintf("/v1/admin/users/%v/suspend", admin.User["id"]), mod.Tokens.AccessToken, nil)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Ожидали 403, получили %d", w.Code)
+	}
+}
+
+func TestAdminForceDeletePost(t *testing.T) {
+	app := setupTestApp(t)
+	author := app.registerUser(t, "author", "author@test.com", "password123")
+	mod := app.registerWithRole(t, "moderator", "moderator")
+
+	w := app.authRequest("POST", "/v1/posts", author.Tokens.AccessToken, map[string]string{"content": "Спам"})
+	var post map[string]any
+	json.NewDecoder(w.Body).Decode(&post)
+
+	// Обычное удаление чужого поста запрещено даже модератору
+	path := fmt.Sprintf("/v1/posts/%v", post["id"])
+	if w := app.authRequest("DELETE", path, mod.Tokens.AccessToken, nil); w.Code != http.StatusForbidden {
+		t.Errorf("DELETE /v1/posts: ожидали 403, получили %d", w.Code)
+	}
+
+	w = app.authRequest("DELETE", "/v1/admin"+path, mod.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d", w.Code)
+	}
+	if w := app.authRequest("DELETE", "/v1/admin"+path, mod.Tokens.AccessToken, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Повторное удаление: ожидали 404, получили %d", w.Code)
+	}
+}
+
+func TestRoleChangeAppliesOnRefresh(t *testing.T) {
+	app := setupTestApp(t)
+	admin := app.registerWithRole(t, "admin", "admin")
+	user := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.authRequest("PUT", fmt.Sprintf("/v1/admin/users/%v/role", user.User["id"]), admin.Tokens.AccessToken,
+		map[string]string{"role": "moderator"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	w = app.request("POST", "/v1/auth/refresh", map[string]string{"refresh_token": user.Tokens.RefreshToken})
+	var tokens tokenPair
+	json.NewDecoder(w.Body).Decode(&tokens)
+
+	w = app.authRequest("GET", "/v1/admin/users?username=admin", tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("Новая роль после refresh: ожидали 200, получили %d", w.Code)
+	}
+}
+
+// ==================== ПОСТЫ ====================
+
+func TestCreatePost(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
+		"content": "Мой первый пост!",
+	})
+	if w.Code != http.StatusCreated {
+		t.Errorf("Ожидали 201, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	var post map[string]any
+	json.NewDecoder(w.Body).Decode(&post)
+	if post["content"] != "Мой первый пост!" {
+		t.Errorf("content = %v", post["content"])
+	}
+}
+
+func TestCreatePostNoAuth(t *testing.T) {
+	app := setupTestApp(t)
+
+	w := app.request("POST", "/v1/posts", map[string]string{
+		"content": "Без авторизации",
+	})
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Ожидали 401, получили %d", w.Code)
+	}
+}
+
+func TestCreatePostEmptyContent(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
+		"content": "",
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидали 400, получили %d", w.Code)
+	}
+}
+
+func TestDeleteOwnPost(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	// Создаём пост
+	w := app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
+		"content": "Для удаления",
+	})
+	var post map[string]any
+	json.NewDecoder(w.Body).Decode(&post)
+	postID := int(post["id"].(float64))
+
+	// Удаляем
+	w = app.authRequest("DELETE", fmt.Sprintf("/v1/posts/%d", postID), resp.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDeleteOtherPost(t *testing.T) {
+	app := setupTestApp(t)
+	user1 := app.registerUser(t, "user1", "user1@test.com", "password123")
+	user2 := app.registerUser(t, "user2", "user2@test.com", "password123")
+
+	// user1 создаёт пост
+	w := app.authRequest("POST", "/v1/posts", user1.Tokens.AccessToken, map[string]string{
+		"content": "Пост юзера 1",
+	})
+	var post map[string]any
+	json.NewDecoder(w.Body).Decode(&post)
+	postID := int(post["id"].(float64))
+
+	// user2 пытается удалить
+	w = app.authRequest("DELETE", fmt.Sprintf("/v1/posts/%d", postID), user2.Tokens.AccessToken, nil)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Ожидали 403, получили %d", w.Code)
+	}
+}
+
+func TestGetFeed(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	// Создаём 3 поста
+	for i := 1; i <= 3; i++ {
+		app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
+			"content": fmt.Sprintf("Пост %d", i),
+		})
+	}
+
+	w := app.request("GET", "/v1/feed", nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("Ожидали 200, получили %d", w.Code)
+	}
+
+	posts := decodePage(w).Items
+	if len(posts) != 3 {
+		t.Errorf("Ожидали 3 поста, получили %d", len(posts))
+	}
+}
+
+// ==================== РЕДАКТИРОВАНИЕ ПОСТОВ ====================
+
+func TestEditPostKeepsRevisions(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
+		"content": "Первая версия",
+	})
+	var post map[string]any
+	json.NewDecoder(w.Body).Decode(&post)
+	postID := int(post["id"].(float64))
+	if post["edited_at"] != nil {
+		t.Errorf("Новый пост не должен быть помечен как изменённый: %v", post["edited_at"])
+	}
+
+	for _, content := range []string{"Вторая версия", "Третья версия"} {
+		w = app.authRequest("PATCH", fmt.Sprintf("/v1/posts/%d", postID), resp.Tokens.AccessToken, map[string]string{
+			"content": content,
+		})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	json.NewDecoder(w.Body).Decode(&post)
+	if post["content"] != "Третья версия" || post["edited_at"] == nil {
+		t.Errorf("content = %v, edited_at = %v", post["content"], post["edited_at"])
+	}
+
+	w = app.request("GET", fmt.Sprintf("/v1/posts/%d/revisions", postID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d", w.Code)
+	}
+	var revisions []map[string]any
+	json.NewDecoder(w.Body).Decode(&revisions)
+	if len(revisions) != 2 {
+		t.Fatalf("Ожидали 2 прежние версии, получили %d", len(revisions))
+	}
+	if revisions[0]["content"] != "Вторая версия" || revisions[1]["content"] != "Первая версия" {
+		t.Errorf("Порядок версий: %v, %v", revisions[0]["content"], revisions[1]["content"])
+	}
+
+	// Лента показывает, что пост изменён
+	w = app.request("GET", "/v1/feed", nil)
+	posts := decodePage(w).Items
+	if len(posts) != 1 || posts[0]["edited_at"] == nil {
+		t.Errorf("Ожидали отметку edited_at в ленте: %v", posts)
+	}
+}
+
+func TestEditPostSameContentNoRevision(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
+		"content": "Без изменений",
+	})
+	var post map[string]any
+	json.NewDecoder(w.Body).Decode(&post)
+	postID := int(post["id"].(float64))
+
+	w = app.authRequest("PATCH", fmt.Sprintf("/v1/posts/%d", postID), resp.Tokens.AccessToken, map[string]string{
+		"content": "Без изменений",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d", w.Code)
+	}
+
+	w = app.request("GET", fmt.Sprintf("/v1/posts/%d/revisions", postID), nil)
+	var revisions []map[string]any
+	json.NewDecoder(w.Body).Decode(&revisions)
+	if len(revisions) != 0 {
+		t.Errorf("Ожидали 0 версий, получили %d", len(revisions))
+	}
+}
+
+func TestEditOtherPost(t *testing.T) {
+	app := setupTestApp(t)
+	user1 := app.registerUser(t, "user1", "user1@test.com", "password123")
+	user2 := app.registerUser(t, "user2", "user2@test.com", "password123")
+
+	w := app.authRequest("POST", "/v1/posts", user1.Tokens.AccessToken, map[string]string{
+		"content": "Пост юзера 1",
+	})
+	var post map[string]any
+	json.NewDecoder(w.Body).Decode(&post)
+	postID := int(post["id"].(float64))
+
+	w = app.authRequest("PATCH", fmt.Sprintf("/v1/posts/%d", postID), user2.Tokens.AccessToken, map[string]string{
+		"content": "Чужая правка",
+	})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Ожидали 403, получили %d", w.Code)
+	}
+
+	w = app.authRequest("PATCH", fmt.Sprintf("/v1/posts/%d", postID), user1.Tokens.AccessToken, map[string]string{
+		"content": "",
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Пустой текст: ожидали 400, получили %d", w.Code)
+	}
+}
+
+func TestPostRevisionsNotFound(t *testing.T) {
+	app := setupTestApp(t)
+
+	w := app.request("GET", "/v1/posts/99999/revisions", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Ожидали 404, получили %d", w.Code)
+	}
+}
+
+// ==================== ОТДЕЛЬНЫЙ ПОСТ ====================
+
+func TestGetPost(t *testing.T) {
+	app := setupTestApp(t)
+	author := app.registerUser(t, "author", "author@test.com", "password123")
+	reader := app.registerUser(t, "reader", "reader@test.com", "password123")
+
+	w := app.authRequest("POST", "/v1/posts", author.Tokens.AccessToken, map[string]string{
+		"content": "Пост по ссылке",
+	})
+	var post map[string]any
+	json.NewDecoder(w.Body).Decode(&post)
+	postID := int(post["id"].(float64))
+
+	app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/like", postID), reader.Tokens.AccessToken, nil)
+
+	w = app.authRequest("GET", fmt.Sprintf("/v1/posts/%d", postID), reader.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	json.NewDecoder(w.Body).Decode(&post)
+	if post["content"] != "Пост по ссылке" || post["is_liked"] != true {
+		t.Errorf("content = %v, is_liked = %v", post["content"], post["is_liked"])
+	}
+	if _, ok := post["comments"]; ok {
+		t.Error("Без expand комментарии не раскрываются")
+	}
+
+	// Без авторизации пост тоже доступен
+	w = app.request("GET", fmt.Sprintf("/v1/posts/%d", postID), nil)
+	var anon map[string]any
+	json.NewDecoder(w.Body).Decode(&anon)
+	if w.Code != http.StatusOK || anon["is_liked"] != false {
+		t.Errorf("Анонимно: код %d, is_liked = %v", w.Code, anon["is_liked"])
+	}
+}
+
+func TestGetPostExpand(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "author", "author@test.com", "password123")
+
+	w := app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
+		"content": "Пост с обсуждением",
+	})
+	var post map[string]any
+	json.NewDecoder(w.Body).Decode(&post)
+	postID := int(post["id"].(float64))
+
+	for i := 1; i <= 2; i++ {
+		app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/comments", postID), resp.Tokens.AccessToken, map[string]string{
+			"content": fmt.Sprintf("Коммент %d", i),
+		})
+	}
+
+	w = app.request("GET", fmt.Sprintf("/v1/posts/%d?expand=comments,author", postID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d", w.Code)
+	}
+	var detail struct {
+		Content  string         `json:"content"`
+		Author   map[string]any `json:"author"`
+		Comments pageResponse   `json:"comments"`
+	}
+	json.NewDecoder(w.Body).Decode(&detail)
+	if detail.Content != "Пост с обсуждением" {
+		t.Errorf("content = %v", detail.Content)
+	}
+	if detail.Author["username"] != "author" {
+		t.Errorf("author = %v", detail.Author)
+	}
+	if len(detail.Comments.Items) != 2 || detail.Comments.Items[0]["content"] != "Коммент 1" {
+		t.Errorf("comments = %v", detail.Comments)
+	}
+
+	w = app.request("GET", fmt.Sprintf("/v1/posts/%d?expand=likes", postID), nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Неизвестный expand: ожидали 400, получили %d", w.Code)
+	}
+}
+
+func TestMissingPost404(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	if w := app.request("GET", "/v1/posts/99999", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET поста: ожидали 404, получили %d", w.Code)
+	}
+	if w := app.request("GET", "/v1/posts/99999/comments", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Комментарии: ожидали 404, получили %d", w.Code)
+	}
+	w := app.authRequest("POST", "/v1/posts/99999/comments", resp.Tokens.AccessToken, map[string]string{
+		"content": "В пустоту",
+	})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Новый комментарий: ожидали 404, получили %d", w.Code)
+	}
+	if w := app.authRequest("POST", "/v1/posts/99999/like", resp.Tokens.AccessToken, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Лайк: ожидали 404, получили %d", w.Code)
+	}
+}
+
+// ==================== ПАГИНАЦИЯ ====================
+
+func TestFeedCursorPagination(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	for i := 1; i <= 5; i++ {
+		app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
+			"content": fmt.Sprintf("Пост %d", i),
+		})
+	}
+
+	var contents []string
+	path := "/v1/feed?limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages > 5 {
+			t.Fatal("Пагинация не закончилась")
+		}
+		w := app.request("GET", path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Ожидали 200, получили %d", w.Code)
+		}
+		page := decodePage(w)
+		for _, p := range page.Items {
+			contents = append(contents, p["content"].(string))
+		}
+
+		// Новый пост между страницами не сдвигает следующую
+		if pages == 0 {
+			app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
+				"content": "Свежий пост",
+			})
+		}
+
+		path = ""
+		if page.NextCursor != "" {
+			path = "/v1/feed?limit=2&cursor=" + page.NextCursor
+		}
+	}
+
+	want := []string{"Пост 5", "Пост 4", "Пост 3", "Пост 2", "Пост 1"}
+	if fmt.Sprint(contents) != fmt.Sprint(want) {
+		t.Errorf("Посты по страницам: %v, ожидали %v", contents, want)
+	}
+}
+
+func TestCommentsCursorPagination(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	w := app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
+		"content": "Пост для комментов",
+	})
+	var post map[string]any
+	json.NewDecoder(w.Body).Decode(&post)
+	postID := int(post["id"].(float64))
+
+	for i := 1; i <= 3; i++ {
+		app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/comments", postID), resp.Tokens.AccessToken, map[string]string{
+			"content": fmt.Sprintf("Коммент %d", i),
+		})
+	}
+
+	first := decodePage(app.request("GET", fmt.Sprintf("/v1/posts/%d/comments?limit=2", postID), nil))
+	if len(first.Items) != 2 || first.Items[0]["content"] != "Коммент 1" || first.NextCursor == "" {
+		t.Fatalf("Первая страница: %v, курсор %q", first.Items, first.NextCursor)
+	}
+
+	second := decodePage(app.request("GET",
+		fmt.Sprintf("/v1/posts/%d/comments?limit=2&cursor=%s", postID, first.NextCursor), nil))
+	if len(second.Items) != 1 || second.Items[0]["content"] != "Коммент 3" {
+		t.Errorf("Вторая страница: %v", second.Items)
+	}
+	if second.NextCursor != "" {
+		t.Errorf("На последней странице курсора быть не должно: %q", second.NextCursor)
+	}
+}
+
+func TestFollowersCursorPagination(t *testing.T) {
+	app := setupTestApp(t)
+	star := app.registerUser(t, "star", "star@test.com", "password123")
+	starID := int(star.User["id"].(float64))
+
+	for i := 1; i <= 3; i++ {
+		fan := app.registerUser(t, fmt.Sprintf("fan%d", i), fmt.Sprintf("fan%d@test.com", i), "password123")
+		app.authRequest("POST", fmt.Sprintf("/v1/users/%d/follow", starID), fan.Tokens.AccessToken, nil)
+	}
+
+	first := decodePage(app.request("GET", fmt.Sprintf("/v1/users/%d/followers?limit=2", starID), nil))
+	if len(first.Items) != 2 || first.Items[0]["username"] != "fan3" {
+		t.Fatalf("Первая страница: %v", first.Items)
+	}
+
+	second := decodePage(app.request("GET",
+		fmt.Sprintf("/v1/users/%d/followers?limit=2&cursor=%s", starID, first.NextCursor), nil))
+	if len(second.Items) != 1 || second.Items[0]["username"] != "fan1" {
+		t.Errorf("Вторая страница: %v", second.Items)
+	}
+}
+
+func TestLegacyOffsetPagination(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+
+	for i := 1; i <= 3; i++ {
+		app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
+			"content": fmt.Sprintf("Пост %d", i),
+		})
+	}
+
+	w := app.request("GET", "/v1/feed?limit=2&offset=2", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d", w.Code)
+	}
+	if w.Header().Get("Deprecation") == "" {
+		t.Error("Ожидали заголовок Deprecation")
+	}
+	var posts []map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&posts); err != nil {
+		t.Fatalf("Со offset ожидали массив: %v", err)
+	}
+	if len(posts) != 1 || posts[0]["content"] != "Пост 1" {
+		t.Errorf("posts = %v", posts)
+	}
+}
+
+func TestInvalidCursor(t *testing.T) {
+	app := setupTestApp(t)
+
+	w := app.request("GET", "/v1/feed?cursor=bad*cursor", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидали 400, получили %d", w.Code)
+	}
+}
+
+// ==================== РЕПОСТЫ И ЦИТАТЫ ====================
+
+func TestRepostInFollowingFeed(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	carol := app.registerUser(t, "carol", "carol@test.com", "password123")
+
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", bob.User["id"]), alice.Tokens.AccessToken, nil)
+	postID := app.newPost(t, carol.Tokens.AccessToken, "Пост Кэрол")
+
+	w := app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/repost", postID), bob.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	posts := decodePage(app.authRequest("GET", "/v1/feed/following", alice.Tokens.AccessToken, nil)).Items
+	if len(posts) != 1 {
+		t.Fatalf("Ожидали репост в ленте подписок, получили %v", posts)
+	}
+	reposter, _ := posts[0]["reposted_by"].(map[string]any)
+	if posts[0]["content"] != "Пост Кэрол" || reposter["username"] != "bob" {
+		t.Errorf("content = %v, reposted_by = %v", posts[0]["content"], posts[0]["reposted_by"])
+	}
+	if posts[0]["reposts_count"] != float64(1) {
+		t.Errorf("reposts_count = %v", posts[0]["reposts_count"])
+	}
+
+	// Отмена репоста убирает пост из ленты
+	app.authRequest("DELETE", fmt.Sprintf("/v1/posts/%d/repost", postID), bob.Tokens.AccessToken, nil)
+	posts = decodePage(app.authRequest("GET", "/v1/feed/following", alice.Tokens.AccessToken, nil)).Items
+	if len(posts) != 0 {
+		t.Errorf("После отмены репоста лента должна быть пустой: %v", posts)
+	}
+}
+
+func TestRepostsDeduplicatedInFeed(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	dave := app.registerUser(t, "dave", "dave@test.com", "password123")
+	carol := app.registerUser(t, "carol", "carol@test.com", "password123")
+
+	for _, u := range []authResponse{bob, dave, carol} {
+		app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", u.User["id"]), alice.Tokens.AccessToken, nil)
+	}
+	postID := app.newPost(t, carol.Tokens.AccessToken, "Популярный пост")
+	app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/repost", postID), bob.Tokens.AccessToken, nil)
+	app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/repost", postID), dave.Tokens.AccessToken, nil)
+
+	posts := decodePage(app.authRequest("GET", "/v1/feed/following", alice.Tokens.AccessToken, nil)).Items
+	if len(posts) != 1 {
+		t.Fatalf("Пост и два репоста должны схлопнуться в одну запись, получили %d", len(posts))
+	}
+	reposter, _ := posts[0]["reposted_by"].(map[string]any)
+	if reposter["username"] != "dave" {
+		t.Errorf("Ожидали последнего репостнувшего dave, получили %v", posts[0]["reposted_by"])
+	}
+}
+
+func TestQuotePost(t *testing.T) {
+	app := setupTestApp(t)
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	carol := app.registerUser(t, "carol", "carol@test.com", "password123")
+	originalID := app.newPost(t, carol.Tokens.AccessToken, "Оригинал")
+
+	w := app.authRequest("POST", "/v1/posts", bob.Tokens.AccessToken, map[string]any{
+		"content": "Согласен!", "quote_of": originalID,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Ожидали 201, получили %d: %s", w.Code, w.Body.String())
+	}
+	var quote map[string]any
+	json.NewDecoder(w.Body).Decode(&quote)
+	embedded, _ := quote["quote"].(map[string]any)
+	if quote["is_quote"] != true || embedded["content"] != "Оригинал" || embedded["username"] != "carol" {
+		t.Fatalf("Цитата: %v", quote)
+	}
+
+	// Оригинал удалён — цитата остаётся, но без встроенного поста
+	app.authRequest("DELETE", fmt.Sprintf("/v1/posts/%d", originalID), carol.Tokens.AccessToken, nil)
+	quote = app.getJSON(t, fmt.Sprintf("/v1/posts/%v", quote["id"]))
+	if quote["is_quote"] != true || quote["quote"] != nil {
+		t.Errorf("После удаления оригинала: is_quote = %v, quote = %v", quote["is_quote"], quote["quote"])
+	}
+
+	w = app.authRequest("POST", "/v1/posts", bob.Tokens.AccessToken, map[string]any{
+		"content": "Цитата в пустоту", "quote_of": 99999,
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Цитата несуществующего поста: ожидали 400, получили %d", w.Code)
+	}
+	if w := app.authRequest("POST", "/v1/posts/99999/repost", bob.Tokens.AccessToken, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Репост несуществующего поста: ожидали 404, получили %d", w.Code)
+	}
+}
+
+// ==================== ХЕШТЕГИ ====================
+
+func TestHashtagPage(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	token := resp.Tokens.AccessToken
+
+	first := app.newPost(t, token, "Запускаем #Весна2026 и #go")
+	app.newPost(t, token, "Без тегов, но с якорем a#b")
+	second := app.newPost(t, token, "Ещё раз про #весна2026!")
+
+	// Тег нечувствителен к регистру, # в пути допускается
+	for _, path := range []string{"/v1/hashtags/весна2026/posts", "/v1/hashtags/%23ВЕСНА2026/posts"} {
+		w := app.request("GET", path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: ожидали 200, получили %d: %s", path, w.Code, w.Body.String())
+		}
+		posts := decodePage(w).Items
+		if len(posts) != 2 || posts[0]["id"] != float64(second) || posts[1]["id"] != float64(first) {
+			t.Errorf("%s: ожидали посты %d и %d, получили %v", path, second, first, posts)
+		}
+	}
+
+	// Правка пересобирает теги
+	app.authRequest("PATCH", fmt.Sprintf("/v1/posts/%d", first), token, map[string]string{"content": "Теперь про #осень"})
+	if posts := decodePage(app.request("GET", "/v1/hashtags/весна2026/posts", nil)).Items; len(posts) != 1 {
+		t.Errorf("После правки под тегом должен остаться один пост, получили %d", len(posts))
+	}
+	if posts := decodePage(app.request("GET", "/v1/hashtags/осень/posts", nil)).Items; len(posts) != 1 {
+		t.Errorf("Новый тег должен появиться после правки, получили %d", len(posts))
+	}
+}
+
+func TestTrendingHashtags(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+
+	// У #solo больше постов, но у #campaign больше разных авторов
+	for i := 0; i < 3; i++ {
+		app.newPost(t, alice.Tokens.AccessToken, fmt.Sprintf("Пост %d #solo", i))
+	}
+	app.newPost(t, alice.Tokens.AccessToken, "Участвую в #campaign")
+	app.newPost(t, bob.Tokens.AccessToken, "И я в #Campaign")
+
+	w := app.request("GET", "/v1/hashtags/trending", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	var tags []map[string]any
+	json.NewDecoder(w.Body).Decode(&tags)
+	if len(tags) != 2 {
+		t.Fatalf("Ожидали 2 тега, получили %v", tags)
+	}
+	if tags[0]["tag"] != "campaign" || tags[0]["authors_count"] != float64(2) {
+		t.Errorf("Первым ожидали campaign с двумя авторами, получили %v", tags[0])
+	}
+	if tags[1]["tag"] != "solo" || tags[1]["posts_count"] != float64(3) {
+		t.Errorf("Вторым ожидали solo с тремя постами, получили %v", tags[1])
+	}
+
+	w = app.request("GET", "/v1/hashtags/trending?limit=1", nil)
+	json.NewDecoder(w.Body).Decode(&tags)
+	if len(tags) != 1 {
+		t.Errorf("limit=1: ожидали один тег, получили %d", len(tags))
+	}
+}
+
+func TestHashtagEmptyAndUnknown(t *testing.T) {
+	app := setupTestApp(t)
+
+	w := app.request("GET", "/v1/hashtags/%23/posts", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Пустой тег: ожидали 400, получили %d", w.Code)
+	}
+
+	w = app.request("GET", "/v1/hashtags/nothing/posts", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d", w.Code)
+	}
+	if posts := decodePage(w).Items; len(posts) != 0 {
+		t.Errorf("Ожидали пустую страницу, получили %v", posts)
+	}
+}
+
+// ==================== УПОМИНАНИЯ ====================
+
+func TestPostMentionEntities(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+
+	postID := app.newPost(t, alice.Tokens.AccessToken, "Привет, @bob и @ghost! Почта bob@test.com")
+
+	post := app.getJSON(t, fmt.Sprintf("/v1/posts/%d", postID))
+	mentions, _ := post["mentions"].([]any)
+	if len(mentions) != 1 {
+		t.Fatalf("Ожидали одно упоминание (ghost не существует, почта не упоминание), получили %v", post["mentions"])
+	}
+	m := mentions[0].(map[string]any)
+	if m["user_id"] != bob.User["id"] || m["username"] != "bob" || m["offset"] != float64(8) || m["length"] != float64(4) {
+		t.Errorf("Неверное упоминание: %v", m)
+	}
+}
+
+func TestMyMentions(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+
+	postID := app.newPost(t, alice.Tokens.AccessToken, "@bob, @bob, смотри!")
+	app.newPost(t, bob.Tokens.AccessToken, "Сам себя: @bob")
+	w := app.postComment(alice.Tokens.AccessToken, postID, nil, "И ещё раз @bob")
+	var comment map[string]any
+	json.NewDecoder(w.Body).Decode(&comment)
+	if mentions, _ := comment["mentions"].([]any); len(mentions) != 1 {
+		t.Errorf("Ожидали упоминание в комментарии, получили %v", comment["mentions"])
+	}
+
+	w = app.authRequest("GET", "/v1/users/me/mentions", bob.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	items := decodePage(w).Items
+	if len(items) != 2 {
+		t.Fatalf("Ожидали комментарий и пост (без повторов и своих постов), получили %v", items)
+	}
+	if items[0]["comment_id"] != comment["id"] || items[1]["post_id"] != float64(postID) || items[1]["comment_id"] != nil {
+		t.Errorf("Неверный порядок или состав упоминаний: %v", items)
+	}
+	if items[1]["username"] != "alice" {
+		t.Errorf("Автор упоминания = %v", items[1]["username"])
+	}
+
+	// Удалённый комментарий и правка без упоминания убирают записи
+	app.authRequest("DELETE", fmt.Sprintf("/v1/posts/%d/comments/%v", postID, comment["id"]), alice.Tokens.AccessToken, nil)
+	app.authRequest("PATCH", fmt.Sprintf("/v1/posts/%d", postID), alice.Tokens.AccessToken, map[string]string{"content": "Без упоминаний"})
+	items = decodePage(app.authRequest("GET", "/v1/users/me/mentions", bob.Tokens.AccessToken, nil)).Items
+	if len(items) != 0 {
+		t.Errorf("Ожидали пустой список упоминаний, получили %v", items)
+	}
+}
+
+func TestMentionsRequireAuth(t *testing.T) {
+	app := setupTestApp(t)
+
+	w := app.request("GET", "/v1/users/me/mentions", nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Ожидали 401, получили %d", w.Code)
+	}
+}
+
+// ==================== ПОИСК ====================
+
+func TestSearchPosts(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	token := resp.Tokens.AccessToken
+
+	app.newPost(t, token, "Кошки любят молоко")
+	app.newPost(t, token, "Собака спит весь день")
+	app.newPost(t, token, "<b>Кошка</b> на окне")
+
+	w := app.request("GET", "/v1/search?q=кошка", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	items := decodePage(w).Items
+	if len(items) != 2 {
+		t.Fatalf("Ожидали 2 поста (с учётом словоформ), получили %v", items)
+	}
+	for _, item := range items {
+		snippet, _ := item["snippet"].(string)
+		if !strings.Contains(snippet, "<mark>") {
+			t.Errorf("Во фрагменте нет подсветки: %q", snippet)
+		}
+		if strings.Contains(snippet, "<b>") {
+			t.Errorf("HTML из текста поста должен экранироваться: %q", snippet)
+		}
+		if item["username"] != "testuser" || item["rank"] == nil {
+			t.Errorf("Найденный пост без автора или ранга: %v", item)
+		}
+	}
+}
+
+func TestSearchPostsPagination(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	for i := 0; i < 3; i++ {
+		app.newPost(t, resp.Tokens.AccessToken, fmt.Sprintf("Релиз номер %d", i))
+	}
+
+	first := decodePage(app.request("GET", "/v1/search?type=posts&q=релиз&limit=2", nil))
+	if len(first.Items) != 2 || first.NextCursor == "" {
+		t.Fatalf("Ожидали 2 поста и курсор, получили %d, %q", len(first.Items), first.NextCursor)
+	}
+	second := decodePage(app.request("GET", "/v1/search?type=posts&q=релиз&limit=2&cursor="+first.NextCursor, nil))
+	if len(second.Items) != 1 || second.NextCursor != "" {
+		t.Fatalf("Ожидали последний пост без курсора, получили %d, %q", len(second.Items), second.NextCursor)
+	}
+	for _, item := range first.Items {
+		if item["id"] == second.Items[0]["id"] {
+			t.Errorf("Пост %v повторился на второй странице", item["id"])
+		}
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	app := setupTestApp(t)
+	app.registerUser(t, "alice", "alice@test.com", "password123")
+	app.registerUser(t, "alicia", "alicia@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	app.authRequest("PUT", "/v1/users/me", bob.Tokens.AccessToken, map[string]string{"bio": "Пишу про алгоритмы и структуры данных"})
+
+	items := decodePage(app.request("GET", "/v1/search?type=users&q=ali", nil)).Items
+	if len(items) != 2 {
+		t.Fatalf("Ожидали alice и alicia, получили %v", items)
+	}
+	for _, item := range items {
+		if _, ok := item["email"]; ok {
+			t.Errorf("Поиск не должен раскрывать email: %v", item)
+		}
+	}
+
+	items = decodePage(app.request("GET", "/v1/search?type=users&q=алгоритмы", nil)).Items
+	if len(items) != 1 || items[0]["username"] != "bob" {
+		t.Fatalf("Ожидали bob по bio, получили %v", items)
+	}
+	if snippet, _ := items[0]["snippet"].(string); !strings.Contains(snippet, "<mark>алгоритмы</mark>") {
+		t.Errorf("snippet = %q", snippet)
+	}
+}
+
+func TestSearchValidation(t *testing.T) {
+	app := setupTestApp(t)
+
+	for _, path := range []string{"/v1/search?q=", "/v1/search?q=%20%20", "/v1/search?q=go&type=hashtags", "/v1/search?q=go&cursor=bad"} {
+		w := app.request("GET", path, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: ожидали 400, получили %d", path, w.Code)
+		}
+	}
+}
+
+// ==================== УВЕДОМЛЕНИЯ ====================
+
+// notifications возвращает группы уведомлений пользователя
+func (app *testApp) notifications(t *testing.T, token string) []map[string]any {
+	t.Helper()
+	w := app.authRequest("GET", "/v1/notifications", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /v1/notifications: ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	return decodePage(w).Items
+}
+
+// unreadNotifications возвращает число непрочитанных групп
+func (app *testApp) unreadNotifications(t *testing.T, token string) float64 {
+	t.Helper()
+	var body map[string]float64
+	json.NewDecoder(app.authRequest("GET", "/v1/notifications/unread-count", token, nil).Body).Decode(&body)
+	return body["count"]
+}
+
+func TestNotificationsGroupLikes(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	postID := app.newPost(t, alice.Tokens.AccessToken, "Пост Алисы")
+
+	app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/like", postID), alice.Tokens.AccessToken, nil)
+	for _, name := range []string{"bob", "carol", "dave"} {
+		u := app.registerUser(t, name, name+"@test.com", "password123")
+		app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/like", postID), u.Tokens.AccessToken, nil)
+	}
+
+	items := app.notifications(t, alice.Tokens.AccessToken)
+	if len(items) != 1 {
+		t.Fatalf("Ожидали одну группу лайков без своего, получили %v", items)
+	}
+	n := items[0]
+	if n["type"] != "like" || n["actors_count"] != float64(3) || n["read"] != false || n["post_id"] != float64(postID) {
+		t.Errorf("Неверная группа: %v", n)
+	}
+	if n["summary"] != "dave и ещё 2 оценили ваш пост" {
+		t.Errorf("summary = %v", n["summary"])
+	}
+	if c := app.unreadNotifications(t, alice.Tokens.AccessToken); c != 1 {
+		t.Errorf("Непрочитанных групп = %v", c)
+	}
+}
+
+func TestNotificationsRetracted(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	postID := app.newPost(t, alice.Tokens.AccessToken, "Пост Алисы")
+
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", alice.User["id"]), bob.Tokens.AccessToken, nil)
+	app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/like", postID), bob.Tokens.AccessToken, nil)
+	if items := app.notifications(t, alice.Tokens.AccessToken); len(items) != 2 {
+		t.Fatalf("Ожидали подписку и лайк, получили %v", items)
+	}
+
+	app.authRequest("DELETE", fmt.Sprintf("/v1/users/%v/follow", alice.User["id"]), bob.Tokens.AccessToken, nil)
+	app.authRequest("DELETE", fmt.Sprintf("/v1/posts/%d/like", postID), bob.Tokens.AccessToken, nil)
+	if items := app.notifications(t, alice.Tokens.AccessToken); len(items) != 0 {
+		t.Errorf("Отменённые действия не должны оставлять уведомлений: %v", items)
+	}
+	if c := app.unreadNotifications(t, alice.Tokens.AccessToken); c != 0 {
+		t.Errorf("Непрочитанных групп = %v", c)
+	}
+}
+
+func TestNotificationsCommentsRepliesMentions(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	carol := app.registerUser(t, "carol", "carol@test.com", "password123")
+	postID := app.newPost(t, alice.Tokens.AccessToken, "Пост Алисы")
+
+	w := app.postComment(bob.Tokens.AccessToken, postID, nil, "@alice, @carol, гляньте")
+	var comment map[string]any
+	json.NewDecoder(w.Body).Decode(&comment)
+	app.postComment(alice.Tokens.AccessToken, postID, comment["id"], "Спасибо!")
+
+	// Автору поста — комментарий, без отдельного упоминания
+	items := app.notifications(t, alice.Tokens.AccessToken)
+	if len(items) != 1 || items[0]["type"] != "comment" || items[0]["comment_id"] != comment["id"] {
+		t.Errorf("alice: ожидали только уведомление о комментарии, получили %v", items)
+	}
+	items = app.notifications(t, carol.Tokens.AccessToken)
+	if len(items) != 1 || items[0]["type"] != "mention" {
+		t.Errorf("carol: ожидали упоминание, получили %v", items)
+	}
+	items = app.notifications(t, bob.Tokens.AccessToken)
+	if len(items) != 1 || items[0]["type"] != "reply" || items[0]["summary"] != "alice ответил(а) на ваш комментарий" {
+		t.Errorf("bob: ожидали ответ, получили %v", items)
+	}
+
+	// Удалённый комментарий уносит свои уведомления
+	app.authRequest("DELETE", fmt.Sprintf("/v1/posts/%d/comments/%v", postID, comment["id"]), bob.Tokens.AccessToken, nil)
+	if items := app.notifications(t, alice.Tokens.AccessToken); len(items) != 0 {
+		t.Errorf("alice: уведомление об удалённом комментарии осталось: %v", items)
+	}
+	if items := app.notifications(t, carol.Tokens.AccessToken); len(items) != 0 {
+		t.Errorf("carol: упоминание из удалённого комментария осталось: %v", items)
+	}
+}
+
+func TestMarkNotificationsRead(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	carol := app.registerUser(t, "carol", "carol@test.com", "password123")
+	postID := app.newPost(t, alice.Tokens.AccessToken, "Пост Алисы")
+
+	app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/like", postID), bob.Tokens.AccessToken, nil)
+	groupID := app.notifications(t, alice.Tokens.AccessToken)[0]["id"]
+
+	// Чужую группу отметить нельзя
+	w := app.authRequest("POST", fmt.Sprintf("/v1/notifications/%v/read", groupID), carol.Tokens.AccessToken, nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Ожидали 404, получили %d", w.Code)
+	}
+
+	w = app.authRequest("POST", fmt.Sprintf("/v1/notifications/%v/read", groupID), alice.Tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	if c := app.unreadNotifications(t, alice.Tokens.AccessToken); c != 0 {
+		t.Errorf("После прочтения непрочитанных = %v", c)
+	}
+
+	// Новый лайк начинает новую группу, прочитанная остаётся как была
+	app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/like", postID), carol.Tokens.AccessToken, nil)
+	items := app.notifications(t, alice.Tokens.AccessToken)
+	if len(items) != 2 || items[0]["read"] != false || items[0]["actors_count"] != float64(1) || items[1]["read"] != true {
+		t.Fatalf("Ожидали новую непрочитанную группу и старую прочитанную, получили %v", items)
+	}
+
+	app.authRequest("POST", "/v1/notifications/read", alice.Tokens.AccessToken, nil)
+	if c := app.unreadNotifications(t, alice.Tokens.AccessToken); c != 0 {
+		t.Errorf("После «прочитать все» непрочитанных = %v", c)
+	}
+}
+
+// ==================== ПОТОК СОБЫТИЙ ====================
+
+// sseEvent — одно событие потока /v1/stream
+type sseEvent struct {
+	Type string
+	Data map[string]any
+}
+
+// openStream подключается к потоку событий через настоящий HTTP-сервер и возвращает
+// канал разобранных событий. Соединение закрывается в конце теста
+func (app *testApp) openStream(t *testing.T, token, query string) <-chan sseEvent {
+	t.Helper()
+	srv := httptest.NewServer(app.handler)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(srv.Close)
+	t.Cleanup(cancel)
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/v1/stream"+query, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /v1/stream: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /v1/stream: ожидали 200, получили %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	events := make(chan sseEvent)
+	go func() {
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		var ev sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				ev.Type = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev.Data)
+			case line == "" && ev.Type != "":
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+				ev = sseEvent{}
+			}
+		}
+	}()
+	return events
+}
+
+// waitEvent ждёт событие нужного типа, пропуская остальные
+func waitEvent(t *testing.T, events <-chan sseEvent, eventType string) sseEvent {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type == eventType {
+				return ev
+			}
+		case <-timeout:
+			t.Fatalf("Не дождались события %s", eventType)
+		}
+	}
+}
+
+func TestStreamNotifications(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	postID := app.newPost(t, alice.Tokens.AccessToken, "Пост Алисы")
+
+	events := app.openStream(t, alice.Tokens.AccessToken, "")
+	app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/like", postID), bob.Tokens.AccessToken, nil)
+
+	ev := waitEvent(t, events, "notification")
+	if ev.Data["type"] != "like" || ev.Data["actor_id"] != bob.User["id"] || ev.Data["post_id"] != float64(postID) {
+		t.Errorf("Неверное уведомление: %v", ev.Data)
+	}
+}
+
+func TestStreamFollowedPosts(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	carol := app.registerUser(t, "carol", "carol@test.com", "password123")
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", bob.User["id"]), alice.Tokens.AccessToken, nil)
+
+	events := app.openStream(t, alice.Tokens.AccessToken, "")
+
+	// Посты тех, на кого Алиса не подписана, в поток не попадают
+	app.newPost(t, carol.Tokens.AccessToken, "Пост Кэрол")
+	bobPost := app.newPost(t, bob.Tokens.AccessToken, "Пост Боба")
+	ev := waitEvent(t, events, "post.created")
+	if ev.Data["post_id"] != float64(bobPost) || ev.Data["username"] != "bob" {
+		t.Errorf("Ожидали пост Боба, получили %v", ev.Data)
+	}
+
+	// Подписка во время соединения сразу включает посты нового автора
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", carol.User["id"]), alice.Tokens.AccessToken, nil)
+	if ev := waitEvent(t, events, "following"); ev.Data["user_id"] != carol.User["id"] || ev.Data["following"] != true {
+		t.Errorf("Неверное событие подписки: %v", ev.Data)
+	}
+	carolPost := app.newPost(t, carol.Tokens.AccessToken, "Ещё пост Кэрол")
+	if ev := waitEvent(t, events, "post.created"); ev.Data["post_id"] != float64(carolPost) {
+		t.Errorf("Ожидали пост Кэрол, получили %v", ev.Data)
+	}
+}
+
+func TestStreamPostCounters(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	postID := app.newPost(t, bob.Tokens.AccessToken, "Пост Боба")
+
+	events := app.openStream(t, alice.Tokens.AccessToken, fmt.Sprintf("?posts=%d", postID))
+
+	app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/like", postID), bob.Tokens.AccessToken, nil)
+	ev := waitEvent(t, events, "post.counters")
+	if ev.Data["post_id"] != float64(postID) || ev.Data["likes_count"] != float64(1) {
+		t.Errorf("Неверные счётчики после лайка: %v", ev.Data)
+	}
+
+	app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/comments", postID), bob.Tokens.AccessToken, map[string]string{"content": "Комментарий"})
+	ev = waitEvent(t, events, "post.counters")
+	if ev.Data["comments_count"] != float64(1) || ev.Data["likes_count"] != float64(1) {
+		t.Errorf("Неверные счётчики после комментария: %v", ev.Data)
+	}
+}
+
+func TestStreamSkipsHiddenPostCounters(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	carol := app.registerUser(t, "carol", "carol@test.com", "password123")
+	app.makePrivate(t, alice.Tokens.AccessToken, true)
+	privatePost := app.newPost(t, alice.Tokens.AccessToken, "Только для своих")
+	publicPost := app.newPost(t, carol.Tokens.AccessToken, "Пост Кэрол")
+
+	events := app.openStream(t, bob.Tokens.AccessToken, fmt.Sprintf("?posts=%d,%d", privatePost, publicPost))
+
+	// Счётчики закрытого поста Боб не получает, даже если попросил
+	app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/like", privatePost), alice.Tokens.AccessToken, nil)
+	app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/like", publicPost), carol.Tokens.AccessToken, nil)
+	if ev := waitEvent(t, events, "post.counters"); ev.Data["post_id"] != float64(publicPost) {
+		t.Errorf("Пришли счётчики недоступного поста: %v", ev.Data)
+	}
+}
+
+func TestStreamSkipsMutedAuthors(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	carol := app.registerUser(t, "carol", "carol@test.com", "password123")
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", bob.User["id"]), alice.Tokens.AccessToken, nil)
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", carol.User["id"]), alice.Tokens.AccessToken, nil)
+	app.authRequest("POST", "/v1/users/me/mutes", alice.Tokens.AccessToken, map[string]any{"user_id": bob.User["id"]})
+
+	events := app.openStream(t, alice.Tokens.AccessToken, "")
+
+	// Скрытый до подключения автор в поток не попадает
+	app.newPost(t, bob.Tokens.AccessToken, "Пост Боба")
+	carolPost := app.newPost(t, carol.Tokens.AccessToken, "Пост Кэрол")
+	if ev := waitEvent(t, events, "post.created"); ev.Data["post_id"] != float64(carolPost) {
+		t.Errorf("Ожидали пост Кэрол, получили %v", ev.Data)
+	}
+
+	// Скрытие и возврат во время соединения действуют сразу
+	app.authRequest("POST", "/v1/users/me/mutes", alice.Tokens.AccessToken, map[string]any{"user_id": carol.User["id"]})
+	if ev := waitEvent(t, events, "muting"); ev.Data["user_id"] != carol.User["id"] || ev.Data["muted"] != true {
+		t.Errorf("Неверное событие скрытия: %v", ev.Data)
+	}
+	app.authRequest("DELETE", fmt.Sprintf("/v1/users/me/mutes/%v", bob.User["id"]), alice.Tokens.AccessToken, nil)
+	if ev := waitEvent(t, events, "muting"); ev.Data["user_id"] != bob.User["id"] || ev.Data["muted"] != false {
+		t.Errorf("Неверное событие возврата: %v", ev.Data)
+	}
+	app.newPost(t, carol.Tokens.AccessToken, "Ещё пост Кэрол")
+	bobPost := app.newPost(t, bob.Tokens.AccessToken, "Ещё пост Боба")
+	if ev := waitEvent(t, events, "post.created"); ev.Data["post_id"] != float64(bobPost) {
+		t.Errorf("Ожидали пост Боба, получили %v", ev.Data)
+	}
+}
+
+func TestStreamValidation(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+
+	if w := app.request("GET", "/v1/stream", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Без токена: ожидали 401, получили %d", w.Code)
+	}
+	if w := app.authRequest("GET", "/v1/stream?posts=1,abc", alice.Tokens.AccessToken, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Неверный список постов: ожидали 400, получили %d", w.Code)
+	}
+
+	ids := make([]string, service.MaxStreamPosts+1)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i + 1)
+	}
+	if w := app.authRequest("GET", "/v1/stream?posts="+strings.Join(ids, ","), alice.Tokens.AccessToken, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Слишком много постов: ожидали 400, получили %d", w.Code)
+	}
+}
+
+// ==================== ЛИЧНЫЕ СООБЩЕНИЯ ====================
+
+// openConversation создаёт диалог и возвращает его; body — {"user_id": ...} или {"user_ids": [...]}
+func (app *testApp) openConversation(t *testing.T, token string, body any) map[string]any {
+	t.Helper()
+	w := app.authRequest("POST", "/v1/conversations", token, body)
+	if w.Code != http.StatusOK && w.Code != http.StatusCreated {
+		t.Fatalf("POST /v1/conversations: %d %s", w.Code, w.Body.String())
+	}
+	var conversation map[string]any
+	json.NewDecoder(w.Body).Decode(&conversation)
+	return conversation
+}
+
+// sendMessage отправляет сообщение и возвращает его ID
+func (app *testApp) sendMessage(t *testing.T, token string, conversationID any, content string) float64 {
+	t.Helper()
+	w := app.authRequest("POST", fmt.Sprintf("/v1/conversations/%v/messages", conversationID), token, map[string]string{"content": content})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Отправка сообщения: ожидали 201, получили %d: %s", w.Code, w.Body.String())
+	}
+	var msg map[string]any
+	json.NewDecoder(w.Body).Decode(&msg)
+	return msg["id"].(float64)
+}
+
+// unreadConversations возвращает число диалогов с непрочитанными сообщениями
+func (app *testApp) unreadConversations(t *testing.T, token string) float64 {
+	t.Helper()
+	var body map[string]float64
+	json.NewDecoder(app.authRequest("GET", "/v1/conversations/unread-count", token, nil).Body).Decode(&body)
+	return body["count"]
+}
+
+func TestDirectMessages(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	carol := app.registerUser(t, "carol", "carol@test.com", "password123")
+
+	conv := app.openConversation(t, alice.Tokens.AccessToken, map[string]any{"user_id": bob.User["id"]})
+	if again := app.openConversation(t, bob.Tokens.AccessToken, map[string]any{"user_id": alice.User["id"]}); again["id"] != conv["id"] {
+		t.Fatalf("Личный диалог создан повторно: %v и %v", conv["id"], again["id"])
+	}
+	msgID := app.sendMessage(t, alice.Tokens.AccessToken, conv["id"], "Привет, Боб!")
+
+	items := decodePage(app.authRequest("GET", "/v1/conversations", bob.Tokens.AccessToken, nil)).Items
+	if len(items) != 1 || items[0]["unread_count"] != float64(1) {
+		t.Fatalf("Ожидали диалог с одним непрочитанным, получили %v", items)
+	}
+	if last := items[0]["last_message"].(map[string]any); last["content"] != "Привет, Боб!" || last["username"] != "alice" {
+		t.Errorf("Неверное последнее сообщение: %v", last)
+	}
+	if c := app.unreadConversations(t, bob.Tokens.AccessToken); c != 1 {
+		t.Errorf("Непрочитанных диалогов = %v", c)
+	}
+
+	w := app.authRequest("POST", fmt.Sprintf("/v1/conversations/%v/read", conv["id"]), bob.Tokens.AccessToken, nil)
+	var read map[string]float64
+	json.NewDecoder(w.Body).Decode(&read)
+	if w.Code != http.StatusOK || read["last_read_id"] != msgID {
+		t.Errorf("Отметка о прочтении: %d %v", w.Code, read)
+	}
+	if c := app.unreadConversations(t, bob.Tokens.AccessToken); c != 0 {
+		t.Errorf("После прочтения непрочитанных диалогов = %v", c)
+	}
+
+	// Посторонний не видит ни диалог, ни сообщения
+	if w := app.authRequest("GET", fmt.Sprintf("/v1/conversations/%v/messages", conv["id"]), carol.Tokens.AccessToken, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Чужой диалог: ожидали 404, получили %d", w.Code)
+	}
+	if w := app.authRequest("POST", fmt.Sprintf("/v1/conversations/%v/messages", conv["id"]), carol.Tokens.AccessToken, map[string]string{"content": "Я тоже тут"}); w.Code != http.StatusNotFound {
+		t.Errorf("Сообщение в чужой диалог: ожидали 404, получили %d", w.Code)
+	}
+}
+
+func TestMessagesFollowingOnly(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+
+	conv := app.openConversation(t, alice.Tokens.AccessToken, map[string]any{"user_id": bob.User["id"]})
+	app.authRequest("PUT", "/v1/users/me", bob.Tokens.AccessToken, map[string]any{"messages_following_only": true})
+
+	w := app.authRequest("POST", fmt.Sprintf("/v1/conversations/%v/messages", conv["id"]), alice.Tokens.AccessToken, map[string]string{"content": "Привет"})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Боб принимает сообщения только от своих подписок: ожидали 403, получили %d", w.Code)
+	}
+	if w := app.authRequest("POST", "/v1/conversations", alice.Tokens.AccessToken, map[string]any{"user_id": bob.User["id"]}); w.Code != http.StatusForbidden {
+		t.Errorf("Новый диалог: ожидали 403, получили %d", w.Code)
+	}
+
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", alice.User["id"]), bob.Tokens.AccessToken, nil)
+	app.sendMessage(t, alice.Tokens.AccessToken, conv["id"], "Привет")
+}
+
+func TestGroupConversation(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	carol := app.registerUser(t, "carol", "carol@test.com", "password123")
+
+	if w := app.authRequest("POST", "/v1/conversations", alice.Tokens.AccessToken, map[string]any{"user_ids": []any{bob.User["id"]}}); w.Code != http.StatusBadRequest {
+		t.Errorf("Группа из двух: ожидали 400, получили %d", w.Code)
+	}
+	group := app.openConversation(t, alice.Tokens.AccessToken, map[string]any{
+		"user_ids": []any{bob.User["id"], carol.User["id"]},
+		"title":    "Книжный клуб",
+	})
+	if group["is_group"] != true || group["title"] != "Книжный клуб" || len(group["participants"].([]any)) != 3 {
+		t.Fatalf("Неверная группа: %v", group)
+	}
+	app.sendMessage(t, alice.Tokens.AccessToken, group["id"], "Всем привет")
+	for _, u := range []authResponse{bob, carol} {
+		if c := app.unreadConversations(t, u.Tokens.AccessToken); c != 1 {
+			t.Errorf("%v: непрочитанных диалогов = %v", u.User["username"], c)
+		}
+	}
+
+	// Carol выходит и больше не видит группу
+	if w := app.authRequest("DELETE", fmt.Sprintf("/v1/conversations/%v", group["id"]), carol.Tokens.AccessToken, nil); w.Code != http.StatusOK {
+		t.Fatalf("Выход из группы: ожидали 200, получили %d", w.Code)
+	}
+	if w := app.authRequest("GET", fmt.Sprintf("/v1/conversations/%v", group["id"]), carol.Tokens.AccessToken, nil); w.Code != http.StatusNotFound {
+		t.Errorf("После выхода: ожидали 404, получили %d", w.Code)
+	}
+	var after map[string]any
+	json.NewDecoder(app.authRequest("GET", fmt.Sprintf("/v1/conversations/%v", group["id"]), alice.Tokens.AccessToken, nil).Body).Decode(&after)
+	if len(after["participants"].([]any)) != 2 {
+		t.Errorf("После выхода участников: %v", after["participants"])
+	}
+
+	// Из личного диалога выйти нельзя
+	direct := app.openConversation(t, alice.Tokens.AccessToken, map[string]any{"user_id": bob.User["id"]})
+	if w := app.authRequest("DELETE", fmt.Sprintf("/v1/conversations/%v", direct["id"]), alice.Tokens.AccessToken, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Выход из личного диалога: ожидали 400, получили %d", w.Code)
+	}
+}
+
+func TestConversationMuteAndArchive(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	conv := app.openConversation(t, alice.Tokens.AccessToken, map[string]any{"user_id": bob.User["id"]})
+	path := fmt.Sprintf("/v1/conversations/%v", conv["id"])
+
+	listed := func(archived bool) int {
+		return len(decodePage(app.authRequest("GET", fmt.Sprintf("/v1/conversations?archived=%t", archived), bob.Tokens.AccessToken, nil)).Items)
+	}
+
+	// Архив: диалог уходит из списка и возвращается с новым сообщением
+	app.authRequest("POST", path+"/archive", bob.Tokens.AccessToken, nil)
+	if listed(false) != 0 || listed(true) != 1 {
+		t.Fatalf("Диалог не ушёл в архив")
+	}
+	app.sendMessage(t, alice.Tokens.AccessToken, conv["id"], "Ты тут?")
+	if listed(false) != 1 || listed(true) != 0 {
+		t.Errorf("Новое сообщение не вернуло диалог из архива")
+	}
+
+	// Заглушённый диалог не считается непрочитанным и остаётся в архиве
+	app.authRequest("POST", path+"/mute", bob.Tokens.AccessToken, nil)
+	app.authRequest("POST", path+"/archive", bob.Tokens.AccessToken, nil)
+	app.sendMessage(t, alice.Tokens.AccessToken, conv["id"], "Ау")
+	if c := app.unreadConversations(t, bob.Tokens.AccessToken); c != 0 {
+		t.Errorf("Заглушённый диалог в счётчике: %v", c)
+	}
+	if listed(true) != 1 {
+		t.Errorf("Заглушённый диалог покинул архив")
+	}
+
+	app.authRequest("DELETE", path+"/mute", bob.Tokens.AccessToken, nil)
+	if c := app.unreadConversations(t, bob.Tokens.AccessToken); c != 1 {
+		t.Errorf("После включения звука непрочитанных диалогов = %v", c)
+	}
+}
+
+// ==================== БЛОКИРОВКИ И СКРЫТИЕ ====================
+
+func TestBlockUser(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	postID := app.newPost(t, alice.Tokens.AccessToken, "Пост Алисы")
+
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", alice.User["id"]), bob.Tokens.AccessToken, nil)
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", bob.User["id"]), alice.Tokens.AccessToken, nil)
+
+	w := app.authRequest("POST", "/v1/users/me/blocks", alice.Tokens.AccessToken, map[string]any{"user_id": bob.User["id"]})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Блокировка: ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+
+	// Подписки удалены в обе стороны вместе со счётчиками
+	profile := app.getJSON(t, fmt.Sprintf("/v1/users/%v", alice.User["id"]))
+	if profile["followers_count"] != float64(0) || profile["following_count"] != float64(0) {
+		t.Errorf("Подписки не удалены: %v", profile)
+	}
+
+	// Bob не видит Алису и её посты и не может с ней взаимодействовать
+	bobToken := bob.Tokens.AccessToken
+	checks := []struct {
+		method, path string
+		body         any
+	}{
+		{"GET", fmt.Sprintf("/v1/users/%v", alice.User["id"]), nil},
+		{"GET", fmt.Sprintf("/v1/posts/%d", postID), nil},
+		{"GET", fmt.Sprintf("/v1/posts/%d/revisions", postID), nil},
+		{"POST", fmt.Sprintf("/v1/posts/%d/like", postID), nil},
+		{"POST", fmt.Sprintf("/v1/posts/%d/comments", postID), map[string]string{"content": "Эй"}},
+		{"POST", fmt.Sprintf("/v1/users/%v/follow", alice.User["id"]), nil},
+		{"POST", "/v1/conversations", map[string]any{"user_id": alice.User["id"]}},
+	}
+	for _, c := range checks {
+		if w := app.authRequest(c.method, c.path, bobToken, c.body); w.Code != http.StatusNotFound {
+			t.Errorf("%s %s: ожидали 404, получили %d", c.method, c.path, w.Code)
+		}
+	}
+	for _, path := range []string{"/v1/feed", fmt.Sprintf("/v1/users/%v/posts", alice.User["id"])} {
+		if items := decodePage(app.authRequest("GET", path, bobToken, nil)).Items; len(items) != 0 {
+			t.Errorf("%s: заблокированный видит посты: %v", path, items)
+		}
+	}
+	if items := decodePage(app.authRequest("GET", "/v1/search?type=users&q=alice", bobToken, nil)).Items; len(items) != 0 {
+		t.Errorf("Заблокированный находит Алису в поиске: %v", items)
+	}
+
+	// Блокировка взаимна: Алиса тоже не видит Боба
+	if w := app.authRequest("GET", fmt.Sprintf("/v1/users/%v", bob.User["id"]), alice.Tokens.AccessToken, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Профиль заблокированного: ожидали 404, получили %d", w.Code)
+	}
+
+	blocks := decodePage(app.authRequest("GET", "/v1/users/me/blocks", alice.Tokens.AccessToken, nil)).Items
+	if len(blocks) != 1 || blocks[0]["username"] != "bob" {
+		t.Fatalf("Список блокировок: %v", blocks)
+	}
+
+	// После разблокировки пост снова виден, а подписки не возвращаются
+	app.authRequest("DELETE", fmt.Sprintf("/v1/users/me/blocks/%v", bob.User["id"]), alice.Tokens.AccessToken, nil)
+	if w := app.authRequest("GET", fmt.Sprintf("/v1/posts/%d", postID), bobToken, nil); w.Code != http.StatusOK {
+		t.Errorf("После разблокировки: ожидали 200, получили %d", w.Code)
+	}
+	if profile := app.getJSON(t, fmt.Sprintf("/v1/users/%v", alice.User["id"])); profile["followers_count"] != float64(0) {
+		t.Errorf("Подписка вернулась после разблокировки: %v", profile)
+	}
+}
+
+func TestBlockHidesCommentsAndFollowers(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	carol := app.registerUser(t, "carol", "carol@test.com", "password123")
+	postID := app.newPost(t, carol.Tokens.AccessToken, "Пост Кэрол")
+
+	app.postComment(bob.Tokens.AccessToken, postID, nil, "Комментарий Боба")
+	app.postComment(carol.Tokens.AccessToken, postID, nil, "Комментарий Кэрол")
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", carol.User["id"]), bob.Tokens.AccessToken, nil)
+
+	app.authRequest("POST", "/v1/users/me/blocks", alice.Tokens.AccessToken, map[string]any{"user_id": bob.User["id"]})
+
+	comments := decodePage(app.authRequest("GET", fmt.Sprintf("/v1/posts/%d/comments", postID), alice.Tokens.AccessToken, nil)).Items
+	if len(comments) != 1 || comments[0]["username"] != "carol" {
+		t.Errorf("Алиса видит комментарии заблокированного: %v", comments)
+	}
+	followers := decodePage(app.authRequest("GET", fmt.Sprintf("/v1/users/%v/followers", carol.User["id"]), alice.Tokens.AccessToken, nil)).Items
+	if len(followers) != 0 {
+		t.Errorf("Алиса видит заблокированного среди подписчиков: %v", followers)
+	}
+
+	// Остальные видят всё как раньше
+	if comments := decodePage(app.request("GET", fmt.Sprintf("/v1/posts/%d/comments", postID), nil)).Items; len(comments) != 2 {
+		t.Errorf("Гость должен видеть оба комментария: %v", comments)
+	}
+}
+
+func TestBlockStopsDirectMessages(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	conv := app.openConversation(t, bob.Tokens.AccessToken, map[string]any{"user_id": alice.User["id"]})
+
+	app.authRequest("POST", "/v1/users/me/blocks", alice.Tokens.AccessToken, map[string]any{"user_id": bob.User["id"]})
+
+	w := app.authRequest("POST", fmt.Sprintf("/v1/conversations/%v/messages", conv["id"]), bob.Tokens.AccessToken, map[string]string{"content": "Ответь"})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Сообщение заблокировавшему: ожидали 404, получили %d", w.Code)
+	}
+	if w := app.authRequest("POST", "/v1/users/me/blocks", alice.Tokens.AccessToken, map[string]any{"user_id": alice.User["id"]}); w.Code != http.StatusBadRequest {
+		t.Errorf("Блокировка себя: ожидали 400, получили %d", w.Code)
+	}
+}
+
+func TestBlockInGroupConversation(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	carol := app.registerUser(t, "carol", "carol@test.com", "password123")
+	members := map[string]any{"user_ids": []any{alice.User["id"], bob.User["id"]}}
+	group := app.openConversation(t, carol.Tokens.AccessToken, members)
+	app.sendMessage(t, bob.Tokens.AccessToken, group["id"], "Привет всем")
+
+	app.authRequest("POST", "/v1/users/me/blocks", alice.Tokens.AccessToken, map[string]any{"user_id": bob.User["id"]})
+
+	// Алиса больше не видит сообщений Боба, Кэрол видит
+	path := fmt.Sprintf("/v1/conversations/%v/messages", group["id"])
+	if items := decodePage(app.authRequest("GET", path, alice.Tokens.AccessToken, nil)).Items; len(items) != 0 {
+		t.Errorf("Сообщения заблокированного в группе: %v", items)
+	}
+	if items := decodePage(app.authRequest("GET", path, carol.Tokens.AccessToken, nil)).Items; len(items) != 1 {
+		t.Errorf("Кэрол: ожидали 1 сообщение, получили %d", len(items))
+	}
+
+	// Новую группу с обоими собрать нельзя
+	if w := app.authRequest("POST", "/v1/conversations", carol.Tokens.AccessToken, members); w.Code != http.StatusBadRequest {
+		t.Errorf("Группа с заблокированным: ожидали 400, получили %d", w.Code)
 	}
 }
 
-func TestLogout(t *testing.T) {
+func TestBlockedUserCannotMention(t *testing.T) {
 	app := setupTestApp(t)
-	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
-
-	w := app.authRequest("POST", "/v1/auth/logout", resp.Tokens.AccessToken, map[string]string{
-		"refresh_token": resp.Tokens.RefreshToken,
-	})
-	if w.Code != http.StatusOK {
-		t.Errorf("Ожидали 200, получили %d", w.Code)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+
+	// Упоминание до блокировки пропадает из входящих и уведомлений Алисы
+	app.newPost(t, bob.Tokens.AccessToken, "Привет, @alice")
+	app.authRequest("POST", "/v1/users/me/blocks", alice.Tokens.AccessToken, map[string]any{"user_id": bob.User["id"]})
+
+	// После блокировки @alice остаётся просто текстом
+	postID := app.newPost(t, bob.Tokens.AccessToken, "Снова @alice")
+	post := app.getJSON(t, fmt.Sprintf("/v1/posts/%d", postID))
+	if mentions, _ := post["mentions"].([]any); len(mentions) != 0 {
+		t.Errorf("Упоминание заблокировавшего сохранилось: %v", post["mentions"])
+	}
+	w := app.postComment(bob.Tokens.AccessToken, postID, nil, "И тут @alice")
+	var comment map[string]any
+	json.NewDecoder(w.Body).Decode(&comment)
+	if mentions, _ := comment["mentions"].([]any); len(mentions) != 0 {
+		t.Errorf("Упоминание в комментарии сохранилось: %v", comment["mentions"])
 	}
 
-	// Повторный refresh должен провалиться
-	w = app.request("POST", "/v1/auth/refresh", map[string]string{
-		"refresh_token": resp.Tokens.RefreshToken,
-	})
-	if w.Code != http.StatusUnauthorized {
-		t.Errorf("Ожидали 401 после логаута, получили %d", w.Code)
+	if items := decodePage(app.authRequest("GET", "/v1/users/me/mentions", alice.Tokens.AccessToken, nil)).Items; len(items) != 0 {
+		t.Errorf("Упоминания заблокированного во входящих: %v", items)
+	}
+	if n := app.notifications(t, alice.Tokens.AccessToken); len(n) != 0 {
+		t.Errorf("Уведомления от заблокированного: %v", n)
 	}
 }
 
-// ==================== ПОСТЫ ====================
-
-func TestCreatePost(t *testing.T) {
+func TestMuteUser(t *testing.T) {
 	app := setupTestApp(t)
-	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	app.newPost(t, bob.Tokens.AccessToken, "Шумный пост #новости")
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", bob.User["id"]), alice.Tokens.AccessToken, nil)
 
-	w := app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
-		"content": "Мой первый пост!",
-	})
-	if w.Code != http.StatusCreated {
-		t.Errorf("Ожидали 201, получили %d: %s", w.Code, w.Body.String())
+	w := app.authRequest("POST", "/v1/users/me/mutes", alice.Tokens.AccessToken, map[string]any{"user_id": bob.User["id"]})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Скрытие: ожидали 200, получили %d", w.Code)
 	}
 
-	var post map[string]any
-	json.NewDecoder(w.Body).Decode(&post)
-	if post["content"] != "Мой первый пост!" {
-		t.Errorf("content = %v", post["content"])
+	for _, path := range []string{"/v1/feed", "/v1/feed/following", "/v1/hashtags/новости/posts"} {
+		if items := decodePage(app.authRequest("GET", path, alice.Tokens.AccessToken, nil)).Items; len(items) != 0 {
+			t.Errorf("%s: посты скрытого в ленте: %v", path, items)
+		}
 	}
-}
 
-func TestCreatePostNoAuth(t *testing.T) {
-	app := setupTestApp(t)
+	// Профиль и посты скрытого доступны напрямую, подписка остаётся
+	var profile map[string]any
+	json.NewDecoder(app.authRequest("GET", fmt.Sprintf("/v1/users/%v", bob.User["id"]), alice.Tokens.AccessToken, nil).Body).Decode(&profile)
+	if profile["is_muted"] != true || profile["is_following"] != true {
+		t.Errorf("Профиль скрытого: %v", profile)
+	}
+	if items := decodePage(app.authRequest("GET", fmt.Sprintf("/v1/users/%v/posts", bob.User["id"]), alice.Tokens.AccessToken, nil)).Items; len(items) != 1 {
+		t.Errorf("Посты на странице скрытого: %v", items)
+	}
 
-	w := app.request("POST", "/v1/posts", map[string]string{
-		"content": "Без авторизации",
-	})
-	if w.Code != http.StatusUnauthorized {
-		t.Errorf("Ожидали 401, получили %d", w.Code)
+	// Скрытый ничего не замечает: он по-прежнему видит Алису как подписчицу
+	followers := decodePage(app.authRequest("GET", fmt.Sprintf("/v1/users/%v/followers", bob.User["id"]), bob.Tokens.AccessToken, nil)).Items
+	if len(followers) != 1 {
+		t.Errorf("Подписчики скрытого: %v", followers)
+	}
+
+	mutes := decodePage(app.authRequest("GET", "/v1/users/me/mutes", alice.Tokens.AccessToken, nil)).Items
+	if len(mutes) != 1 || mutes[0]["username"] != "bob" {
+		t.Fatalf("Список скрытых: %v", mutes)
+	}
+	app.authRequest("DELETE", fmt.Sprintf("/v1/users/me/mutes/%v", bob.User["id"]), alice.Tokens.AccessToken, nil)
+	if items := decodePage(app.authRequest("GET", "/v1/feed/following", alice.Tokens.AccessToken, nil)).Items; len(items) != 1 {
+		t.Errorf("После возврата в ленты: %v", items)
 	}
 }
 
-func TestCreatePostEmptyContent(t *testing.T) {
-	app := setupTestApp(t)
-	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+// ==================== ЗАКРЫТЫЕ АККАУНТЫ ====================
 
-	w := app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
-		"content": "",
-	})
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("Ожидали 400, получили %d", w.Code)
+// makePrivate закрывает аккаунт пользователя
+func (app *testApp) makePrivate(t *testing.T, token string, private bool) {
+	t.Helper()
+	w := app.authRequest("PUT", "/v1/users/me", token, map[string]any{"is_private": private})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Смена закрытости: ожидали 200, получили %d", w.Code)
 	}
 }
 
-func TestDeleteOwnPost(t *testing.T) {
+func TestPrivateAccountFollowRequest(t *testing.T) {
 	app := setupTestApp(t)
-	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	carol := app.registerUser(t, "carol", "carol@test.com", "password123")
+	app.makePrivate(t, alice.Tokens.AccessToken, true)
+	postID := app.newPost(t, alice.Tokens.AccessToken, "Только для своих")
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", carol.User["id"]), alice.Tokens.AccessToken, nil)
+
+	w := app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", alice.User["id"]), bob.Tokens.AccessToken, nil)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Подписка на закрытый аккаунт: ожидали 202, получили %d", w.Code)
+	}
+	var profile map[string]any
+	json.NewDecoder(app.authRequest("GET", fmt.Sprintf("/v1/users/%v", alice.User["id"]), bob.Tokens.AccessToken, nil).Body).Decode(&profile)
+	if profile["is_private"] != true || profile["is_requested"] != true || profile["is_following"] != false || profile["followers_count"] != float64(0) {
+		t.Errorf("Профиль с ожидающим запросом: %v", profile)
+	}
 
-	// Создаём пост
-	w := app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
-		"content": "Для удаления",
-	})
-	var post map[string]any
-	json.NewDecoder(w.Body).Decode(&post)
-	postID := int(post["id"].(float64))
+	// До одобрения Боб и гости не видят ни постов, ни подписок Алисы
+	hidden := []string{
+		"/v1/feed",
+		fmt.Sprintf("/v1/users/%v/posts", alice.User["id"]),
+		fmt.Sprintf("/v1/users/%v/following", alice.User["id"]),
+	}
+	for _, path := range hidden {
+		if items := decodePage(app.authRequest("GET", path, bob.Tokens.AccessToken, nil)).Items; len(items) != 0 {
+			t.Errorf("%s: виден контент закрытого аккаунта: %v", path, items)
+		}
+		if items := decodePage(app.request("GET", path, nil)).Items; len(items) != 0 {
+			t.Errorf("%s: гость видит контент закрытого аккаунта: %v", path, items)
+		}
+	}
+	// Сам пост и его комментарии по ID тоже не найти
+	postPath := fmt.Sprintf("/v1/posts/%d", postID)
+	for _, path := range []string{postPath, postPath + "?expand=comments", postPath + "/comments"} {
+		if w := app.authRequest("GET", path, bob.Tokens.AccessToken, nil); w.Code != http.StatusNotFound {
+			t.Errorf("%s: ожидали 404, получили %d", path, w.Code)
+		}
+		if w := app.request("GET", path, nil); w.Code != http.StatusNotFound {
+			t.Errorf("%s для гостя: ожидали 404, получили %d", path, w.Code)
+		}
+	}
+	if w := app.authRequest("POST", postPath+"/like", bob.Tokens.AccessToken, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Лайк скрытого поста: ожидали 404, получили %d", w.Code)
+	}
+	// Сама Алиса видит всё
+	if items := decodePage(app.authRequest("GET", fmt.Sprintf("/v1/users/%v/posts", alice.User["id"]), alice.Tokens.AccessToken, nil)).Items; len(items) != 1 {
+		t.Errorf("Владелец не видит свои посты: %v", items)
+	}
 
-	// Удаляем
-	w = app.authRequest("DELETE", fmt.Sprintf("/v1/posts/%d", postID), resp.Tokens.AccessToken, nil)
+	requests := decodePage(app.authRequest("GET", "/v1/users/me/follow-requests", alice.Tokens.AccessToken, nil)).Items
+	if len(requests) != 1 || requests[0]["username"] != "bob" {
+		t.Fatalf("Запросы на подписку: %v", requests)
+	}
+	if n := app.notifications(t, alice.Tokens.AccessToken); len(n) != 1 || n[0]["type"] != "follow_request" {
+		t.Errorf("Уведомление о запросе: %v", n)
+	}
+
+	w = app.authRequest("POST", fmt.Sprintf("/v1/users/me/follow-requests/%v", bob.User["id"]), alice.Tokens.AccessToken, nil)
 	if w.Code != http.StatusOK {
-		t.Errorf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+		t.Fatalf("Одобрение: ожидали 200, получили %d", w.Code)
+	}
+	for _, path := range hidden {
+		if items := decodePage(app.authRequest("GET", path, bob.Tokens.AccessToken, nil)).Items; len(items) != 1 {
+			t.Errorf("%s: после одобрения ожидали 1 элемент, получили %v", path, items)
+		}
+	}
+	if w := app.authRequest("GET", postPath, bob.Tokens.AccessToken, nil); w.Code != http.StatusOK {
+		t.Errorf("Пост после одобрения: ожидали 200, получили %d", w.Code)
+	}
+	if profile := app.getJSON(t, fmt.Sprintf("/v1/users/%v", alice.User["id"])); profile["followers_count"] != float64(1) {
+		t.Errorf("Счётчик подписчиков после одобрения: %v", profile["followers_count"])
+	}
+	if requests := decodePage(app.authRequest("GET", "/v1/users/me/follow-requests", alice.Tokens.AccessToken, nil)).Items; len(requests) != 0 {
+		t.Errorf("Одобренный запрос остался в списке: %v", requests)
 	}
 }
 
-func TestDeleteOtherPost(t *testing.T) {
+func TestPrivateAccountMentionsNonFollower(t *testing.T) {
 	app := setupTestApp(t)
-	user1 := app.registerUser(t, "user1", "user1@test.com", "password123")
-	user2 := app.registerUser(t, "user2", "user2@test.com", "password123")
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
 
-	// user1 создаёт пост
-	w := app.authRequest("POST", "/v1/posts", user1.Tokens.AccessToken, map[string]string{
-		"content": "Пост юзера 1",
-	})
-	var post map[string]any
-	json.NewDecoder(w.Body).Decode(&post)
-	postID := int(post["id"].(float64))
+	// Упоминание в открытом посте пропадает из входящих, когда аккаунт закрывается
+	app.newPost(t, alice.Tokens.AccessToken, "Привет, @bob")
+	if items := decodePage(app.authRequest("GET", "/v1/users/me/mentions", bob.Tokens.AccessToken, nil)).Items; len(items) != 1 {
+		t.Fatalf("Упоминание в открытом посте: %v", items)
+	}
+	app.makePrivate(t, alice.Tokens.AccessToken, true)
+	if items := decodePage(app.authRequest("GET", "/v1/users/me/mentions", bob.Tokens.AccessToken, nil)).Items; len(items) != 0 {
+		t.Errorf("Упоминание в закрытом посте видно не подписчику: %v", items)
+	}
 
-	// user2 пытается удалить
-	w = app.authRequest("DELETE", fmt.Sprintf("/v1/posts/%d", postID), user2.Tokens.AccessToken, nil)
-	if w.Code != http.StatusForbidden {
-		t.Errorf("Ожидали 403, получили %d", w.Code)
+	// Новое упоминание не подписчика в закрытом аккаунте остаётся текстом и не уведомляет
+	postID := app.newPost(t, alice.Tokens.AccessToken, "Снова @bob")
+	var post map[string]any
+	json.NewDecoder(app.authRequest("GET", fmt.Sprintf("/v1/posts/%d", postID), alice.Tokens.AccessToken, nil).Body).Decode(&post)
+	if mentions, _ := post["mentions"].([]any); len(mentions) != 0 {
+		t.Errorf("Упоминание не подписчика сохранилось: %v", post["mentions"])
+	}
+	var mentionNotices int
+	for _, n := range app.notifications(t, bob.Tokens.AccessToken) {
+		if n["type"] == "mention" {
+			mentionNotices++
+		}
+	}
+	if mentionNotices != 1 {
+		t.Errorf("Ожидали только уведомление о первом упоминании, получили %d", mentionNotices)
 	}
 }
 
-func TestGetFeed(t *testing.T) {
+func TestDenyFollowRequest(t *testing.T) {
 	app := setupTestApp(t)
-	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
-
-	// Создаём 3 поста
-	for i := 1; i <= 3; i++ {
-		app.authRequest("POST", "/v1/posts", resp.Tokens.AccessToken, map[string]string{
-			"content": fmt.Sprintf("Пост %d", i),
-		})
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	app.makePrivate(t, alice.Tokens.AccessToken, true)
+	followPath := fmt.Sprintf("/v1/users/%v/follow", alice.User["id"])
+	requestPath := fmt.Sprintf("/v1/users/me/follow-requests/%v", bob.User["id"])
+
+	app.authRequest("POST", followPath, bob.Tokens.AccessToken, nil)
+	if w := app.authRequest("DELETE", requestPath, alice.Tokens.AccessToken, nil); w.Code != http.StatusOK {
+		t.Fatalf("Отклонение: ожидали 200, получили %d", w.Code)
+	}
+	if w := app.authRequest("POST", requestPath, alice.Tokens.AccessToken, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Одобрение отклонённого: ожидали 404, получили %d", w.Code)
+	}
+	if n := app.notifications(t, alice.Tokens.AccessToken); len(n) != 0 {
+		t.Errorf("Уведомление об отклонённом запросе осталось: %v", n)
 	}
 
-	w := app.request("GET", "/v1/feed", nil)
-	if w.Code != http.StatusOK {
-		t.Errorf("Ожидали 200, получили %d", w.Code)
+	// Отклонённый может попросить снова, а отписка отзывает запрос
+	if w := app.authRequest("POST", followPath, bob.Tokens.AccessToken, nil); w.Code != http.StatusAccepted {
+		t.Errorf("Повторный запрос: ожидали 202, получили %d", w.Code)
+	}
+	app.authRequest("DELETE", followPath, bob.Tokens.AccessToken, nil)
+	if requests := decodePage(app.authRequest("GET", "/v1/users/me/follow-requests", alice.Tokens.AccessToken, nil)).Items; len(requests) != 0 {
+		t.Errorf("Отозванный запрос остался: %v", requests)
 	}
+}
 
-	var posts []map[string]any
-	json.NewDecoder(w.Body).Decode(&posts)
-	if len(posts) != 3 {
-		t.Errorf("Ожидали 3 поста, получили %d", len(posts))
+func TestOpeningAccountApprovesRequests(t *testing.T) {
+	app := setupTestApp(t)
+	alice := app.registerUser(t, "alice", "alice@test.com", "password123")
+	bob := app.registerUser(t, "bob", "bob@test.com", "password123")
+	app.makePrivate(t, alice.Tokens.AccessToken, true)
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%v/follow", alice.User["id"]), bob.Tokens.AccessToken, nil)
+
+	app.makePrivate(t, alice.Tokens.AccessToken, false)
+
+	followers := decodePage(app.request("GET", fmt.Sprintf("/v1/users/%v/followers", alice.User["id"]), nil)).Items
+	if len(followers) != 1 || followers[0]["username"] != "bob" {
+		t.Errorf("После открытия аккаунта запрос не одобрен: %v", followers)
+	}
+	if profile := app.getJSON(t, fmt.Sprintf("/v1/users/%v", bob.User["id"])); profile["following_count"] != float64(1) {
+		t.Errorf("Счётчик подписок: %v", profile["following_count"])
 	}
 }
 
@@ -272,8 +2957,7 @@ func TestGetComments(t *testing.T) {
 		t.Errorf("Ожидали 200, получили %d", w.Code)
 	}
 
-	var comments []map[string]any
-	json.NewDecoder(w.Body).Decode(&comments)
+	comments := decodePage(w).Items
 	if len(comments) != 2 {
 		t.Errorf("Ожидали 2 комментария, получили %d", len(comments))
 	}
@@ -296,13 +2980,334 @@ func TestGetCommentsEmpty(t *testing.T) {
 		t.Errorf("Ожидали 200, получили %d", w.Code)
 	}
 
-	var comments []map[string]any
-	json.NewDecoder(w.Body).Decode(&comments)
+	comments := decodePage(w).Items
 	if len(comments) != 0 {
 		t.Errorf("Ожидали 0 комментариев, получили %d", len(comments))
 	}
 }
 
+// ==================== ВЕТКИ КОММЕНТАРИЕВ ====================
+
+// postComment создаёт пост-комментарий или ответ (parentID != nil) и возвращает ответ сервера
+func (app *testApp) postComment(token string, postID int, parentID any, content string) *httptest.ResponseRecorder {
+	body := map[string]any{"content": content}
+	if parentID != nil {
+		body["parent_id"] = parentID
+	}
+	return app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/comments", postID), token, body)
+}
+
+// newPost создаёт пост и возвращает его ID
+func (app *testApp) newPost(t *testing.T, token, content string) int {
+	t.Helper()
+	w := app.authRequest("POST", "/v1/posts", token, map[string]string{"content": content})
+	var post map[string]any
+	json.NewDecoder(w.Body).Decode(&post)
+	id, ok := post["id"].(float64)
+	if !ok {
+		t.Fatalf("Не удалось создать пост: %d %s", w.Code, w.Body.String())
+	}
+	return int(id)
+}
+
+func TestCommentReplies(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	token := resp.Tokens.AccessToken
+	postID := app.newPost(t, token, "Обсуждение")
+
+	var root map[string]any
+	json.NewDecoder(app.postComment(token, postID, nil, "Корень").Body).Decode(&root)
+	for i := 1; i <= 2; i++ {
+		w := app.postComment(token, postID, root["id"], fmt.Sprintf("Ответ %d", i))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Ответ: ожидали 201, получили %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	// В списке комментариев поста — только верхний уровень
+	comments := decodePage(app.request("GET", fmt.Sprintf("/v1/posts/%d/comments", postID), nil)).Items
+	if len(comments) != 1 || comments[0]["replies_count"] != float64(2) {
+		t.Fatalf("comments = %v", comments)
+	}
+
+	w := app.request("GET", fmt.Sprintf("/v1/comments/%v/replies?limit=1", root["id"]), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d", w.Code)
+	}
+	page := decodePage(w)
+	if len(page.Items) != 1 || page.Items[0]["content"] != "Ответ 1" || page.NextCursor == "" {
+		t.Fatalf("Первая страница ответов: %v", page)
+	}
+	if page.Items[0]["parent_id"] != root["id"] || page.Items[0]["depth"] != float64(1) {
+		t.Errorf("parent_id = %v, depth = %v", page.Items[0]["parent_id"], page.Items[0]["depth"])
+	}
+
+	page = decodePage(app.request("GET",
+		fmt.Sprintf("/v1/comments/%v/replies?limit=1&cursor=%s", root["id"], page.NextCursor), nil))
+	if len(page.Items) != 1 || page.Items[0]["content"] != "Ответ 2" {
+		t.Errorf("Вторая страница ответов: %v", page.Items)
+	}
+}
+
+func TestCommentReplyDepthLimit(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	token := resp.Tokens.AccessToken
+	postID := app.newPost(t, token, "Глубокая ветка")
+
+	var parentID any
+	for depth := 0; depth <= service.MaxCommentDepth; depth++ {
+		w := app.postComment(token, postID, parentID, fmt.Sprintf("Уровень %d", depth))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Уровень %d: ожидали 201, получили %d", depth, w.Code)
+		}
+		var c map[string]any
+		json.NewDecoder(w.Body).Decode(&c)
+		parentID = c["id"]
+	}
+
+	w := app.postComment(token, postID, parentID, "Слишком глубоко")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Ожидали 400, получили %d", w.Code)
+	}
+}
+
+func TestCommentReplyWrongPost(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	token := resp.Tokens.AccessToken
+	post1 := app.newPost(t, token, "Первый")
+	post2 := app.newPost(t, token, "Второй")
+
+	var c map[string]any
+	json.NewDecoder(app.postComment(token, post1, nil, "Под первым").Body).Decode(&c)
+
+	if w := app.postComment(token, post2, c["id"], "Не туда"); w.Code != http.StatusBadRequest {
+		t.Errorf("Ответ под чужим постом: ожидали 400, получили %d", w.Code)
+	}
+	if w := app.request("GET", "/v1/comments/99999/replies", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Ответы несуществующего комментария: ожидали 404, получили %d", w.Code)
+	}
+}
+
+// ==================== ПРАВКА И УДАЛЕНИЕ КОММЕНТАРИЕВ ====================
+
+func TestEditComment(t *testing.T) {
+	app := setupTestApp(t)
+	author := app.registerUser(t, "author", "author@test.com", "password123")
+	other := app.registerUser(t, "other", "other@test.com", "password123")
+	postID := app.newPost(t, author.Tokens.AccessToken, "Пост")
+
+	var c map[string]any
+	json.NewDecoder(app.postComment(author.Tokens.AccessToken, postID, nil, "Опечтка").Body).Decode(&c)
+	path := fmt.Sprintf("/v1/posts/%d/comments/%v", postID, c["id"])
+
+	w := app.authRequest("PATCH", path, other.Tokens.AccessToken, map[string]string{"content": "Чужая правка"})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Чужой комментарий: ожидали 403, получили %d", w.Code)
+	}
+
+	w = app.authRequest("PATCH", path, author.Tokens.AccessToken, map[string]string{"content": "Опечатка"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d: %s", w.Code, w.Body.String())
+	}
+	json.NewDecoder(w.Body).Decode(&c)
+	if c["content"] != "Опечатка" || c["edited_at"] == nil {
+		t.Errorf("content = %v, edited_at = %v", c["content"], c["edited_at"])
+	}
+}
+
+func TestDeleteCommentKeepsThread(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	token := resp.Tokens.AccessToken
+	postID := app.newPost(t, token, "Пост")
+
+	var root map[string]any
+	json.NewDecoder(app.postComment(token, postID, nil, "Корень").Body).Decode(&root)
+	app.postComment(token, postID, root["id"], "Ответ")
+
+	w := app.authRequest("DELETE", fmt.Sprintf("/v1/posts/%d/comments/%v", postID, root["id"]), token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ожидали 200, получили %d", w.Code)
+	}
+
+	comments := decodePage(app.request("GET", fmt.Sprintf("/v1/posts/%d/comments", postID), nil)).Items
+	if len(comments) != 1 {
+		t.Fatalf("Надгробие должно остаться в списке: %v", comments)
+	}
+	if comments[0]["deleted_at"] == nil || comments[0]["content"] != "" || comments[0]["replies_count"] != float64(1) {
+		t.Errorf("Надгробие: %v", comments[0])
+	}
+
+	replies := decodePage(app.request("GET", fmt.Sprintf("/v1/comments/%v/replies", root["id"]), nil)).Items
+	if len(replies) != 1 || replies[0]["content"] != "Ответ" {
+		t.Errorf("Ответы после удаления родителя: %v", replies)
+	}
+
+	// Повторно удалить и отредактировать надгробие нельзя
+	w = app.authRequest("DELETE", fmt.Sprintf("/v1/posts/%d/comments/%v", postID, root["id"]), token, nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Повторное удаление: ожидали 404, получили %d", w.Code)
+	}
+}
+
+func TestPostAuthorModeratesComments(t *testing.T) {
+	app := setupTestApp(t)
+	author := app.registerUser(t, "author", "author@test.com", "password123")
+	commenter := app.registerUser(t, "commenter", "commenter@test.com", "password123")
+	stranger := app.registerUser(t, "stranger", "stranger@test.com", "password123")
+	postID := app.newPost(t, author.Tokens.AccessToken, "Мой пост")
+
+	var rude, spam map[string]any
+	json.NewDecoder(app.postComment(commenter.Tokens.AccessToken, postID, nil, "Грубость").Body).Decode(&rude)
+	json.NewDecoder(app.postComment(commenter.Tokens.AccessToken, postID, nil, "Спам").Body).Decode(&spam)
+
+	// Посторонний не может ни скрыть, ни удалить
+	hidePath := fmt.Sprintf("/v1/posts/%d/comments/%v/hide", postID, rude["id"])
+	if w := app.authRequest("POST", hidePath, stranger.Tokens.AccessToken, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Скрытие посторонним: ожидали 403, получили %d", w.Code)
+	}
+	spamPath := fmt.Sprintf("/v1/posts/%d/comments/%v", postID, spam["id"])
+	if w := app.authRequest("DELETE", spamPath, stranger.Tokens.AccessToken, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Удаление посторонним: ожидали 403, получили %d", w.Code)
+	}
+
+	if w := app.authRequest("DELETE", spamPath, author.Tokens.AccessToken, nil); w.Code != http.StatusOK {
+		t.Errorf("Удаление автором поста: ожидали 200, получили %d", w.Code)
+	}
+	if w := app.authRequest("POST", hidePath, author.Tokens.AccessToken, nil); w.Code != http.StatusOK {
+		t.Fatalf("Скрытие автором поста: ожидали 200, получили %d", w.Code)
+	}
+
+	commentsPath := fmt.Sprintf("/v1/posts/%d/comments", postID)
+	anon := decodePage(app.request("GET", commentsPath, nil)).Items
+	if anon[0]["hidden_at"] == nil || anon[0]["content"] != "" {
+		t.Errorf("Скрытый комментарий виден анониму: %v", anon[0])
+	}
+	own := decodePage(app.authRequest("GET", commentsPath, commenter.Tokens.AccessToken, nil)).Items
+	if own[0]["content"] != "Грубость" {
+		t.Errorf("Автор комментария должен видеть свой текст: %v", own[0])
+	}
+
+	if w := app.authRequest("DELETE", hidePath, author.Tokens.AccessToken, nil); w.Code != http.StatusOK {
+		t.Errorf("Возврат комментария: ожидали 200, получили %d", w.Code)
+	}
+	anon = decodePage(app.request("GET", commentsPath, nil)).Items
+	if anon[0]["content"] != "Грубость" {
+		t.Errorf("После возврата текст должен быть виден: %v", anon[0])
+	}
+}
+
+// ==================== СЧЁТЧИКИ ====================
+
+// getJSON выполняет GET и разбирает объект из ответа
+func (app *testApp) getJSON(t *testing.T, path string) map[string]any {
+	t.Helper()
+	w := app.request("GET", path, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: ожидали 200, получили %d", path, w.Code)
+	}
+	var obj map[string]any
+	json.NewDecoder(w.Body).Decode(&obj)
+	return obj
+}
+
+func TestPostCounters(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	token := resp.Tokens.AccessToken
+	postID := app.newPost(t, token, "Пост со счётчиками")
+	postPath := fmt.Sprintf("/v1/posts/%d", postID)
+
+	// Повторный лайк не увеличивает счётчик
+	app.authRequest("POST", postPath+"/like", token, nil)
+	app.authRequest("POST", postPath+"/like", token, nil)
+
+	var root map[string]any
+	json.NewDecoder(app.postComment(token, postID, nil, "Первый").Body).Decode(&root)
+	app.postComment(token, postID, root["id"], "Ответ")
+	app.postComment(token, postID, nil, "Второй")
+
+	post := app.getJSON(t, postPath)
+	if post["likes_count"] != float64(1) || post["comments_count"] != float64(3) {
+		t.Fatalf("likes_count = %v, comments_count = %v", post["likes_count"], post["comments_count"])
+	}
+
+	app.authRequest("DELETE", fmt.Sprintf("%s/comments/%v", postPath, root["id"]), token, nil)
+	app.authRequest("DELETE", postPath+"/like", token, nil)
+	app.authRequest("DELETE", postPath+"/like", token, nil)
+
+	post = app.getJSON(t, postPath)
+	if post["likes_count"] != float64(0) || post["comments_count"] != float64(2) {
+		t.Errorf("likes_count = %v, comments_count = %v", post["likes_count"], post["comments_count"])
+	}
+}
+
+func TestUserCounters(t *testing.T) {
+	app := setupTestApp(t)
+	user1 := app.registerUser(t, "user1", "user1@test.com", "password123")
+	user2 := app.registerUser(t, "user2", "user2@test.com", "password123")
+	user1ID := int(user1.User["id"].(float64))
+	user2ID := int(user2.User["id"].(float64))
+
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%d/follow", user2ID), user1.Tokens.AccessToken, nil)
+	app.authRequest("POST", fmt.Sprintf("/v1/users/%d/follow", user2ID), user1.Tokens.AccessToken, nil)
+	app.newPost(t, user2.Tokens.AccessToken, "Раз")
+	second := app.newPost(t, user2.Tokens.AccessToken, "Два")
+
+	profile := app.getJSON(t, fmt.Sprintf("/v1/users/%d", user2ID))
+	if profile["followers_count"] != float64(1) || profile["posts_count"] != float64(2) {
+		t.Errorf("followers_count = %v, posts_count = %v", profile["followers_count"], profile["posts_count"])
+	}
+	if p := app.getJSON(t, fmt.Sprintf("/v1/users/%d", user1ID)); p["following_count"] != float64(1) {
+		t.Errorf("following_count = %v", p["following_count"])
+	}
+
+	app.authRequest("DELETE", fmt.Sprintf("/v1/users/%d/follow", user2ID), user1.Tokens.AccessToken, nil)
+	app.authRequest("DELETE", fmt.Sprintf("/v1/posts/%d", second), user2.Tokens.AccessToken, nil)
+
+	profile = app.getJSON(t, fmt.Sprintf("/v1/users/%d", user2ID))
+	if profile["followers_count"] != float64(0) || profile["posts_count"] != float64(1) {
+		t.Errorf("После отписки и удаления: followers_count = %v, posts_count = %v",
+			profile["followers_count"], profile["posts_count"])
+	}
+}
+
+func TestRecountRepairsCounters(t *testing.T) {
+	app := setupTestApp(t)
+	resp := app.registerUser(t, "testuser", "test@test.com", "password123")
+	postID := app.newPost(t, resp.Tokens.AccessToken, "Пост")
+	app.authRequest("POST", fmt.Sprintf("/v1/posts/%d/like", postID), resp.Tokens.AccessToken, nil)
+
+	// Портим счётчики, как могла бы ручная правка БД
+	app.db.Exec(`UPDATE posts SET likes_count = 42, comments_count = 7`)
+	app.db.Exec(`UPDATE users SET posts_count = 0, followers_count = 5`)
+
+	counters := repository.NewCounterRepo(app.db)
+	if n, err := counters.RecountPosts(); err != nil || n != 1 {
+		t.Fatalf("RecountPosts: %d, %v", n, err)
+	}
+	if n, err := counters.RecountUsers(); err != nil || n != 1 {
+		t.Fatalf("RecountUsers: %d, %v", n, err)
+	}
+
+	post := app.getJSON(t, fmt.Sprintf("/v1/posts/%d", postID))
+	if post["likes_count"] != float64(1) || post["comments_count"] != float64(0) {
+		t.Errorf("likes_count = %v, comments_count = %v", post["likes_count"], post["comments_count"])
+	}
+	profile := app.getJSON(t, fmt.Sprintf("/v1/users/%v", resp.User["id"]))
+	if profile["posts_count"] != float64(1) || profile["followers_count"] != float64(0) {
+		t.Errorf("posts_count = %v, followers_count = %v", profile["posts_count"], profile["followers_count"])
+	}
+
+	// Повторный запуск ничего не меняет
+	if n, _ := counters.RecountPosts(); n != 0 {
+		t.Errorf("Повторный пересчёт изменил %d постов", n)
+	}
+}
+
 // ==================== ПОДПИСКИ ====================
 
 func TestFollow(t *testing.T) {
@@ -358,8 +3363,7 @@ func TestGetFollowers(t *testing.T) {
 		t.Errorf("Ожидали 200, получили %d", w.Code)
 	}
 
-	var followers []map[string]any
-	json.NewDecoder(w.Body).Decode(&followers)
+	followers := decodePage(w).Items
 	if len(followers) != 1 {
 		t.Errorf("Ожидали 1 подписчика, получили %d", len(followers))
 	}
@@ -379,8 +3383,7 @@ func TestGetFollowing(t *testing.T) {
 		t.Errorf("Ожидали 200, получили %d", w.Code)
 	}
 
-	var following []map[string]any
-	json.NewDecoder(w.Body).Decode(&following)
+	following := decodePage(w).Items
 	if len(following) != 1 {
 		t.Errorf("Ожидали 1 подписку, получили %d", len(following))
 	}
@@ -406,8 +3409,7 @@ func TestFollowingFeed(t *testing.T) {
 		t.Errorf("Ожидали 200, получили %d", w.Code)
 	}
 
-	var posts []map[string]any
-	json.NewDecoder(w.Body).Decode(&posts)
+	posts := decodePage(w).Items
 	if len(posts) != 1 {
 		t.Errorf("Ожидали 1 пост в ленте подписок, получили %d", len(posts))
 	}
@@ -488,8 +3490,7 @@ func TestFeedWithLikeCount(t *testing.T) {
 
 	// Проверяем ленту с авторизацией
 	w = app.authRequest("GET", "/v1/feed", resp.Tokens.AccessToken, nil)
-	var posts []map[string]any
-	json.NewDecoder(w.Body).Decode(&posts)
+	posts := decodePage(w).Items
 
 	if len(posts) == 0 {
 		t.Fatal("Лента пустая")